@@ -87,6 +87,13 @@ var (
 		CreateBlockMaxTransactionSize:  32768,
 		CreateBlockMaxDropletPrecision: 3,
 		MaxBlockTransactionsSize:       32768,
+		MaxBlockTransactionsPerAddress: 0,
+		AllowEmptyBlocks:               false,
+		BlockCreationInterval:          10,
+		BlockMinTransactions:           1,
+		BlockMinFee:                    0,
+		MaxBlockAge:                    0,
+		EmptyBlockInterval:             0,
 
 		DisplayName:           "LAQ",
 		Ticker:                "LAQ",
@@ -107,6 +114,12 @@ func init() {
 func main() {
 	if parseFlags {
 		flag.Parse()
+
+		// fill in the options not given on the command line from the environment and the -config file
+		if err := nodeConfig.ApplyConfigSources(); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
 	}
 
 	// create a new fiber coin instance
@@ -0,0 +1,97 @@
+/*
+laqpay-sim runs the scenarios of the in-process network simulator, see src/daemon/sim.
+Each scenario runs on its own simulated network. The exit status is 1 if a scenario fails.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"../../src/daemon/sim"
+	"../../src/util/logging"
+)
+
+var (
+	run      = ""
+	seed     = int64(1)
+	logLevel = "fatal"
+	list     = false
+)
+
+func registerFlags() {
+	flag.StringVar(&run, "run", run, "only run the scenarios whose name matches this regular expression")
+	flag.Int64Var(&seed, "seed", seed, "seed of the blockchain keys and of the network's random numbers")
+	flag.StringVar(&logLevel, "log-level", logLevel, "log level of the nodes: debug, info, warn, error, fatal or panic")
+	flag.BoolVar(&list, "list", list, "list the scenarios and exit")
+}
+
+func main() {
+	registerFlags()
+	flag.Parse()
+
+	if err := runScenarios(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func runScenarios() error {
+	level, err := logging.LevelFromString(logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid -log-level %q: %v", logLevel, err)
+	}
+	logging.SetLevel(level)
+
+	re, err := regexp.Compile(run)
+	if err != nil {
+		return fmt.Errorf("invalid -run %q: %v", run, err)
+	}
+
+	width := 0
+	for _, s := range sim.Scenarios {
+		if len(s.Name) > width {
+			width = len(s.Name)
+		}
+	}
+
+	failed := 0
+	for _, s := range sim.Scenarios {
+		if !re.MatchString(s.Name) {
+			continue
+		}
+
+		if list {
+			fmt.Printf("%-*s %s\n", width, s.Name, s.Description)
+			continue
+		}
+
+		start := time.Now()
+		if err := runScenario(s); err != nil {
+			fmt.Printf("FAIL %s (%s): %v\n", s.Name, time.Since(start).Round(time.Millisecond), err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("ok   %s (%s)\n", s.Name, time.Since(start).Round(time.Millisecond))
+	}
+
+	if failed != 0 {
+		return fmt.Errorf("%d scenarios failed", failed)
+	}
+
+	return nil
+}
+
+func runScenario(s sim.Scenario) error {
+	n, err := sim.NewNetwork(seed)
+	if err != nil {
+		return err
+	}
+	defer n.Shutdown()
+
+	return s.Run(n)
+}
@@ -0,0 +1,965 @@
+/*
+newcoin generates the blockchain parameters of a new fiber coin from a template fiber.toml
+*/
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"../../src/cipher"
+	"../../src/cipher/bip39"
+	"../../src/coin"
+	"../../src/fiber"
+	"../../src/params"
+	"../../src/readable"
+	"../../src/util/droplet"
+	"../../src/util/file"
+)
+
+// newcoin reads a template fiber.toml, generates new genesis and block publisher keys,
+// signs the genesis block and creates the distribution addresses. It writes params.go,
+// the daemon's main file with the new genesis parameters and node options, a fiber.toml
+// with the new genesis parameters, the GUI display settings and a keys file with the secret keys,
+// then reloads the written files and prints a verification report.
+// With -regenerate, it only rewrites params.go and the daemon's main file from the template,
+// keeping its genesis parameters, e.g. after adding checkpoints to it.
+
+var (
+	templateFile         = "fiber.toml"
+	coinName             = "laqpay"
+	distributionCount    = 100
+	genesisTimestamp     uint64
+	paramsFile           = "src/params/params.go"
+	daemonFile           = "cmd/laqpay-daemon/laqpay-daemon.go"
+	daemonVersion        = ""
+	configFile           = "fiber.toml"
+	guiConfigFile        = "fiber.json"
+	keysFile             = "newcoin-keys.json"
+	allowKeysFileReplace = false
+	regenerate           = false
+)
+
+func registerFlags() {
+	flag.StringVar(&templateFile, "template", templateFile, "template fiber.toml. The node and params options are copied from it, except the generated parameters")
+	flag.StringVar(&coinName, "coin", coinName, "name of the coin")
+	flag.IntVar(&distributionCount, "distribution-addresses", distributionCount, "number of distribution addresses to create. Must divide params.max_coin_supply")
+	flag.Uint64Var(&genesisTimestamp, "genesis-timestamp", genesisTimestamp, "genesis block timestamp. Defaults to the current time")
+	flag.StringVar(&paramsFile, "params-file", paramsFile, "params.go file to write")
+	flag.StringVar(&daemonFile, "daemon-file", daemonFile, "daemon main file to write, with the genesis parameters and the node options")
+	flag.StringVar(&daemonVersion, "daemon-version", daemonVersion, "version of the daemon. Defaults to the version in -daemon-file, or 0.1.0 if it does not exist")
+	flag.StringVar(&configFile, "config-file", configFile, "fiber.toml file to write. Can be the same file as -template")
+	flag.StringVar(&guiConfigFile, "gui-config-file", guiConfigFile, "file to write the GUI display settings to, in the format of the fiber field of /api/v1/health")
+	flag.StringVar(&keysFile, "keys-file", keysFile, "file to write the genesis and block publisher secret keys and the distribution seed to. Keep it offline")
+	flag.BoolVar(&allowKeysFileReplace, "replace-keys-file", allowKeysFileReplace, "replace -keys-file if it exists. The secret keys of the coin it was created for are lost")
+	flag.BoolVar(&regenerate, "regenerate", regenerate, "rewrite -params-file and -daemon-file from -template, keeping its genesis parameters, checkpoints and publisher key changes. No keys are generated")
+}
+
+// Keys are the secret parameters of a new coin, written to the keys file
+type Keys struct {
+	GenesisAddress   string `json:"genesis_address"`
+	GenesisSeckey    string `json:"genesis_secret_key"`
+	BlockchainPubkey string `json:"blockchain_public_key"`
+	BlockchainSeckey string `json:"blockchain_secret_key"`
+	// DistributionSeed is the seed of a deterministic wallet whose first addresses are the distribution addresses
+	DistributionSeed string `json:"distribution_seed"`
+}
+
+// Coin are the generated parameters of a new coin
+type Coin struct {
+	Name   string
+	Config fiber.Config
+	Keys   Keys
+
+	genesisSeckey    cipher.SecKey
+	blockchainSeckey cipher.SecKey
+}
+
+func main() {
+	registerFlags()
+	flag.Parse()
+
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if regenerate {
+		return runRegenerate()
+	}
+
+	if !allowKeysFileReplace {
+		exists, err := file.Exists(keysFile)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("-keys-file %s already exists. Use -replace-keys-file to replace it", keysFile)
+		}
+	}
+
+	config, err := loadConfig(templateFile)
+	if err != nil {
+		return fmt.Errorf("load -template %s failed: %v", templateFile, err)
+	}
+
+	if genesisTimestamp == 0 {
+		genesisTimestamp = uint64(time.Now().UTC().Unix())
+	}
+
+	c, err := NewCoin(coinName, config, distributionCount, genesisTimestamp)
+	if err != nil {
+		return err
+	}
+
+	if daemonVersion == "" {
+		daemonVersion, err = readDaemonVersion(daemonFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write the secret keys first, so that they are not lost if writing the other files fails
+	if err := file.SaveJSON(keysFile, c.Keys, 0600); err != nil {
+		return err
+	}
+
+	p, err := c.Params()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(paramsFile, p, 0644); err != nil {
+		return err
+	}
+
+	d, err := c.Daemon(daemonVersion)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(daemonFile, d, 0644); err != nil {
+		return err
+	}
+
+	t, err := c.FiberTOML()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(configFile, t, 0644); err != nil {
+		return err
+	}
+
+	if err := file.SaveJSON(guiConfigFile, c.GUIConfig(), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s, %s, %s, %s and %s\n\n", paramsFile, daemonFile, configFile, guiConfigFile, keysFile)
+
+	if dropped := len(config.Params.Checkpoints) + len(config.Params.PublisherKeyChanges); dropped != 0 {
+		fmt.Printf("The checkpoints and publisher key changes of -template were dropped, they belong to the old genesis block\n\n")
+	}
+
+	r := c.Verify(configFile, p, d)
+	r.Print()
+	if !r.OK() {
+		return errors.New("verification failed")
+	}
+
+	return nil
+}
+
+// runRegenerate rewrites params.go and the daemon's main file from the template
+func runRegenerate() error {
+	config, err := loadConfig(templateFile)
+	if err != nil {
+		return fmt.Errorf("load -template %s failed: %v", templateFile, err)
+	}
+
+	if daemonVersion == "" {
+		daemonVersion, err = readDaemonVersion(daemonFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	c := &Coin{
+		Name:   coinName,
+		Config: config,
+	}
+
+	p, err := c.Params()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(paramsFile, p, 0644); err != nil {
+		return err
+	}
+
+	d, err := c.Daemon(daemonVersion)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(daemonFile, d, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s and %s\n\n", paramsFile, daemonFile)
+
+	var r Report
+	dist := distribution(config.Params)
+	r.add("distribution is valid", dist.Validate())
+	c.verifyGenerated(&r, config, p, d)
+	r.Print()
+	if !r.OK() {
+		return errors.New("verification failed")
+	}
+
+	return nil
+}
+
+// readDaemonVersion returns the Version of an existing daemon main file, so that it is kept
+// when the file is regenerated. It returns 0.1.0 if the file does not exist
+func readDaemonVersion(filename string) (string, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "0.1.0", nil
+		}
+		return "", err
+	}
+
+	values, err := daemonValues(filename, src)
+	if err != nil {
+		return "", fmt.Errorf("parse -daemon-file %s failed: %v", filename, err)
+	}
+
+	v, ok := values["Version"]
+	if !ok {
+		return "", fmt.Errorf("-daemon-file %s has no Version, set -daemon-version", filename)
+	}
+
+	return strconv.Unquote(v)
+}
+
+// daemonValues returns the literal values of the package level variables of a daemon main file,
+// as written in the source
+func daemonValues(filename string, src []byte) (map[string]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				if i >= len(vs.Values) {
+					break
+				}
+				if lit, ok := vs.Values[i].(*ast.BasicLit); ok {
+					values[name.Name] = lit.Value
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// loadConfig loads a fiber.toml file
+func loadConfig(filename string) (fiber.Config, error) {
+	return fiber.NewConfig(filepath.Base(filename), filepath.Dir(filename))
+}
+
+// NewCoin generates new genesis and block publisher keys and distribution addresses,
+// and signs the genesis block. The other parameters are copied from config
+func NewCoin(name string, config fiber.Config, distributionCount int, genesisTimestamp uint64) (*Coin, error) {
+	if name == "" {
+		return nil, errors.New("coin name is required")
+	}
+	if distributionCount < 1 {
+		return nil, errors.New("the number of distribution addresses must be >= 1")
+	}
+
+	genesisPubkey, genesisSeckey := cipher.GenerateKeyPair()
+	blockchainPubkey, blockchainSeckey := cipher.GenerateKeyPair()
+	genesisAddress := cipher.AddressFromPubKey(genesisPubkey)
+
+	seed, err := bip39.NewDefaultMnemonic()
+	if err != nil {
+		return nil, err
+	}
+
+	seckeys, err := cipher.GenerateDeterministicKeyPairs([]byte(seed), distributionCount)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, len(seckeys))
+	for i, sk := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(sk).String()
+	}
+
+	config.Params.DistributionAddresses = addrs
+	config.Params.Checkpoints = nil
+	config.Params.PublisherKeyChanges = nil
+
+	dist := distribution(config.Params)
+	if err := dist.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid distribution: %v", err)
+	}
+
+	coinVolume, err := mulUint64(config.Params.MaxCoinSupply, droplet.Multiplier)
+	if err != nil {
+		return nil, errors.New("params.max_coin_supply is too large")
+	}
+
+	gb, err := coin.NewGenesisBlock(genesisAddress, coinVolume, genesisTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Node.GenesisAddressStr = genesisAddress.String()
+	config.Node.GenesisSignatureStr = cipher.MustSignHash(gb.HashHeader(), blockchainSeckey).Hex()
+	config.Node.GenesisTimestamp = genesisTimestamp
+	config.Node.GenesisCoinVolume = coinVolume
+	config.Node.BlockchainPubkeyStr = blockchainPubkey.Hex()
+	config.Node.BlockchainSeckeyStr = ""
+	config.Node.BlockPublisherPubkeys = nil
+
+	return &Coin{
+		Name:   name,
+		Config: config,
+		Keys: Keys{
+			GenesisAddress:   genesisAddress.String(),
+			GenesisSeckey:    genesisSeckey.Hex(),
+			BlockchainPubkey: blockchainPubkey.Hex(),
+			BlockchainSeckey: blockchainSeckey.Hex(),
+			DistributionSeed: seed,
+		},
+		genesisSeckey:    genesisSeckey,
+		blockchainSeckey: blockchainSeckey,
+	}, nil
+}
+
+func distribution(p fiber.ParamsConfig) params.Distribution {
+	return params.Distribution{
+		MaxCoinSupply:        p.MaxCoinSupply,
+		InitialUnlockedCount: p.InitialUnlockedCount,
+		UnlockAddressRate:    p.UnlockAddressRate,
+		UnlockTimeInterval:   p.UnlockTimeInterval,
+		UnlockStartTime:      p.UnlockStartTime,
+		Addresses:            p.DistributionAddresses,
+	}
+}
+
+func checkpoints(p fiber.ParamsConfig) params.Checkpoints {
+	c := make(params.Checkpoints, len(p.Checkpoints))
+	for i, cp := range p.Checkpoints {
+		c[i] = params.Checkpoint{
+			Seq:  cp.Seq,
+			Hash: cp.Hash,
+		}
+	}
+	return c
+}
+
+func publisherKeyChanges(p fiber.ParamsConfig) params.PublisherKeyChanges {
+	c := make(params.PublisherKeyChanges, len(p.PublisherKeyChanges))
+	for i, kc := range p.PublisherKeyChanges {
+		c[i] = params.PublisherKeyChange{
+			Seq:    kc.Seq,
+			Pubkey: kc.Pubkey,
+			Sig:    kc.Sig,
+		}
+	}
+	return c
+}
+
+func mulUint64(a, b uint64) (uint64, error) {
+	if a != 0 && (a*b)/a != b {
+		return 0, errors.New("uint64 overflow")
+	}
+	return a * b, nil
+}
+
+// GUIConfig returns the GUI display settings
+func (c *Coin) GUIConfig() readable.FiberConfig {
+	n := c.Config.Node
+	return readable.FiberConfig{
+		Name:                  c.Name,
+		DisplayName:           n.DisplayName,
+		Ticker:                n.Ticker,
+		CoinHoursName:         n.CoinHoursName,
+		CoinHoursNameSingular: n.CoinHoursNameSingular,
+		CoinHoursTicker:       n.CoinHoursTicker,
+		ExplorerURL:           n.ExplorerURL,
+		Bip44Coin:             n.Bip44Coin,
+	}
+}
+
+// Params returns the gofmt-ed params.go
+func (c *Coin) Params() ([]byte, error) {
+	var b bytes.Buffer
+	if err := paramsTemplate.Execute(&b, struct {
+		Title  string
+		Params fiber.ParamsConfig
+	}{
+		Title:  strings.Title(c.Name),
+		Params: c.Config.Params,
+	}); err != nil {
+		return nil, err
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// Daemon returns the gofmt-ed daemon main file
+func (c *Coin) Daemon(version string) ([]byte, error) {
+	var b bytes.Buffer
+	if err := daemonTemplate.Execute(&b, struct {
+		Name    string
+		Version string
+		Node    fiber.NodeConfig
+	}{
+		Name:    c.Name,
+		Version: version,
+		Node:    c.Config.Node,
+	}); err != nil {
+		return nil, err
+	}
+
+	return format.Source(b.Bytes())
+}
+
+// FiberTOML returns the fiber.toml of the coin
+func (c *Coin) FiberTOML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := fiberTemplate.Execute(&b, c.Config); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Check is a check of the verification report
+type Check struct {
+	Name string
+	Err  error
+}
+
+// Report is the verification report of a new coin
+type Report struct {
+	Checks []Check
+}
+
+func (r *Report) add(name string, err error) {
+	r.Checks = append(r.Checks, Check{
+		Name: name,
+		Err:  err,
+	})
+}
+
+// OK returns true if all checks passed
+func (r Report) OK() bool {
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Print prints the verification report
+func (r Report) Print() {
+	fmt.Println("Verification report:")
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			fmt.Printf("[FAIL] %s: %v\n", c.Name, c.Err)
+		} else {
+			fmt.Printf("[OK]   %s\n", c.Name)
+		}
+	}
+}
+
+// Verify checks the generated parameters, as reloaded from the written fiber.toml, params.go and daemon main file
+func (c *Coin) Verify(configFile string, paramsGo, daemonGo []byte) Report {
+	var r Report
+
+	r.add("genesis address belongs to the genesis secret key", func() error {
+		addr, err := cipher.AddressFromSecKey(c.genesisSeckey)
+		if err != nil {
+			return err
+		}
+		if addr.String() != c.Keys.GenesisAddress {
+			return fmt.Errorf("%s != %s", addr, c.Keys.GenesisAddress)
+		}
+		return nil
+	}())
+
+	r.add("blockchain public key belongs to the blockchain secret key", func() error {
+		pubkey, err := cipher.PubKeyFromSecKey(c.blockchainSeckey)
+		if err != nil {
+			return err
+		}
+		if pubkey.Hex() != c.Keys.BlockchainPubkey {
+			return fmt.Errorf("%s != %s", pubkey.Hex(), c.Keys.BlockchainPubkey)
+		}
+		return nil
+	}())
+
+	r.add("distribution addresses are derived from the distribution seed", func() error {
+		seckeys, err := cipher.GenerateDeterministicKeyPairs([]byte(c.Keys.DistributionSeed), len(c.Config.Params.DistributionAddresses))
+		if err != nil {
+			return err
+		}
+		for i, sk := range seckeys {
+			if addr := cipher.MustAddressFromSecKey(sk).String(); addr != c.Config.Params.DistributionAddresses[i] {
+				return fmt.Errorf("address %d is %s, expected %s", i, c.Config.Params.DistributionAddresses[i], addr)
+			}
+		}
+		return nil
+	}())
+
+	config, err := loadConfig(configFile)
+	r.add(fmt.Sprintf("%s reloads with the generated parameters", configFile), func() error {
+		if err != nil {
+			return err
+		}
+		n, m := config.Node, c.Config.Node
+		switch {
+		case n.GenesisSignatureStr != m.GenesisSignatureStr:
+			return errors.New("genesis_signature_str differs")
+		case n.GenesisAddressStr != m.GenesisAddressStr:
+			return errors.New("genesis_address_str differs")
+		case n.BlockchainPubkeyStr != m.BlockchainPubkeyStr:
+			return errors.New("blockchain_pubkey_str differs")
+		case n.GenesisTimestamp != m.GenesisTimestamp:
+			return errors.New("genesis_timestamp differs")
+		case n.GenesisCoinVolume != m.GenesisCoinVolume:
+			return errors.New("genesis_coin_volume differs")
+		case config.Params.MaxCoinSupply != c.Config.Params.MaxCoinSupply:
+			return errors.New("max_coin_supply differs")
+		case strings.Join(config.Params.DistributionAddresses, ",") != strings.Join(c.Config.Params.DistributionAddresses, ","):
+			return errors.New("distribution_addresses differ")
+		}
+		return nil
+	}())
+	if err != nil {
+		return r
+	}
+
+	genesisAddress, err := cipher.DecodeBase58Address(config.Node.GenesisAddressStr)
+	r.add("genesis address is valid", err)
+	if err != nil {
+		return r
+	}
+
+	gb, err := coin.NewGenesisBlock(genesisAddress, config.Node.GenesisCoinVolume, config.Node.GenesisTimestamp)
+	r.add("genesis signature is signed by the blockchain public key", func() error {
+		if err != nil {
+			return err
+		}
+		pubkey, err := cipher.PubKeyFromHex(config.Node.BlockchainPubkeyStr)
+		if err != nil {
+			return err
+		}
+		sig, err := cipher.SigFromHex(config.Node.GenesisSignatureStr)
+		if err != nil {
+			return err
+		}
+		return cipher.VerifyPubKeySignedHash(pubkey, sig, gb.HashHeader())
+	}())
+	if err != nil {
+		return r
+	}
+
+	r.add("genesis coin volume is the max coin supply", func() error {
+		if config.Node.GenesisCoinVolume != config.Params.MaxCoinSupply*droplet.Multiplier {
+			return fmt.Errorf("genesis_coin_volume %d != max_coin_supply %d * %d droplets",
+				config.Node.GenesisCoinVolume, config.Params.MaxCoinSupply, uint64(droplet.Multiplier))
+		}
+		return nil
+	}())
+
+	dist := distribution(config.Params)
+	err = dist.Validate()
+	r.add(fmt.Sprintf("distribution of %d addresses with %d coins each, %d unlocked",
+		len(dist.Addresses), dist.MaxCoinSupply/uint64(len(dist.Addresses)), dist.InitialUnlockedCount), err)
+	if err != nil {
+		return r
+	}
+
+	if schedule := dist.UnlockSchedule(); len(schedule) != 0 {
+		r.add(fmt.Sprintf("remaining %d addresses unlock in %d steps from %s to %s",
+			len(dist.LockedAddresses(0)), len(schedule),
+			time.Unix(int64(schedule[0].Time), 0).UTC().Format(time.RFC3339),
+			time.Unix(int64(schedule[len(schedule)-1].Time), 0).UTC().Format(time.RFC3339)), nil)
+	}
+
+	r.add("distribution transaction spends the genesis output", func() error {
+		var txn coin.Transaction
+		ux := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+		if err := txn.PushInput(ux[0].Hash()); err != nil {
+			return err
+		}
+		for _, addr := range dist.AddressesDecoded() {
+			if err := txn.PushOutput(addr, dist.AddressInitialBalance()*droplet.Multiplier, 1); err != nil {
+				return err
+			}
+		}
+		txn.SignInputs([]cipher.SecKey{c.genesisSeckey})
+		if err := txn.UpdateHeader(); err != nil {
+			return err
+		}
+		if err := txn.Verify(); err != nil {
+			return err
+		}
+		return txn.VerifyInputSignatures(ux)
+	}())
+
+	c.verifyGenerated(&r, config, paramsGo, daemonGo)
+
+	return r
+}
+
+// verifyGenerated checks the generated params.go and daemon main file against config
+func (c *Coin) verifyGenerated(r *Report, config fiber.Config, paramsGo, daemonGo []byte) {
+	checkpoints, keyChanges := checkpoints(config.Params), publisherKeyChanges(config.Params)
+	err := checkpoints.Validate()
+	if err == nil {
+		err = keyChanges.Validate()
+	}
+	r.add(fmt.Sprintf("%d checkpoints and %d publisher key changes", len(checkpoints), len(keyChanges)), err)
+
+	r.add(fmt.Sprintf("%s is valid Go with the distribution addresses, checkpoints and publisher key changes", paramsFile), func() error {
+		if _, err := parser.ParseFile(token.NewFileSet(), paramsFile, paramsGo, 0); err != nil {
+			return err
+		}
+		for _, addr := range config.Params.DistributionAddresses {
+			if !bytes.Contains(paramsGo, []byte(strconv.Quote(addr))) {
+				return fmt.Errorf("missing distribution address %s", addr)
+			}
+		}
+		for _, cp := range checkpoints {
+			if !bytes.Contains(paramsGo, []byte(strconv.Quote(cp.Hash))) {
+				return fmt.Errorf("missing checkpoint %d", cp.Seq)
+			}
+		}
+		for _, kc := range keyChanges {
+			if !bytes.Contains(paramsGo, []byte(strconv.Quote(kc.Sig))) {
+				return fmt.Errorf("missing publisher key change %d", kc.Seq)
+			}
+		}
+		return nil
+	}())
+
+	r.add(fmt.Sprintf("%s is valid Go with the genesis parameters", daemonFile), func() error {
+		values, err := daemonValues(daemonFile, daemonGo)
+		if err != nil {
+			return err
+		}
+		for _, x := range []struct {
+			name  string
+			value string
+		}{
+			{"CoinName", strconv.Quote(c.Name)},
+			{"GenesisSignatureStr", strconv.Quote(config.Node.GenesisSignatureStr)},
+			{"GenesisAddressStr", strconv.Quote(config.Node.GenesisAddressStr)},
+			{"BlockchainPubkeyStr", strconv.Quote(config.Node.BlockchainPubkeyStr)},
+			{"BlockchainSeckeyStr", strconv.Quote("")},
+			{"GenesisTimestamp", strconv.FormatUint(config.Node.GenesisTimestamp, 10)},
+			{"GenesisCoinVolume", strconv.FormatUint(config.Node.GenesisCoinVolume, 10)},
+		} {
+			if values[x.name] != x.value {
+				return fmt.Errorf("%s is %s, expected %s", x.name, values[x.name], x.value)
+			}
+		}
+		return nil
+	}())
+}
+
+var templateFuncs = template.FuncMap{
+	"quote": strconv.Quote,
+	"quoteList": func(xs []string) string {
+		q := make([]string, len(xs))
+		for i, x := range xs {
+			q[i] = strconv.Quote(x)
+		}
+		return "[" + strings.Join(q, ", ") + "]"
+	},
+}
+
+var paramsTemplate = template.Must(template.New("params.go").Funcs(templateFuncs).Parse(`package params
+
+/*
+CODE GENERATED AUTOMATICALLY WITH FIBER COIN CREATOR
+AVOID EDITING THIS MANUALLY
+*/
+
+var (
+	// MainNetDistribution {{.Title}} mainnet coin distribution parameters
+	MainNetDistribution = Distribution{
+		MaxCoinSupply:        {{.Params.MaxCoinSupply}},
+		InitialUnlockedCount: {{.Params.InitialUnlockedCount}},
+		UnlockAddressRate:    {{.Params.UnlockAddressRate}},
+		UnlockTimeInterval:   {{.Params.UnlockTimeInterval}},
+		UnlockStartTime:      {{.Params.UnlockStartTime}},
+		Addresses: []string{
+{{- range .Params.DistributionAddresses}}
+			{{quote .}},
+{{- end}}
+		},
+	}
+
+	// MainNetCheckpoints {{.Title}} mainnet block checkpoints
+	MainNetCheckpoints = Checkpoints{
+{{- range .Params.Checkpoints}}
+		{
+			Seq:  {{.Seq}},
+			Hash: {{quote .Hash}},
+		},
+{{- end}}
+	}
+
+	// MainNetPublisherKeyChanges {{.Title}} mainnet block publisher key changes
+	MainNetPublisherKeyChanges = PublisherKeyChanges{
+{{- range .Params.PublisherKeyChanges}}
+		{
+			Seq:    {{.Seq}},
+			Pubkey: {{quote .Pubkey}},
+			Sig:    {{quote .Sig}},
+		},
+{{- end}}
+	}
+
+	// UserVerifyTxn transaction verification parameters for user-created transactions
+	UserVerifyTxn = VerifyTxn{
+		// BurnFactor can be overriden with ` + "`USER_BURN_FACTOR`" + ` env var
+		BurnFactor: {{.Params.UserBurnFactor}},
+		// MaxTransactionSize can be overriden with ` + "`USER_MAX_TXN_SIZE`" + ` env var
+		MaxTransactionSize: {{.Params.UserMaxTransactionSize}}, // in bytes
+		// MaxDropletPrecision can be overriden with ` + "`USER_MAX_DECIMALS`" + ` env var
+		MaxDropletPrecision: {{.Params.UserMaxDropletPrecision}},
+	}
+)
+`))
+
+var daemonTemplate = template.Must(template.New("daemon").Funcs(templateFuncs).Parse(`/*
+{{.Name}} daemon
+*/
+package main
+
+/*
+CODE GENERATED AUTOMATICALLY WITH FIBER COIN CREATOR
+AVOID EDITING THIS MANUALLY
+*/
+
+import (
+	"flag"
+	_ "net/http/pprof"
+	"os"
+
+	"../../src/fiber"
+	"../../src/laqpay"
+	"../../src/readable"
+	"../../src/util/logging"
+)
+
+var (
+	// Version of the node. Can be set by -ldflags
+	Version = {{quote .Version}}
+	// Commit ID. Can be set by -ldflags
+	Commit = ""
+	// Branch name. Can be set by -ldflags
+	Branch = ""
+	// ConfigMode (possible values are "", "STANDALONE_CLIENT").
+	// This is used to change the default configuration.
+	// Can be set by -ldflags
+	ConfigMode = ""
+
+	logger = logging.MustGetLogger("main")
+
+	// CoinName name of coin
+	CoinName = {{quote .Name}}
+
+	// GenesisSignatureStr hex string of genesis signature
+	GenesisSignatureStr = {{quote .Node.GenesisSignatureStr}}
+	// GenesisAddressStr genesis address string
+	GenesisAddressStr = {{quote .Node.GenesisAddressStr}}
+	// BlockchainPubkeyStr pubic key string
+	BlockchainPubkeyStr = {{quote .Node.BlockchainPubkeyStr}}
+	// BlockchainSeckeyStr empty private key string
+	BlockchainSeckeyStr = ""
+
+	// GenesisTimestamp genesis block create unix time
+	GenesisTimestamp uint64 = {{.Node.GenesisTimestamp}}
+	// GenesisCoinVolume represents the coin capacity
+	GenesisCoinVolume uint64 = {{.Node.GenesisCoinVolume}}
+
+	// DefaultConnections the default trust node addresses
+	DefaultConnections = []string{
+{{- range .Node.DefaultConnections}}
+		{{quote .}},
+{{- end}}
+	}
+
+	nodeConfig = laqpay.NewNodeConfig(ConfigMode, fiber.NodeConfig{
+		CoinName:            CoinName,
+		GenesisSignatureStr: GenesisSignatureStr,
+		GenesisAddressStr:   GenesisAddressStr,
+		GenesisCoinVolume:   GenesisCoinVolume,
+		GenesisTimestamp:    GenesisTimestamp,
+		BlockchainPubkeyStr: BlockchainPubkeyStr,
+		BlockchainSeckeyStr: BlockchainSeckeyStr,
+{{- if .Node.BlockPublisherPubkeys}}
+		BlockPublisherPubkeys: []string{
+{{- range .Node.BlockPublisherPubkeys}}
+			{{quote .}},
+{{- end}}
+		},
+{{- end}}
+		DefaultConnections:  DefaultConnections,
+		PeerListURL:         {{quote .Node.PeerListURL}},
+{{- if .Node.DNSSeeds}}
+		DNSSeeds: []string{
+{{- range .Node.DNSSeeds}}
+			{{quote .}},
+{{- end}}
+		},
+{{- end}}
+		Port:                {{.Node.Port}},
+		WebInterfacePort:    {{.Node.WebInterfacePort}},
+		DataDirectory:       {{quote (print "$HOME/." .Name)}},
+
+		UnconfirmedBurnFactor:          {{.Node.UnconfirmedBurnFactor}},
+		UnconfirmedMaxTransactionSize:  {{.Node.UnconfirmedMaxTransactionSize}},
+		UnconfirmedMaxDropletPrecision: {{.Node.UnconfirmedMaxDropletPrecision}},
+		CreateBlockBurnFactor:          {{.Node.CreateBlockBurnFactor}},
+		CreateBlockMaxTransactionSize:  {{.Node.CreateBlockMaxTransactionSize}},
+		CreateBlockMaxDropletPrecision: {{.Node.CreateBlockMaxDropletPrecision}},
+		MaxBlockTransactionsSize:       {{.Node.MaxBlockTransactionsSize}},
+		MaxBlockTransactionsPerAddress: {{.Node.MaxBlockTransactionsPerAddress}},
+		AllowEmptyBlocks:               {{.Node.AllowEmptyBlocks}},
+		BlockCreationInterval:          {{.Node.BlockCreationInterval}},
+		BlockMinTransactions:           {{.Node.BlockMinTransactions}},
+		BlockMinFee:                    {{.Node.BlockMinFee}},
+		MaxBlockAge:                    {{.Node.MaxBlockAge}},
+		EmptyBlockInterval:             {{.Node.EmptyBlockInterval}},
+
+		DisplayName:           {{quote .Node.DisplayName}},
+		Ticker:                {{quote .Node.Ticker}},
+		CoinHoursName:         {{quote .Node.CoinHoursName}},
+		CoinHoursNameSingular: {{quote .Node.CoinHoursNameSingular}},
+		CoinHoursTicker:       {{quote .Node.CoinHoursTicker}},
+		ExplorerURL:           {{quote .Node.ExplorerURL}},
+		Bip44Coin:             {{.Node.Bip44Coin}},
+	})
+
+	parseFlags = true
+)
+
+func init() {
+	nodeConfig.RegisterFlags()
+}
+
+func main() {
+	if parseFlags {
+		flag.Parse()
+
+		// fill in the options not given on the command line from the environment and the -config file
+		if err := nodeConfig.ApplyConfigSources(); err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+	}
+
+	// create a new fiber coin instance
+	coin := laqpay.NewCoin(laqpay.Config{
+		Node: nodeConfig,
+		Build: readable.BuildInfo{
+			Version: Version,
+			Commit:  Commit,
+			Branch:  Branch,
+		},
+	}, logger)
+
+	// parse config values
+	if err := coin.ParseConfig(); err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+
+	// run fiber coin node
+	if err := coin.Run(); err != nil {
+		os.Exit(1)
+	}
+}
+`))
+
+var fiberTemplate = template.Must(template.New("fiber.toml").Funcs(templateFuncs).Parse(`[node]
+genesis_signature_str = {{quote .Node.GenesisSignatureStr}}
+genesis_address_str = {{quote .Node.GenesisAddressStr}}
+blockchain_pubkey_str = {{quote .Node.BlockchainPubkeyStr}}
+genesis_timestamp = {{.Node.GenesisTimestamp}}
+genesis_coin_volume = {{.Node.GenesisCoinVolume}}
+port = {{.Node.Port}}
+web_interface_port = {{.Node.WebInterfacePort}}
+default_connections = {{quoteList .Node.DefaultConnections}}
+peer_list_url = {{quote .Node.PeerListURL}}
+dns_seeds = {{quoteList .Node.DNSSeeds}}
+unconfirmed_burn_factor = {{.Node.UnconfirmedBurnFactor}}
+unconfirmed_max_transaction_size = {{.Node.UnconfirmedMaxTransactionSize}}
+unconfirmed_max_decimals = {{.Node.UnconfirmedMaxDropletPrecision}}
+create_block_burn_factor = {{.Node.CreateBlockBurnFactor}}
+create_block_max_transaction_size = {{.Node.CreateBlockMaxTransactionSize}}
+create_block_max_decimals = {{.Node.CreateBlockMaxDropletPrecision}}
+max_block_transactions_size = {{.Node.MaxBlockTransactionsSize}}
+max_block_transactions_per_address = {{.Node.MaxBlockTransactionsPerAddress}}
+allow_empty_blocks = {{.Node.AllowEmptyBlocks}}
+block_creation_interval = {{.Node.BlockCreationInterval}}
+block_min_transactions = {{.Node.BlockMinTransactions}}
+block_min_fee = {{.Node.BlockMinFee}}
+max_block_age = {{.Node.MaxBlockAge}}
+empty_block_interval = {{.Node.EmptyBlockInterval}}
+display_name = {{quote .Node.DisplayName}}
+ticker = {{quote .Node.Ticker}}
+coin_hours_display_name = {{quote .Node.CoinHoursName}}
+coin_hours_display_name_singular = {{quote .Node.CoinHoursNameSingular}}
+coin_hours_ticker = {{quote .Node.CoinHoursTicker}}
+explorer_url = {{quote .Node.ExplorerURL}}
+bip44_coin = {{.Node.Bip44Coin}}
+
+[params]
+max_coin_supply = {{.Params.MaxCoinSupply}}
+initial_unlocked_count = {{.Params.InitialUnlockedCount}}
+unlock_address_rate = {{.Params.UnlockAddressRate}}
+unlock_time_interval = {{.Params.UnlockTimeInterval}}
+unlock_start_time = {{.Params.UnlockStartTime}}
+user_max_decimals = {{.Params.UserMaxDropletPrecision}}
+user_max_transaction_size = {{.Params.UserMaxTransactionSize}}
+user_burn_factor = {{.Params.UserBurnFactor}}
+distribution_addresses = [
+{{- range .Params.DistributionAddresses}}
+	{{quote .}},
+{{- end}}
+]
+`))
@@ -3,6 +3,7 @@ package api
 // APIs for blockchain related information
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 
 	"../../src/cipher"
 	"../../src/coin"
+	"../../src/daemon"
 	"../../src/readable"
 	wh "../../src/util/http"
 	"../../src/visor"
@@ -393,3 +395,205 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, rb)
 	}
 }
+
+// publisherKeysHandler returns or adds block publisher key changes
+// Method: GET, POST
+// URI: /api/v2/blockchain/publisher_keys
+func publisherKeysHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getPublisherKeysHandler(w, gateway)
+		case http.MethodPost:
+			addPublisherKeyChangeHandler(w, r, gateway)
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// Returns the blockchain pubkey, the committed publisher key changes and the proposed changes, sorted by ascending seq
+func getPublisherKeysHandler(w http.ResponseWriter, gateway Gatewayer) {
+	writeHTTPResponse(w, HTTPResponse{
+		Data: readable.NewPublisherKeys(gateway.VisorConfig().BlockchainPubkey, gateway.GetPublisherKeyChanges()),
+	})
+}
+
+// Proposes a publisher key change and broadcasts it to peers.
+// The change must be signed by the key that signs the block before seq,
+// and take effect above the head block and after the committed changes
+// Args:
+//     seq: sequence of the first block signed by pubkey
+//     pubkey: hex-encoded public key of the new block publisher
+//     sig: hex-encoded signature of the change
+func addPublisherKeyChangeHandler(w http.ResponseWriter, r *http.Request, gateway Gatewayer) {
+	var req readable.PublisherKeyChange
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	kc, err := req.ToVisorPublisherKeyChange()
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	if err := gateway.InjectPublisherKeyChange(kc); err != nil {
+		var resp HTTPResponse
+		switch err {
+		case visor.ErrPublisherKeyChangeKnown:
+			resp = NewHTTPErrorResponse(http.StatusConflict, err.Error())
+		default:
+			resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		}
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: readable.NewPublisherKeyChange(kc),
+	})
+}
+
+// blockTemplateHandler creates an unsigned block from the unconfirmed transactions, without changing the node's state.
+// The block header hash, header.block_hash, is signed outside of the node
+// and the signature is given to /api/v2/block/submit with the block's timestamp and transactions
+// Method: GET
+// URI: /api/v2/block/template
+// Response:
+//     200 - the block template
+//     400 - no block can be created, e.g. there are no valid unconfirmed transactions
+//     403 - the block publishers agree on each block
+func blockTemplateHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		b, err := gateway.CreateBlockTemplate()
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case daemon.ErrBlockTemplateConsensus:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rb, err := readable.NewBlock(*b)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rb,
+		})
+	}
+}
+
+// SubmitBlockRequest is the request data for POST /api/v2/block/submit
+type SubmitBlockRequest struct {
+	// Hash is the header hash of a block template
+	Hash string `json:"hash"`
+	// Sig is the signature of the header hash by a block publisher key
+	Sig string `json:"sig"`
+	// Timestamp is the time of the block template
+	Timestamp uint64 `json:"timestamp"`
+	// Txids are the transaction ids of the block template, in its order
+	Txids []string `json:"txids"`
+}
+
+// blockSubmitHandler recreates a block template created by /api/v2/block/template, signs it,
+// executes it and broadcasts it
+// Method: POST
+// URI: /api/v2/block/submit
+// Args:
+//     hash: header hash of the block template
+//     sig: hex-encoded signature of the header hash by the key that signs the block at its seq
+//     timestamp: timestamp of the block template
+//     txids: transaction ids of the block template, in its order
+// Response:
+//     200 - the executed block
+//     400 - invalid request, or the block can't be executed, e.g. the signature is invalid
+//     403 - the block publishers agree on each block, or the node is a block publisher that does not hold the lease
+//     409 - the block template can't be recreated, because the head block or the unconfirmed transactions changed
+//     503 - the block was executed but could not be broadcast, it is announced to peers later
+func blockSubmitHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req SubmitBlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		hash, err := cipher.SHA256FromHex(req.Hash)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid hash: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		sig, err := cipher.SigFromHex(req.Sig)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid sig: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txids := make([]cipher.SHA256, len(req.Txids))
+		for i, s := range req.Txids {
+			txids[i], err = cipher.SHA256FromHex(s)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid txid %q: %v", s, err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		sb, err := gateway.SubmitBlock(hash, sig, req.Timestamp, txids)
+		if err != nil {
+			var resp HTTPResponse
+			switch {
+			case err == daemon.ErrBlockTemplateChanged:
+				resp = NewHTTPErrorResponse(http.StatusConflict, err.Error())
+			case err == daemon.ErrBlockTemplateConsensus, err == daemon.ErrBlockTemplateStandby:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+			case sb != nil && daemon.IsBroadcastFailure(err):
+				resp = NewHTTPErrorResponse(http.StatusServiceUnavailable, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rb, err := readable.NewBlock(sb.Block)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rb,
+		})
+	}
+}
@@ -409,6 +409,28 @@ func (c *Client) CoinSupply() (*CoinSupply, error) {
 	return &cs, nil
 }
 
+// Distribution makes a request to GET /api/v2/distribution
+func (c *Client) Distribution() (*readable.Distribution, error) {
+	var d readable.Distribution
+	ok, err := c.GetV2("/api/v2/distribution", &d)
+	if !ok {
+		return nil, err
+	}
+
+	return &d, err
+}
+
+// NodeConfig makes a request to GET /api/v2/config
+func (c *Client) NodeConfig() ([]readable.ConfigOption, error) {
+	var options []readable.ConfigOption
+	ok, err := c.GetV2("/api/v2/config", &options)
+	if !ok {
+		return nil, err
+	}
+
+	return options, err
+}
+
 // BlockByHash makes a request to GET /api/v1/block?hash=xxx
 func (c *Client) BlockByHash(hash string) (*readable.Block, error) {
 	v := url.Values{}
@@ -928,6 +950,122 @@ func (c *Client) NetworkExchangedPeers() ([]string, error) {
 	return dc, nil
 }
 
+// PublisherKeys makes a request to GET /api/v2/blockchain/publisher_keys
+func (c *Client) PublisherKeys() (*readable.PublisherKeys, error) {
+	var pk readable.PublisherKeys
+	ok, err := c.GetV2("/api/v2/blockchain/publisher_keys", &pk)
+	if !ok {
+		return nil, err
+	}
+
+	return &pk, err
+}
+
+// AddPublisherKeyChange makes a request to POST /api/v2/blockchain/publisher_keys
+func (c *Client) AddPublisherKeyChange(kc readable.PublisherKeyChange) (*readable.PublisherKeyChange, error) {
+	var rkc readable.PublisherKeyChange
+	ok, err := c.PostJSONV2("/api/v2/blockchain/publisher_keys", kc, &rkc)
+	if !ok {
+		return nil, err
+	}
+
+	return &rkc, err
+}
+
+// BlockTemplate makes a request to GET /api/v2/block/template
+func (c *Client) BlockTemplate() (*readable.Block, error) {
+	var b readable.Block
+	ok, err := c.GetV2("/api/v2/block/template", &b)
+	if !ok {
+		return nil, err
+	}
+
+	return &b, err
+}
+
+// SubmitBlock makes a request to POST /api/v2/block/submit, with a block template returned by BlockTemplate
+// and the signature of its header hash
+func (c *Client) SubmitBlock(template readable.Block, sig string) (*readable.Block, error) {
+	txids := make([]string, len(template.Body.Transactions))
+	for i, txn := range template.Body.Transactions {
+		txids[i] = txn.Hash
+	}
+
+	var b readable.Block
+	ok, err := c.PostJSONV2("/api/v2/block/submit", SubmitBlockRequest{
+		Hash:      template.Head.Hash,
+		Sig:       sig,
+		Timestamp: template.Head.Time,
+		Txids:     txids,
+	}, &b)
+	if !ok {
+		return nil, err
+	}
+
+	return &b, err
+}
+
+// RegtestMine makes a request to POST /api/v2/regtest/mine
+func (c *Client) RegtestMine(n int, advance time.Duration) (*readable.Blocks, error) {
+	v := url.Values{}
+	v.Add("n", fmt.Sprint(n))
+	if advance != 0 {
+		v.Add("advance", advance.String())
+	}
+
+	var b readable.Blocks
+	ok, err := c.requestV2(http.MethodPost, "/api/v2/regtest/mine?"+v.Encode(), nil, &b)
+	if !ok {
+		return nil, err
+	}
+
+	return &b, err
+}
+
+// NetworkPeers makes a request to GET /api/v2/network/peers
+func (c *Client) NetworkPeers() ([]readable.Peer, error) {
+	var peers []readable.Peer
+	ok, err := c.GetV2("/api/v2/network/peers", &peers)
+	if !ok {
+		return nil, err
+	}
+
+	return peers, err
+}
+
+// SetNetworkPeer makes a request to POST /api/v2/network/peers to add a peer to the peer list,
+// or update the flags of a peer already in it
+func (c *Client) SetNetworkPeer(addr string, trusted, private bool) (*readable.Peer, error) {
+	var p readable.Peer
+	ok, err := c.PostJSONV2("/api/v2/network/peers", PeerRequest{
+		Address: addr,
+		Trusted: trusted,
+		Private: private,
+	}, &p)
+	if !ok {
+		return nil, err
+	}
+
+	return &p, err
+}
+
+// RemoveNetworkPeer makes a request to DELETE /api/v2/network/peers to remove a peer from the peer list
+func (c *Client) RemoveNetworkPeer(addr string) error {
+	v := url.Values{}
+	v.Add("address", addr)
+
+	_, err := c.DeleteV2("/api/v2/network/peers?"+v.Encode(), nil)
+	return err
+}
+
+// NetworkConnect makes a request to POST /api/v2/network/connect
+func (c *Client) NetworkConnect(addr string) error {
+	_, err := c.PostJSONV2("/api/v2/network/connect", ConnectRequest{
+		Address: addr,
+	}, nil)
+	return err
+}
+
 // PendingTransactions makes a request to GET /api/v1/pendingTxs
 func (c *Client) PendingTransactions() ([]readable.UnconfirmedTransactions, error) {
 	var v []readable.UnconfirmedTransactions
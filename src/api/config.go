@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"../../src/readable"
+)
+
+// configHandler returns the effective daemon options and where each was set.
+// Secret values are redacted.
+// URI: /api/v2/config
+// Method: GET
+func configHandler(options []readable.ConfigOption) http.HandlerFunc {
+	if options == nil {
+		options = []readable.ConfigOption{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: options,
+		})
+	}
+}
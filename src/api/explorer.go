@@ -57,7 +57,10 @@ func coinSupplyHandler(gateway Gatewayer) http.HandlerFunc {
 
 		dist := gateway.VisorConfig().Distribution
 
-		unlockedAddrs := dist.UnlockedAddressesDecoded()
+		// Distribution addresses are locked or unlocked as of the head block
+		headTime := allUnspents.HeadBlock.Head.Time
+
+		unlockedAddrs := dist.UnlockedAddressesDecoded(headTime)
 		// Search map of unlocked addresses, used to filter unspents
 		unlockedAddrSet := newAddrSet(unlockedAddrs)
 
@@ -106,7 +109,7 @@ func coinSupplyHandler(gateway Gatewayer) http.HandlerFunc {
 		}
 
 		// locked distribution addresses
-		lockedAddrs := dist.LockedAddressesDecoded()
+		lockedAddrs := dist.LockedAddressesDecoded(headTime)
 		lockedAddrSet := newAddrSet(lockedAddrs)
 
 		// get total coins hours which excludes locked distribution addresses
@@ -147,8 +150,8 @@ func coinSupplyHandler(gateway Gatewayer) http.HandlerFunc {
 			MaxSupply:             maxSupplyStr,
 			CurrentCoinHourSupply: strconv.FormatUint(currentCoinHours, 10),
 			TotalCoinHourSupply:   strconv.FormatUint(totalCoinHours, 10),
-			UnlockedAddresses:     dist.UnlockedAddresses(),
-			LockedAddresses:       dist.LockedAddresses(),
+			UnlockedAddresses:     dist.UnlockedAddresses(headTime),
+			LockedAddresses:       dist.LockedAddresses(headTime),
 		}
 
 		wh.SendJSONOr500(logger, w, cs)
@@ -240,3 +243,36 @@ func addressCountHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, &map[string]uint64{"count": addrCount})
 	}
 }
+
+// distributionHandler returns the distribution address unlock schedule and which addresses
+// are unlocked as of the head block. Outputs of locked addresses can't be spent.
+// Unlocks take effect once a block at or after the unlock time is created
+// Method: GET
+// URI: /api/v2/distribution
+func distributionHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		headTime, err := gateway.GetHeadBlockTime()
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		d, err := readable.NewDistribution(gateway.VisorConfig().Distribution, headTime)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: d,
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"../../src/cipher"
 	"../../src/coin"
 	"../../src/daemon"
+	"../../src/daemon/gnet"
+	"../../src/daemon/pex"
 	"../../src/kvstorage"
 	"../../src/transaction"
 	"../../src/visor"
@@ -46,13 +48,24 @@ type Daemoner interface {
 	DaemonConfig() daemon.DaemonConfig
 	GetConnection(addr string) (*daemon.Connection, error)
 	GetConnections(f func(c daemon.Connection) bool) ([]daemon.Connection, error)
+	GetNetworkStats() (gnet.ConnectionStats, error)
 	DisconnectByGnetID(gnetID uint64) error
 	GetDefaultConnections() []string
 	GetTrustConnections() []string
 	GetExchgConnection() []string
+	GetPeers() []pex.Peer
+	SetPeer(addr string, trusted, private bool) (*pex.Peer, error)
+	RemovePeer(addr string) error
+	ConnectToPeer(addr string) error
 	GetBlockchainProgress(headSeq uint64) *daemon.BlockchainProgress
+	GetSyncState() daemon.SyncState
+	GetPublisherRole() daemon.PublisherRole
 	InjectBroadcastTransaction(txn coin.Transaction) error
 	InjectTransaction(txn coin.Transaction) error
+	InjectPublisherKeyChange(kc visor.PublisherKeyChange) error
+	CreateBlockTemplate() (*coin.Block, error)
+	SubmitBlock(hash cipher.SHA256, sig cipher.Sig, when uint64, txids []cipher.SHA256) (*coin.SignedBlock, error)
+	MineBlocks(n int, advance time.Duration) ([]coin.SignedBlock, error)
 }
 
 // Visorer interface for visor.Visor methods used by the API
@@ -60,7 +73,9 @@ type Visorer interface {
 	VisorConfig() visor.Config
 	StartedAt() time.Time
 	HeadBkSeq() (uint64, bool, error)
+	GetHeadBlockTime() (uint64, error)
 	GetBlockchainMetadata() (*visor.BlockchainMetadata, error)
+	GetPublisherKeyChanges() []visor.PublisherKeyChange
 	ResendUnconfirmedTxns() ([]cipher.SHA256, error)
 	GetSignedBlockByHash(hash cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockByHashVerbose(hash cipher.SHA256) (*coin.SignedBlock, [][]visor.TransactionInput, error)
@@ -20,6 +20,7 @@ type BlockchainMetadata struct {
 // HealthResponse is returned by the /health endpoint
 type HealthResponse struct {
 	BlockchainMetadata   BlockchainMetadata   `json:"blockchain"`
+	SyncState            daemon.SyncState     `json:"sync_state"`
 	Version              readable.BuildInfo   `json:"version"`
 	CoinName             string               `json:"coin"`
 	DaemonUserAgent      string               `json:"user_agent"`
@@ -33,6 +34,7 @@ type HealthResponse struct {
 	WalletAPIEnabled     bool                 `json:"wallet_api_enabled"`
 	GUIEnabled           bool                 `json:"gui_enabled"`
 	BlockPublisher       bool                 `json:"block_publisher"`
+	PublisherRole        daemon.PublisherRole `json:"publisher_role"`
 	UserVerifyTxn        readable.VerifyTxn   `json:"user_verify_transaction"`
 	UnconfirmedVerifyTxn readable.VerifyTxn   `json:"unconfirmed_verify_transaction"`
 	StartedAt            int64                `json:"started_at"`
@@ -77,6 +79,7 @@ func getHealthData(c muxConfig, gateway Gatewayer) (*HealthResponse, error) {
 			BlockchainMetadata: readable.NewBlockchainMetadata(*metadata),
 			TimeSinceLastBlock: wh.FromDuration(timeSinceLastBlock),
 		},
+		SyncState:            gateway.GetSyncState(),
 		Version:              c.health.BuildInfo,
 		CoinName:             c.health.Fiber.Name,
 		Fiber:                c.health.Fiber,
@@ -89,6 +92,7 @@ func getHealthData(c muxConfig, gateway Gatewayer) (*HealthResponse, error) {
 		CSPEnabled:           !c.disableCSP,
 		GUIEnabled:           c.enableGUI,
 		BlockPublisher:       c.health.BlockPublisher,
+		PublisherRole:        gateway.GetPublisherRole(),
 		WalletAPIEnabled:     walletAPIEnabled,
 		UserVerifyTxn:        readable.NewVerifyTxn(params.UserVerifyTxn),
 		UnconfirmedVerifyTxn: readable.NewVerifyTxn(gateway.DaemonConfig().UnconfirmedVerifyTxn),
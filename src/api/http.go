@@ -58,6 +58,10 @@ const (
 	EndpointsNetCtrl = "NET_CTRL"
 	// EndpointsStorage endpoints implement interface for key-value storage for arbitrary data
 	EndpointsStorage = "STORAGE"
+	// EndpointsBlockPublisher endpoints create blocks signed outside of the node
+	EndpointsBlockPublisher = "BLOCK_PUBLISHER"
+	// EndpointsRegtest endpoints control a regtest chain. Only enabled by -network=regtest
+	EndpointsRegtest = "REGTEST"
 )
 
 // Server exposes an HTTP API
@@ -82,6 +86,8 @@ type Config struct {
 	EnabledAPISets     map[string]struct{}
 	Username           string
 	Password           string
+	// EffectiveConfig are the daemon options exposed in /api/v2/config
+	EffectiveConfig []readable.ConfigOption
 }
 
 // HealthConfig configuration data exposed in /health
@@ -104,6 +110,7 @@ type muxConfig struct {
 	username           string
 	password           string
 	health             HealthConfig
+	effectiveConfig    []readable.ConfigOption
 }
 
 // HTTPResponse represents the http response struct
@@ -198,6 +205,7 @@ func create(host string, c Config, gateway Gatewayer) (*Server, error) {
 		hostWhitelist:      c.HostWhitelist,
 		username:           c.Username,
 		password:           c.Password,
+		effectiveConfig:    c.EffectiveConfig,
 	}
 
 	srvMux := newServerMux(mc, gateway)
@@ -456,6 +464,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/health", healthHandler(c, gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead, EndpointsStatus},
 	})
+	webHandlerV2("/config", configHandler(c.effectiveConfig), map[string][]string{
+		http.MethodGet: []string{EndpointsStatus},
+	})
 
 	// Wallet endpoints
 	webHandlerV1("/wallet", walletHandler(gateway), map[string][]string{
@@ -518,9 +529,22 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/blockchain/progress", blockchainProgressHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead, EndpointsStatus},
 	})
+	webHandlerV2("/blockchain/publisher_keys", publisherKeysHandler(gateway), map[string][]string{
+		http.MethodGet:  []string{EndpointsRead, EndpointsStatus},
+		http.MethodPost: []string{EndpointsTransaction},
+	})
 	webHandlerV1("/block", blockHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
+	webHandlerV2("/block/template", blockTemplateHandler(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsBlockPublisher},
+	})
+	webHandlerV2("/block/submit", blockSubmitHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsBlockPublisher},
+	})
+	webHandlerV2("/regtest/mine", regtestMineHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsRegtest},
+	})
 	webHandlerV1("/blocks", blocksHandler(gateway), map[string][]string{
 		http.MethodGet:  []string{EndpointsRead},
 		http.MethodPost: []string{EndpointsRead},
@@ -550,6 +574,14 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/network/connection/disconnect", disconnectHandler(gateway), map[string][]string{
 		http.MethodPost: []string{EndpointsNetCtrl},
 	})
+	webHandlerV2("/network/peers", networkPeersHandler(gateway), map[string][]string{
+		http.MethodGet:    []string{EndpointsRead, EndpointsStatus},
+		http.MethodPost:   []string{EndpointsNetCtrl},
+		http.MethodDelete: []string{EndpointsNetCtrl},
+	})
+	webHandlerV2("/network/connect", networkConnectHandler(gateway), map[string][]string{
+		http.MethodPost: []string{EndpointsNetCtrl},
+	})
 
 	// Transaction related endpoints
 	webHandlerV1("/pendingTxs", pendingTxnsHandler(gateway), map[string][]string{
@@ -609,6 +641,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/coinSupply", coinSupplyHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
+	webHandlerV2("/distribution", distributionHandler(gateway), map[string][]string{
+		http.MethodGet: []string{EndpointsRead},
+	})
 	webHandlerV1("/richlist", richlistHandler(gateway), map[string][]string{
 		http.MethodGet: []string{EndpointsRead},
 	})
@@ -50,6 +50,26 @@ var (
 			Name: "last_block_seq",
 			Help: "Last block sequence number",
 		})
+	promBytesSent = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "network_bytes_sent",
+			Help: "Number of bytes sent to peers",
+		})
+	promBytesReceived = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "network_bytes_received",
+			Help: "Number of bytes received from peers",
+		})
+	promMessages = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "network_messages",
+			Help: "Number of messages sent to and received from peers, by direction and message type",
+		}, []string{"direction", "type"})
+	promMessageBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "network_message_bytes",
+			Help: "Number of bytes of messages sent to and received from peers, by direction and message type",
+		}, []string{"direction", "type"})
 )
 
 func init() {
@@ -61,6 +81,10 @@ func init() {
 	prometheus.MustRegister(promIncomingConns)
 	prometheus.MustRegister(promStartedAt)
 	prometheus.MustRegister(promLastBlockSeq)
+	prometheus.MustRegister(promBytesSent)
+	prometheus.MustRegister(promBytesReceived)
+	prometheus.MustRegister(promMessages)
+	prometheus.MustRegister(promMessageBytes)
 }
 
 func metricsHandler(c muxConfig, gateway Gatewayer) http.HandlerFunc {
@@ -71,6 +95,12 @@ func metricsHandler(c muxConfig, gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		stats, err := gateway.GetNetworkStats()
+		if err != nil {
+			wh.Error500(w, err.Error())
+			return
+		}
+
 		promUnspents.Set(float64(health.BlockchainMetadata.Unspents))
 		promUnconfirmedTxns.Set(float64(health.BlockchainMetadata.Unconfirmed))
 		promTimeSinceLastBlock.Set(health.BlockchainMetadata.TimeSinceLastBlock.Seconds())
@@ -79,6 +109,16 @@ func metricsHandler(c muxConfig, gateway Gatewayer) http.HandlerFunc {
 		promIncomingConns.Set(float64(health.IncomingConnections))
 		promStartedAt.Set(float64(gateway.StartedAt().Unix()))
 		promLastBlockSeq.Set(float64(health.BlockchainMetadata.Head.BkSeq))
+		promBytesSent.Set(float64(stats.BytesSent))
+		promBytesReceived.Set(float64(stats.BytesReceived))
+		for msgType, ms := range stats.SentByType {
+			promMessages.WithLabelValues("sent", msgType).Set(float64(ms.Count))
+			promMessageBytes.WithLabelValues("sent", msgType).Set(float64(ms.Bytes))
+		}
+		for msgType, ms := range stats.ReceivedByType {
+			promMessages.WithLabelValues("received", msgType).Set(float64(ms.Count))
+			promMessageBytes.WithLabelValues("received", msgType).Set(float64(ms.Bytes))
+		}
 
 		promhttp.Handler().ServeHTTP(w, r)
 	}
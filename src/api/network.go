@@ -3,6 +3,7 @@ package api
 // APIs for network-related information
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sort"
@@ -10,6 +11,7 @@ import (
 	"strings"
 
 	"../../src/daemon"
+	"../../src/daemon/pex"
 	"../../src/readable"
 	wh "../../src/util/http"
 )
@@ -226,3 +228,166 @@ func disconnectHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, struct{}{})
 	}
 }
+
+// Dispatches /network/peers endpoint.
+// Method: GET, POST, DELETE
+// URI: /api/v2/network/peers
+func networkPeersHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getPeersHandler(w, gateway)
+		case http.MethodPost:
+			setPeerHandler(w, r, gateway)
+		case http.MethodDelete:
+			removePeerHandler(w, r, gateway)
+		default:
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+		}
+	}
+}
+
+// Returns all peers in the peer list, sorted by address
+func getPeersHandler(w http.ResponseWriter, gateway Gatewayer) {
+	peers := gateway.GetPeers()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Addr < peers[j].Addr
+	})
+
+	rPeers := make([]readable.Peer, len(peers))
+	for i, p := range peers {
+		rPeers[i] = readable.NewPeer(p)
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: rPeers,
+	})
+}
+
+// PeerRequest is the request data for POST /api/v2/network/peers
+type PeerRequest struct {
+	Address string `json:"address"`
+	Trusted bool   `json:"trusted"`
+	Private bool   `json:"private"`
+}
+
+// Adds a peer to the peer list, or updates the flags of a peer already in it
+// Args:
+//     address: address of the peer
+//     trusted: [optional] mark the peer as trusted
+//     private: [optional] mark the peer as private
+func setPeerHandler(w http.ResponseWriter, r *http.Request, gateway Gatewayer) {
+	var req PeerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	if req.Address == "" {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	p, err := gateway.SetPeer(req.Address, req.Trusted, req.Private)
+	if err != nil {
+		writeHTTPResponse(w, newPeerErrorResponse(err))
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: readable.NewPeer(*p),
+	})
+}
+
+// Removes a peer from the peer list
+// Args:
+//     address: address of the peer
+func removePeerHandler(w http.ResponseWriter, r *http.Request, gateway Gatewayer) {
+	addr := r.FormValue("address")
+	if addr == "" {
+		resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	if err := gateway.RemovePeer(addr); err != nil {
+		writeHTTPResponse(w, newPeerErrorResponse(err))
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{})
+}
+
+// newPeerErrorResponse maps an error from the peer list to an error response
+func newPeerErrorResponse(err error) HTTPResponse {
+	switch err {
+	case pex.ErrInvalidAddress,
+		pex.ErrNoLocalhost,
+		pex.ErrNotExternalIP,
+		pex.ErrPortTooLow:
+		return NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+	case pex.ErrPeerNotFound:
+		return NewHTTPErrorResponse(http.StatusNotFound, "")
+	case pex.ErrPeerlistFull:
+		return NewHTTPErrorResponse(http.StatusServiceUnavailable, err.Error())
+	default:
+		return NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+}
+
+// ConnectRequest is the request data for POST /api/v2/network/connect
+type ConnectRequest struct {
+	Address string `json:"address"`
+}
+
+// networkConnectHandler makes an outgoing connection to a peer right away.
+// The peer is added to the peer list if it is not in it already.
+// The connection is made in the background, use /api/v1/network/connection to check its state
+// URI: /api/v2/network/connect
+// Method: POST
+// Args:
+//     address: address of the peer
+func networkConnectHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req ConnectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Address == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "address is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := gateway.ConnectToPeer(req.Address); err != nil {
+			var resp HTTPResponse
+			switch err {
+			case daemon.ErrNetworkingDisabled,
+				daemon.ErrOutgoingConnectionsDisabled:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+			case daemon.ErrOutgoingConnectionsFull:
+				resp = NewHTTPErrorResponse(http.StatusServiceUnavailable, err.Error())
+			default:
+				// The address is invalid, or the daemon refused to make the connection,
+				// e.g. because it is already connected to a peer with the same base IP
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{})
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"../../src/daemon"
+	"../../src/readable"
+)
+
+// maxMineBlocks is the maximum number of blocks mined by one request to /api/v2/regtest/mine
+const maxMineBlocks = 1000
+
+// regtestMineHandler creates and executes blocks on a regtest chain
+// Method: POST
+// URI: /api/v2/regtest/mine
+// Args:
+//     n: number of blocks to mine [optional, default 1, max 1000]
+//     advance: duration to move the block time forward by before mining, e.g. "24h",
+//         so that coin hours accrue [optional]
+// Response:
+//     200 - the mined blocks
+//     400 - invalid request
+//     403 - the node is not running a regtest chain
+//     500 - mining failed. The blocks mined before the failure are returned
+func regtestMineHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		n := 1
+		if s := r.FormValue("n"); s != "" {
+			var err error
+			n, err = strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxMineBlocks {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid n value %q, must be between 1 and %d", s, maxMineBlocks))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		var advance time.Duration
+		if s := r.FormValue("advance"); s != "" {
+			var err error
+			advance, err = time.ParseDuration(s)
+			if err != nil || advance < 0 {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid advance value %q", s))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		blocks, mineErr := gateway.MineBlocks(n, advance)
+		if mineErr == daemon.ErrNotRegtest {
+			resp := NewHTTPErrorResponse(http.StatusForbidden, mineErr.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		rb, err := readable.NewBlocks(blocks)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if mineErr != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, mineErr.Error())
+			resp.Data = rb
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rb,
+		})
+	}
+}
@@ -22,6 +22,9 @@ import (
 	"fmt"
 	"hash"
 	"log"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"../../src/cipher/ripemd160"
@@ -434,6 +437,48 @@ func VerifyAddressSignedHash(address Address, sig Sig, hash SHA256) error {
 	return nil
 }
 
+// VerifyAddressSignedHashes checks each hash with VerifyAddressSignedHash,
+// using up to runtime.NumCPU() goroutines.
+// Returns the error for each hash, in the same order as the hashes
+func VerifyAddressSignedHashes(addresses []Address, sigs []Sig, hashes []SHA256) []error {
+	if len(addresses) != len(hashes) || len(sigs) != len(hashes) {
+		log.Panic("VerifyAddressSignedHashes: addresses, sigs and hashes must have the same length")
+	}
+
+	errs := make([]error, len(hashes))
+
+	workers := runtime.NumCPU()
+	if workers > len(hashes) {
+		workers = len(hashes)
+	}
+
+	if workers <= 1 {
+		for i := range hashes {
+			errs[i] = VerifyAddressSignedHash(addresses[i], sigs[i], hashes[i])
+		}
+		return errs
+	}
+
+	next := int64(-1)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= len(hashes) {
+					return
+				}
+				errs[i] = VerifyAddressSignedHash(addresses[i], sigs[i], hashes[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
 // VerifyPubKeySignedHash verifies that hash was signed by PubKey
 func VerifyPubKeySignedHash(pubkey PubKey, sig Sig, hash SHA256) error {
 	pubkeyRec, err := PubKeyFromSig(sig, hash) // recovered pubkey
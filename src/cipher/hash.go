@@ -206,3 +206,37 @@ func Merkle(h0 []SHA256) SHA256 {
 	}
 	return h1[0]
 }
+
+// MerkleBranch returns the sibling hashes needed to prove that h0[i] is included in Merkle(h0),
+// ordered from the leaf up to the root
+func MerkleBranch(h0 []SHA256, i int) []SHA256 {
+	lh := uint64(len(h0))
+	np := nextPowerOfTwo(lh)
+	h1 := make([]SHA256, np)
+	copy(h1, h0)
+
+	var branch []SHA256
+	for len(h1) != 1 {
+		branch = append(branch, h1[i^1])
+		h2 := make([]SHA256, len(h1)/2)
+		for j := 0; j < len(h2); j++ {
+			h2[j] = AddSHA256(h1[2*j], h1[2*j+1])
+		}
+		h1 = h2
+		i /= 2
+	}
+	return branch
+}
+
+// VerifyMerkleBranch returns true if branch proves that h is the i-th hash of a merkle tree with the given root
+func VerifyMerkleBranch(h SHA256, i int, branch []SHA256, root SHA256) bool {
+	for _, b := range branch {
+		if i%2 == 0 {
+			h = AddSHA256(h, b)
+		} else {
+			h = AddSHA256(b, h)
+		}
+		i /= 2
+	}
+	return i == 0 && h == root
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"../../src/cipher"
+	"../../src/params"
 	"../../src/util/apputil"
 	"../../src/visor"
 	"../../src/visor/dbutil"
@@ -75,7 +76,7 @@ func checkDB(_ *cobra.Command, args []string) error {
 		apputil.CatchInterrupt(quitChan)
 	}()
 
-	if err := visor.CheckDatabase(wrapDB(db), pubkey, quitChan); err != nil {
+	if err := visor.CheckDatabase(wrapDB(db), pubkey, nil, params.MainNetPublisherKeyChanges, quitChan); err != nil {
 		if err == visor.ErrVerifyStopped {
 			return nil
 		}
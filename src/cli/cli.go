@@ -212,6 +212,15 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		pendingTransactionsCmd(),
 		addresscountCmd(),
 		distributeGenesisCmd(),
+		listPeersCmd(),
+		addPeerCmd(),
+		removePeerCmd(),
+		connectPeerCmd(),
+		publisherKeysCmd(),
+		changePublisherKeyCmd(),
+		signBlockCmd(),
+		createKeystoreCmd(),
+		reencryptKeystoreCmd(),
 	}
 
 	laqCLI.Version = Version
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"../../src/cipher"
+	"../../src/util/file"
+	"../../src/wallet"
+)
+
+func createKeystoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "Create an encrypted keystore for a blockchain secret key",
+		Use:   "createKeystore [keystore file]",
+		Long: `Create a keystore file that holds a blockchain secret key encrypted with a password,
+    for a block publisher started with -blockchain-keystore. A new key pair is generated,
+    unless a file with a hex-encoded secret key is given with "-k". The public key is printed.
+
+    Use caution when using the "-p" command. If you have command history enabled
+    your keystore password can be recovered from the history log. If you
+    do not include the "-p" option you will be prompted to enter your password
+    after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			keystoreFile := args[0]
+
+			cryptoType, err := wallet.CryptoTypeFromString(c.Flag("crypto-type").Value.String())
+			if err != nil {
+				printHelp(c)
+				return err
+			}
+
+			exists, err := file.Exists(keystoreFile)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("keystore file %s already exists", keystoreFile)
+			}
+
+			var seckey cipher.SecKey
+			if seckeyFile := c.Flag("secret-key-file").Value.String(); seckeyFile != "" {
+				b, err := ioutil.ReadFile(seckeyFile)
+				if err != nil {
+					return fmt.Errorf("read secret key file failed: %v", err)
+				}
+
+				seckey, err = cipher.SecKeyFromHex(strings.TrimSpace(string(b)))
+				if err != nil {
+					return fmt.Errorf("invalid secret key: %v", err)
+				}
+			} else {
+				_, seckey = cipher.GenerateKeyPair()
+			}
+
+			password, err := readKeystorePassword("enter keystore password:", c.Flag("password").Value.String())
+			if err != nil {
+				return err
+			}
+
+			k, err := wallet.NewKeystore(seckey, password, cryptoType)
+			if err != nil {
+				return err
+			}
+
+			if err := k.Save(keystoreFile); err != nil {
+				return err
+			}
+
+			return printJSON(struct {
+				Pubkey string `json:"pubkey"`
+			}{
+				Pubkey: k.Pubkey,
+			})
+		},
+	}
+
+	cmd.Flags().StringP("secret-key-file", "k", "", "File with the hex-encoded secret key to encrypt. A new key pair is generated if not set")
+	cmd.Flags().StringP("password", "p", "", "keystore password")
+	cmd.Flags().StringP("crypto-type", "x", string(wallet.DefaultCryptoType), "The crypto type for keystore encryption, can be scrypt-chacha20poly1305 or sha256-xor")
+	return cmd
+}
+
+func reencryptKeystoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "Encrypt a keystore with a new password or crypto type",
+		Use:   "reencryptKeystore [keystore file]",
+		Long: `Decrypt a keystore file created by createKeystore with its password, and encrypt it
+    again with a new password and crypto type. The keystore file is replaced.
+
+    Use caution when using the "-p" and "-n" commands. If you have command history enabled
+    your keystore passwords can be recovered from the history log. If you do not include
+    these options you will be prompted to enter the passwords after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			keystoreFile := args[0]
+
+			cryptoType, err := wallet.CryptoTypeFromString(c.Flag("crypto-type").Value.String())
+			if err != nil {
+				printHelp(c)
+				return err
+			}
+
+			k, err := wallet.LoadKeystore(keystoreFile)
+			if err != nil {
+				return err
+			}
+
+			password, err := readKeystorePassword("enter current keystore password:", c.Flag("password").Value.String())
+			if err != nil {
+				return err
+			}
+
+			newPassword, err := readKeystorePassword("enter new keystore password:", c.Flag("new-password").Value.String())
+			if err != nil {
+				return err
+			}
+
+			if err := k.Reencrypt(password, newPassword, cryptoType); err != nil {
+				return err
+			}
+
+			return k.Save(keystoreFile)
+		},
+	}
+
+	cmd.Flags().StringP("password", "p", "", "current keystore password")
+	cmd.Flags().StringP("new-password", "n", "", "new keystore password")
+	cmd.Flags().StringP("crypto-type", "x", string(wallet.DefaultCryptoType), "The crypto type for keystore encryption, can be scrypt-chacha20poly1305 or sha256-xor")
+	return cmd
+}
+
+// readKeystorePassword returns p if it is not empty, otherwise prompts for the password
+func readKeystorePassword(prompt, p string) ([]byte, error) {
+	if p != "" {
+		return []byte(p), nil
+	}
+
+	fmt.Fprint(os.Stdout, prompt)
+	bp, err := terminal.ReadPassword(int(syscall.Stdin)) //nolint:unconvert
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(os.Stdout, "")
+
+	if len(bp) == 0 {
+		return nil, errors.New("missing password")
+	}
+
+	return bp, nil
+}
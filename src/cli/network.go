@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func listPeersCmd() *cobra.Command {
+	return &cobra.Command{
+		Short:                 "List the peers known to the node",
+		Use:                   "listPeers",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			peers, err := apiClient.NetworkPeers()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(peers)
+		},
+	}
+}
+
+func addPeerCmd() *cobra.Command {
+	addPeerCmd := &cobra.Command{
+		Short: "Add a peer to the node's peer list",
+		Use:   "addPeer [address]",
+		Long: `Add a peer to the node's peer list, or update the flags of a peer that is already in it.
+    The address must be in ip:port form, IPv6 addresses must be enclosed in brackets.
+
+    Trusted peers are always connected to, unless trusted peers are disabled in the node.
+    Private peers are always connected to, and their address is not shared with other peers.
+    The flags of a peer that is already in the peer list are replaced, so omitting a flag unsets it.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			trusted, err := c.Flags().GetBool("trusted")
+			if err != nil {
+				return err
+			}
+
+			private, err := c.Flags().GetBool("private")
+			if err != nil {
+				return err
+			}
+
+			p, err := apiClient.SetNetworkPeer(args[0], trusted, private)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(p)
+		},
+	}
+
+	addPeerCmd.Flags().BoolP("trusted", "t", false, "Mark the peer as trusted")
+	addPeerCmd.Flags().BoolP("private", "p", false, "Mark the peer as private")
+
+	return addPeerCmd
+}
+
+func removePeerCmd() *cobra.Command {
+	return &cobra.Command{
+		Short:                 "Remove a peer from the node's peer list",
+		Long:                  "Remove a peer from the node's peer list. The connection to the peer, if any, is not closed.",
+		Use:                   "removePeer [address]",
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := apiClient.RemoveNetworkPeer(args[0]); err != nil {
+				return err
+			}
+
+			fmt.Println("success")
+			return nil
+		},
+	}
+}
+
+func connectPeerCmd() *cobra.Command {
+	return &cobra.Command{
+		Short: "Make the node connect to a peer",
+		Long: `Make the node connect to a peer right away. The peer is added to the node's peer list.
+    The connection is made in the background, its state is shown by the node's
+    /api/v1/network/connection endpoint.`,
+		Use:                   "connectPeer [address]",
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := apiClient.NetworkConnect(args[0]); err != nil {
+				return err
+			}
+
+			fmt.Println("success")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"../../src/cipher"
+	"../../src/readable"
+	"../../src/visor"
+)
+
+func publisherKeysCmd() *cobra.Command {
+	return &cobra.Command{
+		Short:                 "List the block publisher key changes known to the node",
+		Use:                   "publisherKeys",
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			pk, err := apiClient.PublisherKeys()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(pk)
+		},
+	}
+}
+
+func changePublisherKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Short: "Hand off block publishing to a new public key",
+		Use:   "changePublisherKey [seq] [new publisher public key] [current publisher secret key]",
+		Long: `Hand off block publishing to a new public key, from the block at seq.
+    The change is signed by the secret key of the block publisher that signs the block before seq,
+    and sent to the node, which relays it to its peers. Seq must be above the node's head block.
+
+    Blocks at and above seq must be signed by the new key. Run a block publisher with the new
+    secret key before the blockchain reaches seq, or no more blocks will be created.
+
+    Use --offline to print the signed change without sending it. It can be sent later
+    to the node's /api/v2/blockchain/publisher_keys endpoint.`,
+		Args:         cobra.ExactArgs(3),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			offline, err := c.Flags().GetBool("offline")
+			if err != nil {
+				return err
+			}
+
+			seq, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid seq: %v", err)
+			}
+
+			pubkey, err := cipher.PubKeyFromHex(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid public key: %v", err)
+			}
+
+			seckey, err := cipher.SecKeyFromHex(args[2])
+			if err != nil {
+				return fmt.Errorf("invalid secret key: %v", err)
+			}
+
+			kc, err := visor.NewPublisherKeyChange(seq, pubkey, seckey)
+			if err != nil {
+				return err
+			}
+
+			rkc := readable.NewPublisherKeyChange(kc)
+			if offline {
+				return printJSON(rkc)
+			}
+
+			added, err := apiClient.AddPublisherKeyChange(rkc)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(added)
+		},
+	}
+
+	cmd.Flags().BoolP("offline", "o", false, "Print the signed change instead of sending it to the node")
+
+	return cmd
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"../../src/cipher"
+	"../../src/visor"
+)
+
+func signBlockCmd() *cobra.Command {
+	return &cobra.Command{
+		Short: "Sign a block header read from stdin with a block publisher secret key",
+		Use:   "signBlock [secret key file]",
+		Long: `Sign a block for a block publisher that does not hold its secret key.
+    The sign request is read as JSON from stdin. Its serialized block header is
+    decoded and hashed, and the hex-encoded signature of the hash is printed.
+    Requests without a block header, or whose fields do not match it, are refused,
+    so that only block headers are signed. The secret key file contains the
+    hex-encoded secret key.
+
+    This command is run by a block publisher started with -block-signer-command,
+    e.g. -block-signer-command "ssh signer@signing-host laqpay-cli signBlock /path/to/key".
+    It does not connect to a node.`,
+		Args:                  cobra.ExactArgs(1),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			b, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("read secret key file failed: %v", err)
+			}
+
+			seckey, err := cipher.SecKeyFromHex(strings.TrimSpace(string(b)))
+			if err != nil {
+				return fmt.Errorf("invalid secret key: %v", err)
+			}
+
+			var req visor.BlockSignRequest
+			if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+				return fmt.Errorf("invalid sign request: %v", err)
+			}
+
+			hash, err := req.Verify()
+			if err != nil {
+				return fmt.Errorf("invalid sign request: %v", err)
+			}
+
+			sig, err := cipher.SignHash(hash, seckey)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(sig.Hex())
+			return nil
+		},
+	}
+}
@@ -41,12 +41,37 @@ func statusCmd() *cobra.Command {
 }
 
 func showConfigCmd() *cobra.Command {
-	return &cobra.Command{
-		Use:                   "showConfig",
-		Short:                 "Show cli configuration",
-		DisableFlagsInUseLine: true,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return printJSON(cliConfig)
+	showConfigCmd := &cobra.Command{
+		Use:   "showConfig",
+		Short: "Show cli configuration",
+		Long: `Show the cli configuration.
+
+    Use --effective to show the options of the node instead, with where each
+    was set: "flag", "env", "file", "default" or "derived", if the node changed
+    it because of other options. Secret values are redacted.
+    The node must have the STATUS API set enabled.`,
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(c *cobra.Command, _ []string) error {
+			effective, err := c.Flags().GetBool("effective")
+			if err != nil {
+				return err
+			}
+
+			if !effective {
+				return printJSON(cliConfig)
+			}
+
+			options, err := apiClient.NodeConfig()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(options)
 		},
 	}
+
+	showConfigCmd.Flags().Bool("effective", false, "Show the effective options of the node and where each was set")
+
+	return showConfigCmd
 }
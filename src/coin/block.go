@@ -61,11 +61,8 @@ func (b SignedBlock) VerifySignature(pubkey cipher.PubKey) error {
 }
 
 // NewBlock creates new block.
+// A block may have no transactions, the blockchain decides whether it accepts empty blocks.
 func NewBlock(prev Block, currentTime uint64, uxHash cipher.SHA256, txns Transactions, calc FeeCalculator) (*Block, error) {
-	if len(txns) == 0 {
-		return nil, fmt.Errorf("Refusing to create block with no transactions")
-	}
-
 	fee, err := txns.Fees(calc)
 	if err != nil {
 		// This should have been caught earlier
@@ -158,6 +155,15 @@ func (bh *BlockHeader) Bytes() []byte {
 	return buf
 }
 
+// DeserializeBlockHeader deserializes a block header serialized by BlockHeader.Bytes
+func DeserializeBlockHeader(b []byte) (BlockHeader, error) {
+	bh := BlockHeader{}
+	if err := decodeBlockHeaderExact(b, &bh); err != nil {
+		return BlockHeader{}, fmt.Errorf("Invalid block header: %v", err)
+	}
+	return bh, nil
+}
+
 // Hash returns the merkle hash of contained transactions
 func (bb BlockBody) Hash() cipher.SHA256 {
 	hashes := make([]cipher.SHA256, len(bb.Transactions))
@@ -75,7 +75,7 @@ type TransactionOutput struct {
 // Verify cannot check if the transaction would create or destroy coins
 // or if the inputs have the required coin base
 func (txn *Transaction) Verify() error {
-	return txn.verify(true)
+	return txn.verify(true, true)
 }
 
 // VerifyUnsigned attempts to determine if the transaction is well formed,
@@ -85,10 +85,19 @@ func (txn *Transaction) Verify() error {
 // Verify cannot check if the transaction would create or destroy coins
 // or if the inputs have the required coin base
 func (txn *Transaction) VerifyUnsigned() error {
-	return txn.verify(false)
+	return txn.verify(false, true)
 }
 
-func (txn *Transaction) verify(signed bool) error {
+// VerifyAssumeSigned attempts to determine if the transaction is well formed, like Verify,
+// but does not check that its signatures can be recovered.
+// It requires the transaction to have no null signatures.
+// It is used for transactions in blocks committed to by a checkpoint,
+// whose signatures are assumed to be valid
+func (txn *Transaction) VerifyAssumeSigned() error {
+	return txn.verify(true, false)
+}
+
+func (txn *Transaction) verify(signed, checkSigs bool) error {
 	if len(txn.In) == 0 {
 		return errors.New("No inputs")
 	}
@@ -186,6 +195,10 @@ func (txn *Transaction) verify(signed bool) error {
 			continue
 		}
 
+		if !checkSigs {
+			continue
+		}
+
 		hash := cipher.AddSHA256(txn.InnerHash, txn.In[i])
 		if err := cipher.VerifySignatureRecoverPubKey(sig, hash); err != nil {
 			return err
@@ -230,14 +243,69 @@ func (txn Transaction) VerifyInputSignatures(uxIn UxArray) error {
 	}
 
 	// Check signatures against unspent address
+	addrs, sigs, hashes := txn.inputSignatures(uxIn, nil, nil, nil)
+	return txn.inputSignaturesError(cipher.VerifyAddressSignedHashes(addrs, sigs, hashes))
+}
+
+// VerifyInputSignatures verifies the inputs and signatures of the transactions.
+// uxIns[i] are the outputs spent by txns[i].
+// The signatures of all transactions are verified concurrently.
+// Returns the error of txns[i].VerifyInputSignatures(uxIns[i]) for each transaction,
+// so the errors do not depend on the order in which the signatures are verified
+func (txns Transactions) VerifyInputSignatures(uxIns []UxArray) []error {
+	if len(txns) != len(uxIns) {
+		log.Panic("Transactions.VerifyInputSignatures: txns and uxIns must have the same length")
+	}
+
+	errs := make([]error, len(txns))
+	offsets := make([]int, len(txns))
+
+	var addrs []cipher.Address
+	var sigs []cipher.Sig
+	var hashes []cipher.SHA256
+	for i, txn := range txns {
+		if err := txn.verifyInputSignaturesPrelude(uxIns[i]); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		offsets[i] = len(hashes)
+		addrs, sigs, hashes = txn.inputSignatures(uxIns[i], addrs, sigs, hashes)
+	}
+
+	sigErrs := cipher.VerifyAddressSignedHashes(addrs, sigs, hashes)
+
+	for i, txn := range txns {
+		if errs[i] != nil {
+			continue
+		}
+
+		errs[i] = txn.inputSignaturesError(sigErrs[offsets[i] : offsets[i]+len(txn.In)])
+	}
+
+	return errs
+}
+
+// inputSignatures appends the address, signature and signed hash of each input
+func (txn Transaction) inputSignatures(uxIn UxArray, addrs []cipher.Address, sigs []cipher.Sig, hashes []cipher.SHA256) ([]cipher.Address, []cipher.Sig, []cipher.SHA256) {
+	for i := range txn.In {
+		addrs = append(addrs, uxIn[i].Body.Address)
+		sigs = append(sigs, txn.Sigs[i])
+		hashes = append(hashes, cipher.AddSHA256(txn.InnerHash, txn.In[i])) // use inner hash, not outer hash
+	}
+
+	return addrs, sigs, hashes
+}
+
+// inputSignaturesError returns the error of the first input that is unsigned,
+// or whose signature is not valid. sigErrs are the errors of cipher.VerifyAddressSignedHash for each input
+func (txn Transaction) inputSignaturesError(sigErrs []error) error {
 	for i := range txn.In {
 		if txn.Sigs[i].Null() {
 			return errors.New("Unsigned input in transaction")
 		}
 
-		hash := cipher.AddSHA256(txn.InnerHash, txn.In[i]) // use inner hash, not outer hash
-		err := cipher.VerifyAddressSignedHash(uxIn[i].Body.Address, txn.Sigs[i], hash)
-		if err != nil {
+		if sigErrs[i] != nil {
 			return errors.New("Signature not valid for output being spent")
 		}
 	}
@@ -266,16 +266,16 @@ func (self *BlockStat) try_add_hash_and_sig(
 			action_skip = true
 			action_insert = false
 
-			fmt.Printf("WARNING: %p, Detected malicious publish from"+
-				" pubkey=%s for hash=%s sig=%s\n", &info,
+			logger.Warningf("%p, Detected malicious publish from"+
+				" pubkey=%s for hash=%s sig=%s", &info,
 				signer_pubkey.Hex()[:8], hash.Hex()[:8], sig.Hex()[:8])
 		}
 
 		// These bools could have change, see above:
 		if action_insert || action_update {
 			if false {
-				fmt.Printf("Calling %p->ObserveSigAndPubkey(sig=%s,"+
-					" signer_pubkey=%s), hash=%s\n", &info,
+				logger.Debugf("Calling %p->ObserveSigAndPubkey(sig=%s,"+
+					" signer_pubkey=%s), hash=%s", &info,
 					sig.Hex()[:8], signer_pubkey.Hex()[:8], hash.Hex()[:8])
 			}
 			info.ObserveSigAndPubkey(sig, signer_pubkey)
@@ -372,6 +372,21 @@ func (self *BlockStat) GetBestHashPubkeySig() (
 	return best_h, best_p, best_s
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Returns the signers of each hash. A caller that knows which signers
+// take part can count only their signatures, instead of relying on
+// GetBestHashPubkeySig.
+func (self *BlockStat) GetHashSigners() map[cipher.SHA256][]cipher.PubKey {
+	hash2signers := make(map[cipher.SHA256][]cipher.PubKey, len(self.hash2info))
+	for hash, info := range self.hash2info {
+		for pubkey := range info.pubkey2sig {
+			hash2signers[hash] = append(hash2signers[hash], pubkey)
+		}
+	}
+
+	return hash2signers
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 func (self *BlockStat) Print() {
 
@@ -513,12 +528,12 @@ func (self *BlockStatQueue) try_append_to_BlockStatQueue(
 			//
 			//
 			if already_in_blockchain {
-				fmt.Print("DEBUG Already in blockchain. Ignoring block.\n")
+				logger.Debug("Already in blockchain. Ignoring block")
 				action_skip = true
 			} else if l.seqno-blockPtr.Seqno >
 				Cfg_consensus_candidate_max_seqno_gap {
-				fmt.Printf("DEBUG proposed=%d, first=%d, last=%d. Too far"+
-					" behind. Ignoring block.\n",
+				logger.Debugf("proposed=%d, first=%d, last=%d. Too far"+
+					" behind. Ignoring block",
 					blockPtr.Seqno, f.seqno, l.seqno)
 				action_skip = true
 			} else {
@@ -532,8 +547,8 @@ func (self *BlockStatQueue) try_append_to_BlockStatQueue(
 			// length would be too large.
 			if blockPtr.Seqno-f.seqno >
 				Cfg_consensus_candidate_max_seqno_gap {
-				fmt.Printf("DEBUG proposed=%d, first=%d, last=%d. Too far"+
-					" ahead. Ignoring block.\n",
+				logger.Debugf("proposed=%d, first=%d, last=%d. Too far"+
+					" ahead. Ignoring block",
 					blockPtr.Seqno, f.seqno, l.seqno)
 				action_skip = true
 			} else {
@@ -625,3 +640,20 @@ func (self *BlockStatQueue) try_append_to_BlockStatQueue(
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+func (self *BlockStatQueue) discard_up_to(seqno uint64) {
+	var queue PriorityQueue
+	for _, statPtr := range self.queue {
+		if statPtr.seqno > seqno {
+			queue = append(queue, statPtr)
+		}
+	}
+	for i := range queue {
+		queue[i].index = i
+	}
+	heap.Init(&queue)
+	self.queue = queue
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -8,15 +8,18 @@ import (
 	"fmt"
 
 	"../../src/cipher"
+	"../../src/util/logging"
 )
 
+var logger = logging.MustGetLogger("consensus")
+
 ////////////////////////////////////////////////////////////////////////////////
 //
 //
 //
 ////////////////////////////////////////////////////////////////////////////////
 var Cfg_debug_block_duplicate bool = false
-var Cfg_debug_block_out_of_sequence bool = true
+var Cfg_debug_block_out_of_sequence bool = false
 var Cfg_debug_block_accepted bool = false
 var Cfg_debug_HashCandidate bool = false
 
@@ -134,7 +137,7 @@ func (self *BlockchainTail) try_append_to_BlockchainTail(blockPtr *BlockBase) in
 			if Cfg_debug_block_duplicate {
 				// Duplicate hash detected. Silently ignore it. We
 				// expect to have this condition often enough.
-				fmt.Printf("Block is duplicate so ignored.\n")
+				logger.Debug("Block is duplicate so ignored")
 			}
 			return 1 // Duplicate hash
 		}
@@ -144,21 +147,21 @@ func (self *BlockchainTail) try_append_to_BlockchainTail(blockPtr *BlockBase) in
 		prop := blockPtr.Seqno
 		if prop < next { // uint cmp
 			if Cfg_debug_block_out_of_sequence {
-				fmt.Printf("Block's seqno is too low (%d vs %d), block"+
-					" ignored.\n", prop, curr)
+				logger.Debugf("Block's seqno is too low (%d vs %d), block"+
+					" ignored", prop, curr)
 			}
 			return 2 // SeqNo too low
 		} else if prop > next { // uint cmp
 			if Cfg_debug_block_out_of_sequence {
-				fmt.Printf("Block's seqno is too high (%d vs %d), block"+
-					" ignored.\n", prop, curr)
+				logger.Debugf("Block's seqno is too high (%d vs %d), block"+
+					" ignored", prop, curr)
 			}
 			return 3 // SeqNo too high
 		}
 	}
 	self.append_nocheck(blockPtr)
 	if Cfg_debug_block_accepted {
-		fmt.Printf("Block is accepted, len(blockchain)=%d.\n",
+		logger.Debugf("Block is accepted, len(blockchain)=%d",
 			len(self.blockPtr_slice))
 	}
 	return 0 // Inserted
@@ -208,11 +211,11 @@ func (self *HashCandidate) ObserveSigAndPubkey(
 
 	if Cfg_debug_HashCandidate {
 		for k, v := range self.pubkey2sig {
-			fmt.Printf("HashCandidate %p pubkey2sig: pubkey=%s sig=%s\n",
+			logger.Debugf("HashCandidate %p pubkey2sig: pubkey=%s sig=%s",
 				self, k.Hex()[:8], v.Hex()[:8])
 		}
 		for k, _ := range self.sig2none {
-			fmt.Printf("HashCandidate %p sig2none: sig=%s\n", self, k.Hex()[:8])
+			logger.Debugf("HashCandidate %p sig2none: sig=%s", self, k.Hex()[:8])
 		}
 	}
 
@@ -222,8 +225,7 @@ func (self *HashCandidate) ObserveSigAndPubkey(
 	n1 := len(self.pubkey2sig)
 	n2 := len(self.sig2none)
 	if n1 != n2 {
-		fmt.Printf("Inconsistent HashCandidate: n1=%d n2=%d\n", n1, n2)
-		panic("Oops")
+		logger.Panicf("Inconsistent HashCandidate: n1=%d n2=%d", n1, n2)
 	}
 
 }
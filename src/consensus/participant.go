@@ -29,6 +29,14 @@ type ConsensusParticipant struct {
 	// Candidates Blocks.
 	block_stat_queue BlockStatQueue
 
+	// BlockStat entries with a seqno at or below this are ripe, see
+	// SetRipeSeqNo.
+	ripe_seqno uint64
+
+	// Blocks selected by harvest_ripe_BlockStat that were not yet
+	// returned by PopSelectedBlocks.
+	selected_blocks []*BlockBase
+
 	Incoming_block_count int
 }
 
@@ -112,6 +120,19 @@ func (self *ConsensusParticipant) Get_block_stat_queue_element_at(
 	return self.block_stat_queue.queue[j] // A pointer, BTW
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Returns the BlockStat of 'seqno', or nil if no header with that
+// seqno was accepted.
+func (self *ConsensusParticipant) GetBlockStat(seqno uint64) *BlockStat {
+	for _, statPtr := range self.block_stat_queue.queue {
+		if statPtr.seqno == seqno {
+			return statPtr
+		}
+	}
+
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 func (self *ConsensusParticipant) OnBlockHeaderArrived(blockPtr *BlockBase) {
 
@@ -140,7 +161,8 @@ func (self *ConsensusParticipant) harvest_ripe_BlockStat() {
 	for i := 0; i < n; i++ {
 		statPtr := self.block_stat_queue.queue[i]
 		if statPtr.seqno+
-			Cfg_consensus_waiting_time_as_seqno_diff <= top_seqno {
+			Cfg_consensus_waiting_time_as_seqno_diff <= top_seqno ||
+			statPtr.seqno <= self.ripe_seqno {
 
 			if !statPtr.frozen {
 				//
@@ -159,6 +181,7 @@ func (self *ConsensusParticipant) harvest_ripe_BlockStat() {
 					// TODO: 'frozen' items should be removed and the 'best'
 					// moved to BlockchainTail.
 					statPtr.frozen = true
+					self.selected_blocks = append(self.selected_blocks, blockPtr)
 				} else {
 					// Appending did not work. Need to examine 'res'
 					// and log the reason why.
@@ -178,3 +201,46 @@ func (self *ConsensusParticipant) harvest_ripe_BlockStat() {
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+
+////////////////////////////////////////////////////////////////////////////////
+// Marks the BlockStat entries with a seqno at or below 'seqno' as ripe
+// and harvests them. When each block refers to the previous one, no
+// block with a higher seqno can be made before the block is selected,
+// so the caller decides when the candidates had enough time to
+// arrive, instead of waiting for
+// Cfg_consensus_waiting_time_as_seqno_diff newer seqnos.
+func (self *ConsensusParticipant) SetRipeSeqNo(seqno uint64) {
+	if seqno > self.ripe_seqno {
+		self.ripe_seqno = seqno
+	}
+	self.harvest_ripe_BlockStat()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Returns the blocks selected by harvest_ripe_BlockStat since the
+// previous call, in the order they were appended to the BlockchainTail.
+func (self *ConsensusParticipant) PopSelectedBlocks() []*BlockBase {
+	blocks := self.selected_blocks
+	self.selected_blocks = nil
+	return blocks
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Restarts the BlockchainTail from a block that was appended to the
+// blockchain by other means, e.g. when the blockchain is synced from
+// peers. The BlockStat entries with a seqno at or below the block's
+// seqno are discarded.
+func (self *ConsensusParticipant) SetBlockchainHead(blockPtr *BlockBase) {
+	self.block_queue = BlockchainTail{}
+	self.block_queue.Init()
+	self.block_queue.append_nocheck(blockPtr)
+
+	self.block_stat_queue.discard_up_to(blockPtr.Seqno)
+
+	if self.ripe_seqno > blockPtr.Seqno {
+		self.ripe_seqno = blockPtr.Seqno
+	}
+	self.selected_blocks = nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"time"
+)
+
+// The block policy decides, at each block creation interval, whether the block publisher creates a block.
+// The default ThresholdBlockPolicy waits for a minimum number of valid unconfirmed transactions or total fee,
+// unless the head block is older than a maximum age, and can create empty heartbeat blocks
+// so that blocks are created at a predictable rate. The selection of the transactions in a block
+// is done by visor.Visor.
+
+// BlockAction is what a BlockPolicy decides the block publisher does
+type BlockAction int
+
+const (
+	// BlockActionNone don't create a block
+	BlockActionNone BlockAction = iota
+	// BlockActionCreate create a block from the valid unconfirmed transactions
+	BlockActionCreate
+	// BlockActionEmpty create a block with no transactions
+	BlockActionEmpty
+)
+
+// BlockPolicyState is what a BlockPolicy decides on
+type BlockPolicyState struct {
+	// Now is the current time
+	Now time.Time
+	// HeadTime is the time of the head block
+	HeadTime time.Time
+	// PendingTxns is the number of valid unconfirmed transactions
+	PendingTxns int
+	// PendingFee is the total fee of the valid unconfirmed transactions, in coin hours
+	PendingFee uint64
+}
+
+// HeadAge returns how long ago the head block was created
+func (s BlockPolicyState) HeadAge() time.Duration {
+	return s.Now.Sub(s.HeadTime)
+}
+
+// BlockPolicy decides when the block publisher creates blocks
+type BlockPolicy interface {
+	// Decide is called at each block creation interval
+	Decide(s BlockPolicyState) BlockAction
+}
+
+// ThresholdBlockPolicy is the BlockPolicy configured by DaemonConfig
+type ThresholdBlockPolicy struct {
+	// MinTransactions is the minimum number of valid unconfirmed transactions to create a block
+	MinTransactions int
+	// MinFee is the minimum total fee of the valid unconfirmed transactions to create a block, in coin hours
+	MinFee uint64
+	// MaxBlockAge is how old the head block may get before a block is created from any valid unconfirmed
+	// transactions, even if MinTransactions or MinFee are not reached. 0 waits for them indefinitely
+	MaxBlockAge time.Duration
+	// EmptyBlockInterval is how old the head block may get before an empty block is created,
+	// if there are no valid unconfirmed transactions. 0 disables empty blocks
+	EmptyBlockInterval time.Duration
+}
+
+// NewThresholdBlockPolicy creates a ThresholdBlockPolicy from the DaemonConfig
+func NewThresholdBlockPolicy(c DaemonConfig) *ThresholdBlockPolicy {
+	return &ThresholdBlockPolicy{
+		MinTransactions:    c.BlockMinTransactions,
+		MinFee:             c.BlockMinFee,
+		MaxBlockAge:        time.Second * time.Duration(c.MaxBlockAge),
+		EmptyBlockInterval: time.Second * time.Duration(c.EmptyBlockInterval),
+	}
+}
+
+// Decide creates a block when the thresholds are reached or the head block is too old
+func (p *ThresholdBlockPolicy) Decide(s BlockPolicyState) BlockAction {
+	if s.PendingTxns == 0 {
+		if p.EmptyBlockInterval > 0 && s.HeadAge() >= p.EmptyBlockInterval {
+			return BlockActionEmpty
+		}
+		return BlockActionNone
+	}
+
+	if s.PendingTxns >= p.MinTransactions && s.PendingFee >= p.MinFee {
+		return BlockActionCreate
+	}
+
+	if p.MaxBlockAge > 0 && s.HeadAge() >= p.MaxBlockAge {
+		return BlockActionCreate
+	}
+
+	return BlockActionNone
+}
+
+// checkBlockPolicy returns what the block policy decides the block publisher does
+func (dm *Daemon) checkBlockPolicy() (BlockAction, error) {
+	metadata, err := dm.visor.GetBlockchainMetadata()
+	if err != nil {
+		return BlockActionNone, err
+	}
+
+	n, fee, err := dm.visor.GetValidUnconfirmedFee()
+	if err != nil {
+		return BlockActionNone, err
+	}
+
+	return dm.blockPolicy.Decide(BlockPolicyState{
+		Now:         time.Now(),
+		HeadTime:    time.Unix(int64(metadata.HeadBlock.Head.Time), 0),
+		PendingTxns: n,
+		PendingFee:  fee,
+	}), nil
+}
@@ -0,0 +1,94 @@
+package daemon
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"../../src/cipher"
+	"../../src/coin"
+	"../../src/visor"
+)
+
+// Block templates let a block publisher key stay on a host that does not run the node.
+// The node creates an unsigned block from its unconfirmed transactions with CreateBlockTemplate,
+// without changing its state. The header hash of the template is signed elsewhere, and the signature is given
+// back to SubmitBlock with the time and the transactions of the template. SubmitBlock recreates the block
+// from them, checks that it has the signed header hash, executes the signed block and broadcasts it.
+
+var (
+	// ErrBlockTemplateChanged is returned by SubmitBlock if the block template can't be recreated with the header hash,
+	// because the head block advanced or a transaction of the template left the unconfirmed pool
+	ErrBlockTemplateChanged = errors.New("Block template can't be recreated, the head block or the unconfirmed transactions changed")
+	// ErrBlockTemplateConsensus is returned by block template operations if the block publishers agree on each block
+	ErrBlockTemplateConsensus = errors.New("Block templates can't be used when the block publishers agree on each block")
+	// ErrBlockTemplateStandby is returned by SubmitBlock if the node is a block publisher that does not hold the lease
+	ErrBlockTemplateStandby = errors.New("Block publisher does not hold the lease, another block publisher creates the blocks")
+)
+
+// CreateBlockTemplate creates an unsigned block from the unconfirmed transactions, for SubmitBlock
+func (dm *Daemon) CreateBlockTemplate() (*coin.Block, error) {
+	if dm.consensus != nil {
+		return nil, ErrBlockTemplateConsensus
+	}
+
+	b, err := dm.visor.CreateUnsignedBlock(uint64(time.Now().UTC().Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &b, nil
+}
+
+// SubmitBlock recreates the block template created at time when from the transactions with the txids,
+// signs it with sig, executes it and broadcasts it. The template must have the header hash.
+// The signature must be by a key that signs the block at its seq.
+// If the node is a block publisher, it must hold the lease, see publisherLease.
+// The block is executed even if networking is disabled, in which case ErrNetworkingDisabled is returned
+func (dm *Daemon) SubmitBlock(hash cipher.SHA256, sig cipher.Sig, when uint64, txids []cipher.SHA256) (*coin.SignedBlock, error) {
+	if dm.consensus != nil {
+		return nil, ErrBlockTemplateConsensus
+	}
+
+	if dm.publisherLease != nil && dm.publisherLease.getRole() != PublisherRoleActive {
+		return nil, ErrBlockTemplateStandby
+	}
+
+	b, err := dm.visor.CreateUnsignedBlockFromTxns(txids, when)
+	if err != nil {
+		if err == visor.ErrUnconfirmedTxnNotFound {
+			return nil, ErrBlockTemplateChanged
+		}
+		return nil, err
+	}
+
+	if b.HashHeader() != hash {
+		return nil, ErrBlockTemplateChanged
+	}
+
+	sb := coin.SignedBlock{
+		Block: b,
+		Sig:   sig,
+	}
+
+	if err := dm.visor.ExecuteSignedBlock(sb); err != nil {
+		return nil, err
+	}
+
+	if dm.publisherLease != nil {
+		dm.publisherLease.setCreated(sb.Block.Head.BkSeq)
+	}
+
+	logger.Critical().WithFields(logrus.Fields{
+		"version": sb.Block.Head.Version,
+		"seq":     sb.Block.Head.BkSeq,
+		"time":    sb.Block.Head.Time,
+	}).Info("Executed a submitted block")
+
+	if dm.config.DisableNetworking {
+		return &sb, ErrNetworkingDisabled
+	}
+
+	return &sb, dm.broadcastBlock(sb)
+}
@@ -0,0 +1,222 @@
+/*
+Package bloom implements the bloom filters that light clients load into a peer's connection,
+so that the peer only relays the transactions that the light client is interested in.
+
+The filter layout and hashing follows BIP37: the filter is a bit array, and each element
+is hashed HashFuncs times with murmur3, seeded by the hash function number and a random tweak.
+*/
+package bloom
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+)
+
+const (
+	// MaxFilterSize is the maximum size of a filter in bytes
+	MaxFilterSize = 36000
+	// MaxHashFuncs is the maximum number of hash functions of a filter
+	MaxHashFuncs = 50
+	// MaxElementSize is the maximum size of an element added with FilterAddMessage
+	MaxElementSize = 520
+
+	// hashFuncSeedMultiplier is multiplied by the hash function number to derive its murmur3 seed
+	hashFuncSeedMultiplier = 0xFBA4C795
+)
+
+var (
+	// ErrFilterTooLarge the filter exceeds MaxFilterSize
+	ErrFilterTooLarge = errors.New("Bloom filter exceeds max size")
+	// ErrTooManyHashFuncs the filter uses more than MaxHashFuncs hash functions
+	ErrTooManyHashFuncs = errors.New("Bloom filter has too many hash functions")
+	// ErrEmptyFilter the filter has no data or no hash functions
+	ErrEmptyFilter = errors.New("Bloom filter is empty")
+	// ErrElementTooLarge an element exceeds MaxElementSize
+	ErrElementTooLarge = errors.New("Bloom filter element exceeds max size")
+)
+
+// UpdateFlags controls how a peer updates a filter when a transaction matches it
+type UpdateFlags uint8
+
+const (
+	// UpdateNone the filter is never updated by the peer
+	UpdateNone UpdateFlags = 0
+	// UpdateOutputs the IDs of the outputs of matching transactions are added to the filter,
+	// so that transactions spending them match too
+	UpdateOutputs UpdateFlags = 1
+)
+
+// Filter is a bloom filter. It is safe for concurrent use.
+type Filter struct {
+	sync.Mutex
+	data      []byte
+	hashFuncs uint32
+	tweak     uint32
+	flags     UpdateFlags
+}
+
+// New creates an empty Filter sized for the number of elements and false positive rate
+func New(elements int, fpRate float64, tweak uint32, flags UpdateFlags) *Filter {
+	if elements < 1 {
+		elements = 1
+	}
+
+	// Optimal number of bits is -n*ln(p)/ln(2)^2, and of hash functions is m/n*ln(2)
+	size := int(-float64(elements) * math.Log(fpRate) / (math.Ln2 * math.Ln2) / 8)
+	if size < 1 {
+		size = 1
+	}
+	if size > MaxFilterSize {
+		size = MaxFilterSize
+	}
+
+	hashFuncs := uint32(float64(size*8) / float64(elements) * math.Ln2)
+	if hashFuncs < 1 {
+		hashFuncs = 1
+	}
+	if hashFuncs > MaxHashFuncs {
+		hashFuncs = MaxHashFuncs
+	}
+
+	return &Filter{
+		data:      make([]byte, size),
+		hashFuncs: hashFuncs,
+		tweak:     tweak,
+		flags:     flags,
+	}
+}
+
+// Load creates a Filter from the contents of a FilterLoadMessage
+func Load(data []byte, hashFuncs, tweak uint32, flags UpdateFlags) (*Filter, error) {
+	if len(data) > MaxFilterSize {
+		return nil, ErrFilterTooLarge
+	}
+	if hashFuncs > MaxHashFuncs {
+		return nil, ErrTooManyHashFuncs
+	}
+	if len(data) == 0 || hashFuncs == 0 {
+		return nil, ErrEmptyFilter
+	}
+
+	d := make([]byte, len(data))
+	copy(d, data)
+
+	return &Filter{
+		data:      d,
+		hashFuncs: hashFuncs,
+		tweak:     tweak,
+		flags:     flags,
+	}, nil
+}
+
+// Data returns a copy of the filter's bit array
+func (f *Filter) Data() []byte {
+	f.Lock()
+	defer f.Unlock()
+
+	d := make([]byte, len(f.data))
+	copy(d, f.data)
+	return d
+}
+
+// HashFuncs returns the number of hash functions
+func (f *Filter) HashFuncs() uint32 {
+	return f.hashFuncs
+}
+
+// Tweak returns the random value that seeds the hash functions
+func (f *Filter) Tweak() uint32 {
+	return f.tweak
+}
+
+// Flags returns the filter's UpdateFlags
+func (f *Filter) Flags() UpdateFlags {
+	return f.flags
+}
+
+// Add adds an element to the filter
+func (f *Filter) Add(element []byte) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.add(element)
+}
+
+// Matches returns true if the element may have been added to the filter
+func (f *Filter) Matches(element []byte) bool {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.matches(element)
+}
+
+func (f *Filter) add(element []byte) {
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		n := f.bit(i, element)
+		f.data[n>>3] |= 1 << (n & 7)
+	}
+}
+
+func (f *Filter) matches(element []byte) bool {
+	for i := uint32(0); i < f.hashFuncs; i++ {
+		n := f.bit(i, element)
+		if f.data[n>>3]&(1<<(n&7)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bit returns the index of the bit set by hash function i for an element
+func (f *Filter) bit(i uint32, element []byte) uint32 {
+	return murmur3(i*hashFuncSeedMultiplier+f.tweak, element) % uint32(len(f.data)*8)
+}
+
+// murmur3 computes the 32-bit murmur3 hash of data
+func murmur3(seed uint32, data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	h := seed
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+
+		h ^= k
+		h = (h << 13) | (h >> 19)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[n*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = (k << 15) | (k >> 17)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
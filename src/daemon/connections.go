@@ -2,13 +2,13 @@ package daemon
 
 import (
 	"errors"
-	"fmt"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"../../src/cipher"
+	"../../src/daemon/bloom"
 	"../../src/params"
 	"../../src/util/iputil"
 	"../../src/util/useragent"
@@ -51,6 +51,8 @@ var (
 	ErrConnectionAlreadyConnected = errors.New("Connection is already in connected state")
 	// ErrInvalidGnetID invalid gnet ID value used as argument
 	ErrInvalidGnetID = errors.New("Invalid gnet ID")
+	// ErrNoBloomFilter the connection has not loaded a bloom filter
+	ErrNoBloomFilter = errors.New("Connection has no bloom filter")
 )
 
 // ConnectionDetails connection data managed by daemon
@@ -65,6 +67,19 @@ type ConnectionDetails struct {
 	UserAgent            useragent.Data
 	UnconfirmedVerifyTxn params.VerifyTxn
 	GenesisHash          cipher.SHA256
+	// Services are the optional protocol features advertised by the peer
+	Services Services
+	// BloomFilter is loaded by light clients, nil otherwise
+	BloomFilter *bloom.Filter
+	// RTT is the round-trip time of the last ping answered by the peer
+	RTT time.Duration
+
+	// pingSentAt is when the last unanswered ping was queued
+	pingSentAt time.Time
+	// blockRequestPending is true if blocks were requested from the peer, and it has not sent any since
+	blockRequestPending bool
+	// blocksRequestedAt is when the pending block request was sent
+	blocksRequestedAt time.Time
 }
 
 // HasIntroduced returns true if the connection has introduced
@@ -95,7 +110,7 @@ func (c *connection) ListenAddr() string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s:%d", ip, c.ListenPort)
+	return iputil.JoinAddr(ip, c.ListenPort)
 }
 
 // Connections manages a collection of Connection
@@ -134,7 +149,7 @@ func (c *Connections) pending(addr string) (*connection, error) {
 		return nil, ErrConnectionExists
 	}
 
-	c.ipCounts[ip]++
+	c.ipCounts[iputil.BaseIP(ip)]++
 
 	conn := &connection{
 		Addr: addr,
@@ -177,7 +192,7 @@ func (c *Connections) connected(addr string, gnetID uint64) (*connection, error)
 	conn := c.conns[addr]
 
 	if conn == nil {
-		c.ipCounts[ip]++
+		c.ipCounts[iputil.BaseIP(ip)]++
 
 		conn = &connection{
 			Addr: addr,
@@ -300,6 +315,7 @@ func (c *Connections) introduced(addr string, gnetID uint64, m *IntroductionMess
 	conn.UserAgent = m.UserAgent
 	conn.UnconfirmedVerifyTxn = m.UnconfirmedVerifyTxn
 	conn.GenesisHash = m.GenesisHash
+	conn.Services = m.Services
 
 	if !conn.Outgoing {
 		listenAddr := conn.ListenAddr()
@@ -389,6 +405,29 @@ func (c *Connections) modify(addr string, gnetID uint64, f func(c *ConnectionDet
 	return nil
 }
 
+// SetBloomFilter sets the bloom filter for a connection. Set to nil to clear the filter
+func (c *Connections) SetBloomFilter(addr string, gnetID uint64, f *bloom.Filter) error {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.modify(addr, gnetID, func(c *ConnectionDetails) {
+		c.BloomFilter = f
+	})
+}
+
+// bloomFilter returns the bloom filter of a connection, or nil if it has not loaded one
+func (c *Connections) bloomFilter(addr string) *bloom.Filter {
+	c.Lock()
+	defer c.Unlock()
+
+	conn := c.conns[addr]
+	if conn == nil {
+		return nil
+	}
+
+	return conn.BloomFilter
+}
+
 // SetHeight sets the height for a connection
 func (c *Connections) SetHeight(addr string, gnetID uint64, height uint64) error {
 	c.Lock()
@@ -399,6 +438,103 @@ func (c *Connections) SetHeight(addr string, gnetID uint64, height uint64) error
 	})
 }
 
+// pingSent records when a ping was queued for a connection
+func (c *Connections) pingSent(addr string, t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if conn := c.conns[addr]; conn != nil {
+		conn.pingSentAt = t
+	}
+}
+
+// pongReceived sets the connection's RTT from the time that the last ping was sent.
+// Returns the RTT and the connection's listen address, or false if there was no unanswered ping
+func (c *Connections) pongReceived(addr string, gnetID uint64, t time.Time) (time.Duration, string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	conn := c.conns[addr]
+	if conn == nil || conn.gnetID != gnetID || conn.pingSentAt.IsZero() {
+		return 0, "", false
+	}
+
+	conn.RTT = t.Sub(conn.pingSentAt)
+	conn.pingSentAt = time.Time{}
+
+	return conn.RTT, conn.ListenAddr(), true
+}
+
+// blocksRequested marks a block request sent at t as pending on the connections with a gnet ID in gnetIDs,
+// if they reported a height above headSeq. Returns the listen addresses of those connections
+func (c *Connections) blocksRequested(gnetIDs []uint64, headSeq uint64, t time.Time) []string {
+	c.Lock()
+	defer c.Unlock()
+
+	var addrs []string
+	for _, id := range gnetIDs {
+		conn := c.conns[c.gnetIDs[id]]
+		if conn == nil || conn.Height <= headSeq {
+			continue
+		}
+
+		if !conn.blockRequestPending {
+			conn.blockRequestPending = true
+			conn.blocksRequestedAt = t
+		}
+		if listenAddr := conn.ListenAddr(); listenAddr != "" {
+			addrs = append(addrs, listenAddr)
+		}
+	}
+
+	return addrs
+}
+
+// blocksReceived clears the pending block request of a connection.
+// Returns the connection's listen address, or false if no block request was pending
+func (c *Connections) blocksReceived(addr string, gnetID uint64) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	conn := c.conns[addr]
+	if conn == nil || conn.gnetID != gnetID || !conn.blockRequestPending {
+		return "", false
+	}
+
+	conn.blockRequestPending = false
+	conn.blocksRequestedAt = time.Time{}
+
+	return conn.ListenAddr(), true
+}
+
+// unservedBlockRequests returns the connections that report a height above headSeq,
+// and have not answered a block request sent before t.
+// The pending block requests of connections that do not report a height above headSeq anymore are cleared,
+// since the blocks that were requested from them are not needed
+func (c *Connections) unservedBlockRequests(headSeq uint64, t time.Time) []connection {
+	c.Lock()
+	defer c.Unlock()
+
+	var conns []connection
+	for _, conn := range c.conns {
+		if !conn.blockRequestPending {
+			continue
+		}
+
+		if conn.Height <= headSeq {
+			conn.blockRequestPending = false
+			conn.blocksRequestedAt = time.Time{}
+			continue
+		}
+
+		if conn.blocksRequestedAt.Before(t) {
+			conns = append(conns, *conn)
+		}
+	}
+
+	return conns
+}
+
 func (c *Connections) updateMirror(ip string, mirror uint32, port uint16) error {
 	x := c.mirrors[mirror]
 	if x == nil {
@@ -430,11 +566,12 @@ func (c *Connections) canUpdateMirror(ip string, mirror uint32) error {
 	return nil
 }
 
-// IPCount returns the number of connections for a given base IP (without port)
+// IPCount returns the number of connections for a given IP (without port).
+// IPv6 connections are counted per /64 network, see iputil.BaseIP.
 func (c *Connections) IPCount(ip string) int {
 	c.Lock()
 	defer c.Unlock()
-	return c.ipCounts[ip]
+	return c.ipCounts[iputil.BaseIP(ip)]
 }
 
 // Len returns number of connections
@@ -512,8 +649,9 @@ func (c *Connections) remove(addr string, gnetID uint64) error {
 		delete(c.mirrors, conn.Mirror)
 	}
 
-	if c.ipCounts[ip] > 0 {
-		c.ipCounts[ip]--
+	baseIP := iputil.BaseIP(ip)
+	if c.ipCounts[baseIP] > 0 {
+		c.ipCounts[baseIP]--
 	} else {
 		logger.Critical().WithFields(fields).Warning("ipCount was already 0 when removing existing address")
 	}
@@ -0,0 +1,794 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"../../src/cipher"
+	"../../src/coin"
+	"../../src/consensus"
+	"../../src/daemon/gnet"
+	"../../src/visor"
+)
+
+// Block consensus lets several block publishers make the blockchain, so that it does not
+// depend on a single publisher. It is enabled when the visor is configured with BlockPublisherPubkeys.
+// Each publisher proposes a block for the next sequence and sends it in a GiveBlockCandidateMessage.
+// The agreement on a candidate runs in rounds. In round 0, a publisher signs the header of at most one candidate,
+// its own or the first valid one it receives, and sends the signature in a GiveBlockHeaderMessage.
+// The round 0 signatures are collected by a consensus.ConsensusParticipant.
+// A candidate is selected once a quorum, more than half of the block publishers at the sequence, signed it
+// in the same round. As each publisher signs one candidate per round, no other candidate can reach a quorum
+// in that round, so all nodes select the same block. It is executed with the signature of the publisher that proposed it.
+// If the publishers have not agreed on a block ConsensusWait after the first candidate was seen,
+// e.g. because their signatures are split while a publisher is offline, the candidates and signatures are sent again,
+// for peers that missed them, the blocks are requested from peers, in case the block was executed without this node,
+// and the publishers move to the next round, where each of them signs the candidate with the lowest header hash
+// that it has. A publisher that receives a signature for a later round moves to that round too.
+// Each vote is saved by the visor before it is sent, so that a restarted publisher does not sign
+// another candidate in the same round. A selected candidate that has not arrived is requested from peers.
+// The key of a block publisher can be replaced by a visor.PublisherKeyChange, so the publishers
+// are looked up for each sequence. The signatures of the new key of a proposed change are not counted
+// until the change is committed.
+// Blocks executed by other means, e.g. received in a GiveBlocksMessage while syncing, reset the consensus
+// to the new head block.
+
+// consensusCheckRate is how often the consensus is checked for a selected block, or for a retry
+const consensusCheckRate = time.Second
+
+// blockVote is a block publisher's signature of a candidate in a round after round 0
+type blockVote struct {
+	Hash cipher.SHA256
+	Sig  cipher.Sig
+}
+
+// consensusVoteStore saves the last vote of this node, see visor.ConsensusVote
+type consensusVoteStore interface {
+	GetConsensusVote() (*visor.ConsensusVote, error)
+	SetConsensusVote(visor.ConsensusVote) error
+}
+
+// blockConsensus holds the state of the agreement on the next block.
+// It is only accessed from the daemon run loop
+type blockConsensus struct {
+	participant *consensus.ConsensusParticipant
+	// pubkeysAt returns the public keys that may sign a block candidate at a sequence
+	pubkeysAt func(seq uint64) []cipher.PubKey
+	// votersAt returns the public keys of the block publishers whose signatures are counted at a sequence
+	votersAt func(seq uint64) []cipher.PubKey
+	// votes saves the votes of this node
+	votes consensusVoteStore
+	// publisher is true if this node is a block publisher
+	publisher bool
+	// pubkey is the public key of this node, if it is a block publisher
+	pubkey cipher.PubKey
+	// relay sends a header accepted by the participant to peers
+	relay func(consensus.BlockBase)
+
+	// head is the head block that the participant was last reset to
+	head *consensus.BlockBase
+	// candidates are the blocks proposed for the next sequence, by header hash
+	candidates map[cipher.SHA256]coin.SignedBlock
+	// headers are the headers for the next sequence that the participant accepted, to send them again
+	headers []consensus.BlockBase
+	// waitingSince is when the first header for the next sequence arrived, or when the consensus last moved to a new round
+	waitingSince time.Time
+	// round is the consensus round of this node for the next sequence
+	round uint32
+	// vote is the last vote of this node for the next sequence, nil if it has not voted
+	vote *visor.ConsensusVote
+	// roundVotes are the signatures for the next sequence in the rounds after round 0, by round and signer
+	roundVotes map[uint32]map[cipher.PubKey]blockVote
+	// selected is the header selected for the next sequence, if its block has not been executed
+	selected *consensus.BlockBase
+}
+
+func newBlockConsensus(pubkeysAt, votersAt func(uint64) []cipher.PubKey, votes consensusVoteStore, seckey cipher.SecKey, publisher bool, relay func(consensus.BlockBase)) *blockConsensus {
+	bc := &blockConsensus{
+		pubkeysAt:  pubkeysAt,
+		votersAt:   votersAt,
+		votes:      votes,
+		publisher:  publisher,
+		relay:      relay,
+		candidates: make(map[cipher.SHA256]coin.SignedBlock),
+		roundVotes: make(map[uint32]map[cipher.PubKey]blockVote),
+	}
+
+	bc.participant = consensus.NewConsensusParticipantPtr(bc)
+	if publisher {
+		bc.pubkey = cipher.MustPubKeyFromSecKey(seckey)
+		bc.participant.SetPubkeySeckey(bc.pubkey, seckey)
+	}
+
+	return bc
+}
+
+// SendBlockToAllMySubscriber implements consensus.ConnectionManagerInterface.
+// It is called by the participant for every header that it accepts
+func (bc *blockConsensus) SendBlockToAllMySubscriber(b *consensus.BlockBase) {
+	bc.headers = append(bc.headers, *b)
+	if bc.relay != nil {
+		bc.relay(*b)
+	}
+}
+
+// Print implements consensus.ConnectionManagerInterface
+func (bc *blockConsensus) Print() {
+	logger.Debugf("blockConsensus={candidates=%d,round=%d,voted=%t}", len(bc.candidates), bc.round, bc.vote != nil)
+}
+
+// nextSeq returns the sequence of the block that is being agreed on
+func (bc *blockConsensus) nextSeq() uint64 {
+	return bc.head.Seqno + 1
+}
+
+// setHead resets the consensus to a new head block. A vote for the next sequence saved before
+// the node was restarted is restored
+func (bc *blockConsensus) setHead(b coin.SignedBlock) error {
+	var vote *visor.ConsensusVote
+	if bc.publisher {
+		v, err := bc.votes.GetConsensusVote()
+		if err != nil {
+			return err
+		}
+		if v != nil && v.Seq == b.Seq()+1 {
+			vote = v
+		}
+	}
+
+	bc.head = &consensus.BlockBase{
+		Sig:   b.Sig,
+		Hash:  b.HashHeader(),
+		Seqno: b.Seq(),
+	}
+	bc.participant.SetBlockchainHead(bc.head)
+
+	bc.candidates = make(map[cipher.SHA256]coin.SignedBlock)
+	bc.headers = nil
+	bc.waitingSince = time.Time{}
+	bc.round = 0
+	bc.vote = vote
+	bc.roundVotes = make(map[uint32]map[cipher.PubKey]blockVote)
+	bc.selected = nil
+
+	if vote != nil {
+		bc.round = vote.Round
+	}
+
+	return nil
+}
+
+// addHeader gives a header for the next sequence to the participant
+func (bc *blockConsensus) addHeader(seq uint64, hash cipher.SHA256, sig cipher.Sig) {
+	if bc.waitingSince.IsZero() {
+		bc.waitingSince = time.Now()
+	}
+
+	bc.participant.OnBlockHeaderArrived(&consensus.BlockBase{
+		Sig:   sig,
+		Hash:  hash,
+		Seqno: seq,
+	})
+}
+
+// canVote returns true if this node is a block publisher whose signature is counted at the next sequence,
+// and it has not voted in round or in a later round
+func (bc *blockConsensus) canVote(round uint32) bool {
+	if !bc.publisher || !containsPubKey(bc.votersAt(bc.nextSeq()), bc.pubkey) {
+		return false
+	}
+
+	return bc.vote == nil || bc.vote.Round < round
+}
+
+// saveVote saves the vote of this node for a candidate for the next sequence. It must be called
+// before the signature is sent
+func (bc *blockConsensus) saveVote(round uint32, hash cipher.SHA256) error {
+	v := visor.ConsensusVote{
+		Seq:   bc.nextSeq(),
+		Round: round,
+		Hash:  hash,
+	}
+
+	if err := bc.votes.SetConsensusVote(v); err != nil {
+		return err
+	}
+
+	bc.vote = &v
+	return nil
+}
+
+// sign signs a header for the next sequence in round 0, unless this node already voted
+// or its key is not counted at the next sequence. Returns false if the header was not signed
+func (bc *blockConsensus) sign(hash cipher.SHA256) (cipher.Sig, bool) {
+	if !bc.canVote(0) {
+		return cipher.Sig{}, false
+	}
+
+	if err := bc.saveVote(0, hash); err != nil {
+		logger.WithError(err).Error("Failed to save the consensus vote")
+		return cipher.Sig{}, false
+	}
+
+	return bc.participant.SignatureOf(hash), true
+}
+
+// roundVoteHash returns the hash that a block publisher signs to vote for a candidate in a round after round 0.
+// Round 0 signatures sign the candidate's header hash, like the signature of the publisher that proposed it
+func roundVoteHash(seq uint64, round uint32, hash cipher.SHA256) cipher.SHA256 {
+	var b [len(hash) + 12]byte
+	copy(b[:], hash[:])
+	binary.LittleEndian.PutUint64(b[len(hash):], seq)
+	binary.LittleEndian.PutUint32(b[len(hash)+8:], round)
+	return cipher.SumSHA256(b[:])
+}
+
+// verifySigner checks that sig is the signature of hash by a block publisher at seq
+func (bc *blockConsensus) verifySigner(seq uint64, hash cipher.SHA256, sig cipher.Sig) error {
+	signer, err := cipher.PubKeyFromSig(sig, hash)
+	if err != nil {
+		return err
+	}
+
+	if !containsPubKey(bc.pubkeysAt(seq), signer) {
+		return visor.ErrUnknownBlockSigner
+	}
+
+	return cipher.VerifyPubKeySignedHash(signer, sig, hash)
+}
+
+// verifyVoter checks that sig is the signature of a vote for hash in a round after round 0,
+// by a block publisher whose signature is counted at seq. Returns the signer
+func (bc *blockConsensus) verifyVoter(seq uint64, round uint32, hash cipher.SHA256, sig cipher.Sig) (cipher.PubKey, error) {
+	h := roundVoteHash(seq, round, hash)
+	signer, err := cipher.PubKeyFromSig(sig, h)
+	if err != nil {
+		return cipher.PubKey{}, err
+	}
+
+	if !containsPubKey(bc.votersAt(seq), signer) {
+		return cipher.PubKey{}, visor.ErrUnknownBlockSigner
+	}
+
+	if err := cipher.VerifyPubKeySignedHash(signer, sig, h); err != nil {
+		return cipher.PubKey{}, err
+	}
+
+	return signer, nil
+}
+
+// addRoundVote records a vote for the next sequence in a round after round 0.
+// Returns false if the signer already voted in the round
+func (bc *blockConsensus) addRoundVote(round uint32, signer cipher.PubKey, v blockVote) bool {
+	if bc.waitingSince.IsZero() {
+		bc.waitingSince = time.Now()
+	}
+
+	votes, ok := bc.roundVotes[round]
+	if !ok {
+		votes = make(map[cipher.PubKey]blockVote)
+		bc.roundVotes[round] = votes
+	}
+
+	if _, ok := votes[signer]; ok {
+		return false
+	}
+
+	votes[signer] = v
+	return true
+}
+
+// lowestCandidate returns the hash of the candidate with the lowest header hash, that the block publishers
+// vote for in the rounds after round 0. Returns false if there are no candidates
+func (bc *blockConsensus) lowestCandidate() (cipher.SHA256, bool) {
+	var lowest cipher.SHA256
+	found := false
+	for hash := range bc.candidates {
+		if !found || bytes.Compare(hash[:], lowest[:]) < 0 {
+			lowest = hash
+			found = true
+		}
+	}
+
+	return lowest, found
+}
+
+func containsPubKey(pubkeys []cipher.PubKey, pk cipher.PubKey) bool {
+	for _, x := range pubkeys {
+		if x == pk {
+			return true
+		}
+	}
+	return false
+}
+
+// quorum returns how many of n block publishers must sign a candidate to select it
+func quorum(n int) int {
+	return n/2 + 1
+}
+
+// quorumHash returns the hash signed by a quorum of voters, counting only the signatures of voters.
+// If several hashes have a quorum, which takes a voter that signed more than one hash in a round, the lowest is returned
+func quorumHash(signers map[cipher.SHA256][]cipher.PubKey, voters []cipher.PubKey) (cipher.SHA256, bool) {
+	var best cipher.SHA256
+	found := false
+	for hash, pks := range signers {
+		n := 0
+		for _, pk := range pks {
+			if containsPubKey(voters, pk) {
+				n++
+			}
+		}
+
+		if n >= quorum(len(voters)) && (!found || bytes.Compare(hash[:], best[:]) < 0) {
+			best = hash
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// ripe selects the header for the next sequence, once a quorum of the block publishers at the sequence
+// signed it in the same round. Earlier rounds are checked first. Returns nil if no header is selected yet
+func (bc *blockConsensus) ripe() *consensus.BlockBase {
+	if bc.selected != nil {
+		return bc.selected
+	}
+
+	seq := bc.nextSeq()
+	voters := bc.votersAt(seq)
+
+	if stat := bc.participant.GetBlockStat(seq); stat != nil {
+		if hash, ok := quorumHash(stat.GetHashSigners(), voters); ok {
+			bc.selected = &consensus.BlockBase{
+				Hash:  hash,
+				Seqno: seq,
+			}
+			return bc.selected
+		}
+	}
+
+	for round := uint32(1); round <= bc.round; round++ {
+		signers := make(map[cipher.SHA256][]cipher.PubKey)
+		for pk, v := range bc.roundVotes[round] {
+			signers[v.Hash] = append(signers[v.Hash], pk)
+		}
+
+		if hash, ok := quorumHash(signers, voters); ok {
+			bc.selected = &consensus.BlockBase{
+				Hash:  hash,
+				Seqno: seq,
+			}
+			return bc.selected
+		}
+	}
+
+	return nil
+}
+
+// retryDue returns true if the block publishers have not agreed on the next block wait after the first
+// header for it arrived, or after the last retry
+func (bc *blockConsensus) retryDue(now time.Time, wait time.Duration) bool {
+	return !bc.waitingSince.IsZero() && now.Sub(bc.waitingSince) >= wait
+}
+
+// syncConsensusHead resets the consensus if the head block changed since it was last reset
+func (dm *Daemon) syncConsensusHead() error {
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("No HeadBkSeq found")
+	}
+
+	if dm.consensus.head != nil && dm.consensus.head.Seqno == headSeq {
+		return nil
+	}
+
+	head, err := dm.visor.GetSignedBlockBySeq(headSeq)
+	if err != nil {
+		return err
+	}
+
+	return dm.consensus.setHead(*head)
+}
+
+// proposeBlock creates a block from unconfirmed transactions, or a block with no transactions if empty is true,
+// and sends it to the other block publishers as a candidate for the next sequence. The block is not executed,
+// see checkConsensus. Does nothing if this node already voted for a candidate for the next sequence
+func (dm *Daemon) proposeBlock(empty bool) (*coin.SignedBlock, error) {
+	if dm.config.DisableNetworking {
+		return nil, ErrNetworkingDisabled
+	}
+
+	if err := dm.syncConsensusHead(); err != nil {
+		return nil, err
+	}
+
+	if dm.consensus.vote != nil {
+		return nil, nil
+	}
+
+	createBlock := dm.visor.CreateBlock
+	if empty {
+		createBlock = dm.visor.CreateEmptyBlock
+	}
+
+	sb, err := createBlock(uint64(time.Now().UTC().Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	if sb.Seq() != dm.consensus.nextSeq() {
+		return nil, fmt.Errorf("created block seq %d is not the next seq %d", sb.Seq(), dm.consensus.nextSeq())
+	}
+
+	// The candidate's signature is this node's round 0 vote
+	hash := sb.HashHeader()
+	if err := dm.consensus.saveVote(0, hash); err != nil {
+		return nil, err
+	}
+
+	dm.consensus.candidates[hash] = sb
+	dm.consensus.addHeader(sb.Seq(), hash, sb.Sig)
+
+	err = dm.broadcastConsensusMessage(NewGiveBlockCandidateMessage(sb))
+
+	return &sb, err
+}
+
+// receiveBlockCandidate records a block proposed by a block publisher for the next sequence.
+// A candidate that was not seen before is verified and relayed to peers.
+// If this node is a block publisher that has not voted for a candidate for the next sequence yet,
+// it signs this one
+func (dm *Daemon) receiveBlockCandidate(addr string, sb coin.SignedBlock) {
+	if dm.consensus == nil {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr": addr,
+		"seq":  sb.Seq(),
+	}
+
+	if err := dm.syncConsensusHead(); err != nil {
+		logger.WithError(err).WithFields(fields).Error("syncConsensusHead failed")
+		return
+	}
+
+	if sb.Seq() != dm.consensus.nextSeq() {
+		logger.WithFields(fields).Debug("Ignoring block candidate that is not for the next seq")
+		return
+	}
+
+	hash := sb.HashHeader()
+	fields["hash"] = hash.Hex()
+
+	if _, ok := dm.consensus.candidates[hash]; !ok {
+		if err := visor.VerifyBlockSignature(sb, dm.consensus.pubkeysAt(sb.Seq())); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Block candidate signature is invalid")
+			return
+		}
+
+		if err := dm.visor.VerifyBlock(sb); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Block candidate is invalid")
+			return
+		}
+
+		dm.consensus.candidates[hash] = sb
+
+		if err := dm.broadcastConsensusMessage(NewGiveBlockCandidateMessage(sb)); err != nil {
+			logger.WithError(err).WithFields(fields).Debug("Relay GiveBlockCandidateMessage failed")
+		}
+	}
+
+	dm.consensus.addHeader(sb.Seq(), hash, sb.Sig)
+
+	if sig, ok := dm.consensus.sign(hash); ok {
+		logger.WithFields(fields).Info("Signed block candidate")
+		dm.consensus.addHeader(sb.Seq(), hash, sig)
+	}
+
+	// The block may have been selected before it arrived
+	if s := dm.consensus.selected; s != nil {
+		if s.Hash == hash {
+			dm.executeSelectedBlock()
+		}
+		return
+	}
+
+	dm.selectBlock()
+}
+
+// receiveBlockHeader records a block publisher's signature of a candidate for the next sequence, in a round.
+// A signature for a round after this node's round moves this node to that round
+func (dm *Daemon) receiveBlockHeader(addr string, seq uint64, round uint32, hash cipher.SHA256, sig cipher.Sig) {
+	if dm.consensus == nil {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":  addr,
+		"seq":   seq,
+		"round": round,
+		"hash":  hash.Hex(),
+	}
+
+	if err := dm.syncConsensusHead(); err != nil {
+		logger.WithError(err).WithFields(fields).Error("syncConsensusHead failed")
+		return
+	}
+
+	if seq != dm.consensus.nextSeq() {
+		logger.WithFields(fields).Debug("Ignoring block header that is not for the next seq")
+		return
+	}
+
+	if round == 0 {
+		if err := dm.consensus.verifySigner(seq, hash, sig); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Block header signature is invalid")
+			return
+		}
+
+		dm.consensus.addHeader(seq, hash, sig)
+	} else {
+		signer, err := dm.consensus.verifyVoter(seq, round, hash, sig)
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Block header signature is invalid")
+			return
+		}
+
+		v := blockVote{
+			Hash: hash,
+			Sig:  sig,
+		}
+		if !dm.consensus.addRoundVote(round, signer, v) {
+			return
+		}
+
+		dm.broadcastBlockVote(seq, round, v)
+
+		if round > dm.consensus.round {
+			dm.voteInRound(round)
+		}
+	}
+
+	// The candidate may not have reached this node, e.g. if it was proposed during a partition.
+	// It is needed to vote for it in a later round, and to execute it if it is selected
+	if _, ok := dm.consensus.candidates[hash]; !ok {
+		dm.requestBlockCandidate(consensus.BlockBase{
+			Seqno: seq,
+			Hash:  hash,
+		})
+	}
+
+	dm.selectBlock()
+}
+
+// sendBlockCandidate sends a block candidate for the next sequence to a peer that asked for it.
+// Does nothing if this node does not have the candidate
+func (dm *Daemon) sendBlockCandidate(addr string, seq uint64, hash cipher.SHA256) {
+	if dm.consensus == nil || dm.consensus.head == nil || seq != dm.consensus.nextSeq() {
+		return
+	}
+
+	sb, ok := dm.consensus.candidates[hash]
+	if !ok {
+		return
+	}
+
+	if err := dm.sendMessage(addr, NewGiveBlockCandidateMessage(sb)); err != nil {
+		logger.WithError(err).WithField("addr", addr).Debug("Send GiveBlockCandidateMessage failed")
+	}
+}
+
+// checkConsensus executes the block selected for the next sequence. If the block publishers have not
+// agreed on it within ConsensusWait, or the selected block has not arrived, the consensus is retried
+func (dm *Daemon) checkConsensus() {
+	if err := dm.syncConsensusHead(); err != nil {
+		logger.WithError(err).Error("checkConsensus: syncConsensusHead failed")
+		return
+	}
+
+	if dm.consensus.selected == nil && dm.consensus.ripe() != nil {
+		dm.executeSelectedBlock()
+		return
+	}
+
+	if !dm.consensus.retryDue(time.Now(), dm.config.ConsensusWait) {
+		return
+	}
+
+	dm.retryConsensus()
+}
+
+// selectBlock executes the block for the next sequence, if the block publishers' signatures select it.
+// Does nothing if a block was already selected
+func (dm *Daemon) selectBlock() {
+	if dm.consensus.selected != nil || dm.consensus.ripe() == nil {
+		return
+	}
+
+	dm.executeSelectedBlock()
+}
+
+// retryConsensus requests the selected block candidate if it has not arrived, or else sends the candidates
+// and signatures for the next sequence again, for the peers that missed them, and moves to the next round.
+// The blocks are also requested from peers, in case the next block was executed by the other nodes
+func (dm *Daemon) retryConsensus() {
+	bc := dm.consensus
+	bc.waitingSince = time.Now()
+
+	fields := logrus.Fields{
+		"seq":        bc.nextSeq(),
+		"round":      bc.round,
+		"candidates": len(bc.candidates),
+		"headers":    len(bc.headers),
+	}
+
+	if s := bc.selected; s != nil {
+		fields["hash"] = s.Hash.Hex()
+		logger.WithFields(fields).Info("Selected block candidate has not arrived, requesting it again")
+		dm.requestBlockCandidate(*s)
+	} else {
+		logger.WithFields(fields).Info("Block publishers have not agreed on the next block, sending the candidates and signatures again")
+
+		for _, sb := range bc.candidates {
+			if err := dm.broadcastConsensusMessage(NewGiveBlockCandidateMessage(sb)); err != nil {
+				logger.WithError(err).Debug("Broadcast GiveBlockCandidateMessage failed")
+			}
+		}
+
+		for _, b := range bc.headers {
+			dm.broadcastBlockHeader(b)
+		}
+
+		for _, v := range bc.roundVotes[bc.round] {
+			dm.broadcastBlockVote(bc.nextSeq(), bc.round, v)
+		}
+
+		dm.voteInRound(bc.round + 1)
+		dm.selectBlock()
+	}
+
+	if err := dm.requestBlocks(); err != nil {
+		logger.WithError(err).Debug("retryConsensus: requestBlocks failed")
+	}
+}
+
+// voteInRound moves the consensus for the next sequence to a later round. If this node is a block publisher
+// that has not voted in the round, it signs the candidate with the lowest header hash, so that the block publishers
+// whose signatures were split in the earlier rounds sign the same candidate
+func (dm *Daemon) voteInRound(round uint32) {
+	bc := dm.consensus
+	if round <= bc.round {
+		return
+	}
+
+	bc.round = round
+	bc.waitingSince = time.Now()
+
+	if !bc.canVote(round) {
+		return
+	}
+
+	hash, ok := bc.lowestCandidate()
+	if !ok {
+		return
+	}
+
+	fields := logrus.Fields{
+		"seq":   bc.nextSeq(),
+		"round": round,
+		"hash":  hash.Hex(),
+	}
+
+	if err := bc.saveVote(round, hash); err != nil {
+		logger.WithError(err).WithFields(fields).Error("Failed to save the consensus vote")
+		return
+	}
+
+	v := blockVote{
+		Hash: hash,
+		Sig:  bc.participant.SignatureOf(roundVoteHash(bc.nextSeq(), round, hash)),
+	}
+	bc.addRoundVote(round, bc.pubkey, v)
+
+	logger.WithFields(fields).Info("Signed the block candidate with the lowest hash in a new consensus round")
+
+	dm.broadcastBlockVote(bc.nextSeq(), round, v)
+}
+
+// requestBlockCandidate asks the peers for the selected block candidate
+func (dm *Daemon) requestBlockCandidate(b consensus.BlockBase) {
+	if err := dm.broadcastConsensusMessage(NewGetBlockCandidateMessage(b.Seqno, b.Hash)); err != nil {
+		logger.WithError(err).Debug("Broadcast GetBlockCandidateMessage failed")
+	}
+}
+
+// executeSelectedBlock executes the block selected for the next sequence, with the signature of the
+// block publisher that proposed it. If the block has not arrived yet, it is requested from peers
+func (dm *Daemon) executeSelectedBlock() {
+	selected := dm.consensus.selected
+
+	sb, ok := dm.consensus.candidates[selected.Hash]
+	if !ok {
+		logger.WithFields(logrus.Fields{
+			"seq":  selected.Seqno,
+			"hash": selected.Hash.Hex(),
+		}).Debug("Waiting for the selected block candidate")
+		dm.requestBlockCandidate(*selected)
+		return
+	}
+
+	if err := dm.visor.ExecuteSignedBlock(sb); err != nil {
+		logger.Critical().WithError(err).WithField("seq", sb.Seq()).Error("Failed to execute the selected block candidate")
+		dm.consensus.head = nil
+		return
+	}
+
+	logger.Critical().WithFields(logrus.Fields{
+		"seq":        sb.Seq(),
+		"hash":       selected.Hash.Hex(),
+		"candidates": len(dm.consensus.candidates),
+	}).Info("Added new block selected by the block publishers")
+
+	if err := dm.consensus.setHead(sb); err != nil {
+		logger.WithError(err).Error("Failed to reset the consensus to the new head block")
+		dm.consensus.head = nil
+	}
+
+	// Peers that do not take part in the consensus receive the block from the block publishers
+	if dm.consensus.publisher {
+		if err := dm.broadcastBlock(sb); err != nil {
+			logger.WithError(err).Warning("broadcastBlock failed")
+		}
+		return
+	}
+
+	if _, err := dm.broadcastMessage(NewAnnounceBlocksMessage(sb.Seq())); err != nil {
+		logger.WithError(err).Debug("Broadcast AnnounceBlocksMessage failed")
+	}
+}
+
+// broadcastBlockHeader sends a block header accepted by the participant to peers
+func (dm *Daemon) broadcastBlockHeader(b consensus.BlockBase) {
+	if err := dm.broadcastConsensusMessage(NewGiveBlockHeaderMessage(b.Seqno, 0, b.Hash, b.Sig)); err != nil {
+		logger.WithError(err).Debug("Broadcast GiveBlockHeaderMessage failed")
+	}
+}
+
+// broadcastBlockVote sends a signature for a round after round 0 to peers
+func (dm *Daemon) broadcastBlockVote(seq uint64, round uint32, v blockVote) {
+	if err := dm.broadcastConsensusMessage(NewGiveBlockHeaderMessage(seq, round, v.Hash, v.Sig)); err != nil {
+		logger.WithError(err).Debug("Broadcast GiveBlockHeaderMessage failed")
+	}
+}
+
+// broadcastConsensusMessage sends a message to the introduced connections that advertise ServiceConsensus
+func (dm *Daemon) broadcastConsensusMessage(msg gnet.Message) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	var addrs []string
+	for _, c := range dm.connections.all() {
+		if c.HasIntroduced() && c.Services.Has(ServiceConsensus) {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	_, err := dm.pool.Pool.BroadcastMessage(msg, addrs)
+	return err
+}
@@ -17,6 +17,7 @@ import (
 
 	"../../src/cipher"
 	"../../src/coin"
+	"../../src/daemon/bloom"
 	"../../src/daemon/gnet"
 	"../../src/daemon/pex"
 	"../../src/params"
@@ -34,6 +35,12 @@ var (
 	ErrNetworkingDisabled = errors.New("Networking is disabled")
 	// ErrNoPeerAcceptsTxn is returned if no peer will propagate a transaction broadcasted with BroadcastUserTransaction
 	ErrNoPeerAcceptsTxn = errors.New("No peer will propagate this transaction")
+	// ErrNoStemPeer is returned if no peer can relay a transaction in the Dandelion stem phase
+	ErrNoStemPeer = errors.New("No peer can relay this transaction in the stem phase")
+	// ErrOutgoingConnectionsDisabled is returned when making an outgoing connection if outgoing connections are disabled
+	ErrOutgoingConnectionsDisabled = errors.New("Outgoing connections disabled")
+	// ErrOutgoingConnectionsFull is returned when making an outgoing connection if the connection limits are reached
+	ErrOutgoingConnectionsFull = errors.New("Max outgoing connections reached")
 
 	logger = logging.MustGetLogger("daemon")
 )
@@ -54,6 +61,8 @@ func IsBroadcastFailure(err error) bool {
 
 const (
 	daemonRunDurationThreshold = time.Millisecond * 200
+	// outgoingCandidatesPerSlot is the number of peers ranked for each free outgoing connection slot
+	outgoingCandidatesPerSlot = 8
 )
 
 // Config subsystem configurations
@@ -112,6 +121,34 @@ func (cfg *Config) preprocess() (Config, error) {
 		return Config{}, errors.New("MaxOutgoingConnections cannot be more than MaxConnections")
 	}
 
+	if config.Daemon.DandelionFluffProbability < 0 || config.Daemon.DandelionFluffProbability > 1 {
+		return Config{}, errors.New("DandelionFluffProbability must be between 0 and 1")
+	}
+
+	if config.Daemon.DandelionEmbargo <= 0 {
+		return Config{}, errors.New("DandelionEmbargo must be > 0")
+	}
+
+	if config.Daemon.SyncStallTimeout != 0 && config.Daemon.SyncStallTimeout <= config.Daemon.BlocksRequestRate {
+		return Config{}, errors.New("SyncStallTimeout must be greater than BlocksRequestRate, or 0 to disable it")
+	}
+
+	if config.Daemon.ConsensusWait <= 0 {
+		return Config{}, errors.New("ConsensusWait must be > 0")
+	}
+
+	if config.Daemon.PublisherLeaseIntervals < 2 {
+		return Config{}, errors.New("PublisherLeaseIntervals must be >= 2")
+	}
+
+	if config.Daemon.BlockMinTransactions < 1 {
+		return Config{}, errors.New("BlockMinTransactions must be >= 1")
+	}
+
+	if config.Daemon.Regtest && !config.Daemon.DisableNetworking {
+		return Config{}, errors.New("Regtest requires DisableNetworking")
+	}
+
 	if config.Daemon.MaxPendingConnections > config.Daemon.MaxOutgoingConnections {
 		config.Daemon.MaxPendingConnections = config.Daemon.MaxOutgoingConnections
 	}
@@ -189,6 +226,9 @@ type DaemonConfig struct { //nolint:golint
 	IPCountsMax int
 	// Disable all networking activity
 	DisableNetworking bool
+	// Run a local regtest chain. Blocks are created only by MineBlocks, and user transactions
+	// are not broadcast. Requires DisableNetworking. See MineBlocks
+	Regtest bool
 	// Don't make outgoing connections
 	DisableOutgoingConnections bool
 	// Don't allow incoming connections
@@ -207,8 +247,44 @@ type DaemonConfig struct { //nolint:golint
 	MaxGetBlocksResponseCount uint64
 	// Max announce txns hash number
 	MaxTxnAnnounceNum int
+	// How long a peer may leave a block request unanswered before it is disconnected, and how long
+	// the head block may not advance while peers report a higher height before the sync is stalled.
+	// 0 disables the sync watchdog, see checkSync
+	SyncStallTimeout time.Duration
+	// Relay transactions created by this node through a random path of peers before
+	// they are broadcast, to hide their origin (Dandelion). See stemUserTransaction
+	Dandelion bool
+	// Probability that a transaction relayed in the stem phase is broadcast instead of passed on
+	DandelionFluffProbability float64
+	// How long to wait for a transaction sent in the stem phase to be broadcast by another node,
+	// before broadcasting it. A random delay of up to the same duration is added
+	DandelionEmbargo time.Duration
 	// How often new blocks are created by the signing node, in seconds
 	BlockCreationInterval uint64
+	// Run the block publisher as a hot standby, which creates blocks only after the lease
+	// of the active block publisher expires. See publisherLease
+	PublisherStandby bool
+	// How many block creation intervals a block may be due without a new block
+	// before the lease of the active block publisher expires
+	PublisherLeaseIntervals uint64
+	// Minimum number of valid unconfirmed transactions to create a block
+	BlockMinTransactions int
+	// Minimum total fee of the valid unconfirmed transactions to create a block, in coin hours
+	BlockMinFee uint64
+	// How old the head block may get before a block is created even if BlockMinTransactions
+	// or BlockMinFee are not reached, in seconds. 0 waits for them indefinitely
+	MaxBlockAge uint64
+	// How old the head block may get before an empty block is created, if there are
+	// no valid unconfirmed transactions, in seconds. 0 disables empty blocks.
+	// The blockchain must allow empty blocks, see visor.Config.AllowEmptyBlocks
+	EmptyBlockInterval uint64
+	// Decides when blocks are created. If nil, a ThresholdBlockPolicy is created from
+	// BlockMinTransactions, BlockMinFee, MaxBlockAge and EmptyBlockInterval
+	BlockPolicy BlockPolicy
+	// How long to wait for the block publishers to agree on the next block before the candidates
+	// and signatures are sent again, missing blocks are requested from peers and the block publishers
+	// move to the next round. See checkConsensus
+	ConsensusWait time.Duration
 	// How often to check the unconfirmed pool for transactions that become valid
 	UnconfirmedRefreshRate time.Duration
 	// How often to remove transactions that become permanently invalid from the unconfirmed pool
@@ -257,7 +333,18 @@ func NewDaemonConfig() DaemonConfig {
 		GetBlocksRequestCount:        20,
 		MaxGetBlocksResponseCount:    20,
 		MaxTxnAnnounceNum:            16,
+		SyncStallTimeout:             time.Minute * 5,
+		Dandelion:                    false,
+		DandelionFluffProbability:    0.25,
+		DandelionEmbargo:             time.Second * 30,
 		BlockCreationInterval:        10,
+		PublisherStandby:             false,
+		PublisherLeaseIntervals:      3,
+		BlockMinTransactions:         1,
+		BlockMinFee:                  0,
+		MaxBlockAge:                  0,
+		EmptyBlockInterval:           0,
+		ConsensusWait:                time.Second * 5,
 		UnconfirmedRefreshRate:       time.Minute,
 		UnconfirmedRemoveInvalidRate: time.Minute,
 		Mirror:                       rand.New(rand.NewSource(time.Now().UTC().UnixNano())).Uint32(),
@@ -277,20 +364,33 @@ type daemoner interface {
 	sendMessage(addr string, msg gnet.Message) error
 	broadcastMessage(msg gnet.Message) ([]uint64, error)
 	disconnectNow(addr string, r gnet.DisconnectReason) error
-	addPeers(addrs []string) int
+	addPeers(source string, addrs []string) int
 	recordPeerHeight(addr string, gnetID, height uint64)
 	getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, error)
 	headBkSeq() (uint64, bool, error)
 	executeSignedBlock(b coin.SignedBlock) error
+	checkBlockConflict(addr string, b coin.SignedBlock) bool
 	filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.SHA256, error)
 	getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error)
 	requestBlocksFromAddr(addr string) error
+	recordBlocksReceived(addr string, gnetID uint64)
+	recordPong(addr string, gnetID uint64)
 	announceAllValidTxns() error
+	relayStemTxn(addr string, txn coin.Transaction, known bool)
+	stemTxnsFluffed(txids []cipher.SHA256)
+	receiveBlockCandidate(addr string, sb coin.SignedBlock)
+	receiveBlockHeader(addr string, seq uint64, round uint32, hash cipher.SHA256, sig cipher.Sig)
+	sendBlockCandidate(addr string, seq uint64, hash cipher.SHA256)
+	sendPublisherKeyChanges(addr string) error
+	receivePublisherKeyChanges(addr string, kcs []visor.PublisherKeyChange)
 	pexConfig() pex.Config
 	injectTransaction(txn coin.Transaction) (bool, *visor.ErrTxnViolatesSoftConstraint, error)
 	recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error
 	connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error)
 	sendRandomPeers(addr string) error
+	peerSupports(addr string, s Services) bool
+	setBloomFilter(addr string, gnetID uint64, f *bloom.Filter) error
+	addToBloomFilter(addr string, gnetID uint64, element []byte) error
 }
 
 // Daemon stateful properties of the daemon
@@ -306,6 +406,16 @@ type Daemon struct {
 
 	// Cache of announced transactions that are flushed to the database periodically
 	announcedTxns *announcedTxnsCache
+	// Transactions in the Dandelion stem phase
+	stemTxns *stemTxnPool
+	// Progress of the blockchain sync
+	syncWatchdog *syncWatchdog
+	// Agreement of the block publishers on the next block, nil if there is a single block publisher
+	consensus *blockConsensus
+	// Which block publisher creates the blocks, nil if not a block publisher or if there are several block publishers
+	publisherLease *publisherLease
+	// Decides when the block publisher creates blocks
+	blockPolicy BlockPolicy
 	// Cache of connection metadata
 	connections *Connections
 	// connect, disconnect, message, error events channel
@@ -335,12 +445,38 @@ func New(config Config, v *visor.Visor) (*Daemon, error) {
 		visor:    v,
 
 		announcedTxns: newAnnouncedTxnsCache(),
+		stemTxns:      newStemTxnPool(),
+		syncWatchdog:  newSyncWatchdog(),
 		connections:   NewConnections(),
 		events:        make(chan interface{}, config.Pool.EventChannelSize),
 		quit:          make(chan struct{}),
 		done:          make(chan struct{}),
 	}
 
+	d.blockPolicy = config.Daemon.BlockPolicy
+	if d.blockPolicy == nil {
+		if config.Daemon.EmptyBlockInterval > 0 && !v.Config.AllowEmptyBlocks {
+			return nil, errors.New("EmptyBlockInterval requires the blockchain to allow empty blocks")
+		}
+		d.blockPolicy = NewThresholdBlockPolicy(config.Daemon)
+	}
+
+	if len(v.Config.BlockPublisherPubkeys) > 0 {
+		d.consensus = newBlockConsensus(v.PublisherPubkeysAt, v.ConsensusPubkeysAt, v, v.Config.BlockchainSeckey, v.Config.IsBlockPublisher, d.broadcastBlockHeader)
+	} else if v.Config.IsBlockPublisher {
+		interval := time.Second * time.Duration(config.Daemon.BlockCreationInterval)
+		d.publisherLease = newPublisherLease(config.Daemon.PublisherStandby, interval, config.Daemon.PublisherLeaseIntervals)
+
+		c, err := loadPublisherConflict(config.Daemon.DataDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("load %s failed: %v", PublisherConflictFilename, err)
+		}
+		if c != nil {
+			logger.Critical().WithField("seq", c.Seq).Error("The block publisher lost a block conflict, standing by until our chain is synced again")
+			d.publisherLease.setConflict(*c)
+		}
+	}
+
 	d.pool, err = NewPool(config.Pool, d)
 	if err != nil {
 		return nil, err
@@ -372,6 +508,12 @@ type ConnectFailureEvent struct {
 	Error     error
 }
 
+// connectRequestEvent asks the daemon loop to make an outgoing connection, see ConnectToPeer
+type connectRequestEvent struct {
+	Addr   string
+	Result chan error
+}
+
 // messageEvent encapsulates a deserialized message from the network
 type messageEvent struct {
 	Message asyncMessage
@@ -436,7 +578,7 @@ func (dm *Daemon) Run() error {
 
 	blockInterval := time.Duration(dm.config.BlockCreationInterval)
 	blockCreationTicker := time.NewTicker(time.Second * blockInterval)
-	if !dm.visor.Config.IsBlockPublisher {
+	if !dm.visor.Config.IsBlockPublisher || dm.config.Regtest {
 		blockCreationTicker.Stop()
 	}
 
@@ -478,6 +620,20 @@ func (dm *Daemon) Run() error {
 	flushAnnouncedTxnsTicker := time.NewTicker(dm.config.FlushAnnouncedTxnsRate)
 	defer flushAnnouncedTxnsTicker.Stop()
 
+	stemEmbargoTicker := time.NewTicker(stemEmbargoCheckRate)
+	defer stemEmbargoTicker.Stop()
+
+	syncWatchdogTicker := time.NewTicker(syncWatchdogRate)
+	defer syncWatchdogTicker.Stop()
+
+	consensusTicker := time.NewTicker(consensusCheckRate)
+	defer consensusTicker.Stop()
+	if dm.consensus == nil {
+		consensusTicker.Stop()
+	} else {
+		logger.Infof("Block consensus is enabled with %d block publishers", len(dm.visor.Config.PublisherPubkeys()))
+	}
+
 	// Connect to all trusted peers on startup to try to ensure a connection establishes quickly.
 	// The number of connections to default peers is restricted;
 	// if multiple connections succeed, extra connections beyond the limit will be disconnected.
@@ -573,7 +729,12 @@ loop:
 			// Sends pings as needed
 			elapser.Register("idleCheckTicker")
 			if !dm.config.DisableNetworking {
-				dm.pool.sendPings()
+				// The ping time is recorded right before the ping is queued. Pongs are recorded
+				// by the gnet goroutine of the connection as soon as they are read, see PongMessage.Handle,
+				// so the time must be recorded before the ping can be written
+				dm.pool.sendPings(func(addr string) {
+					dm.connections.pingSent(addr, time.Now())
+				})
 			}
 
 		case <-outgoingConnectionsTicker.C:
@@ -623,13 +784,73 @@ loop:
 				logger.WithError(err).Error("Failed to set unconfirmed txn announce time")
 			}
 
+		case <-stemEmbargoTicker.C:
+			elapser.Register("stemEmbargoTicker")
+			if !dm.config.DisableNetworking {
+				dm.fluffExpiredStemTxns()
+			}
+
+		case <-syncWatchdogTicker.C:
+			elapser.Register("syncWatchdogTicker")
+			if !dm.config.DisableNetworking {
+				dm.checkSync()
+			}
+
+		case <-consensusTicker.C:
+			elapser.Register("consensusTicker")
+			if !dm.config.DisableNetworking {
+				dm.checkConsensus()
+			}
+
 		case <-blockCreationTicker.C:
 			// Create blocks, if block publisher
 			elapser.Register("blockCreationTicker.C")
-			if dm.visor.Config.IsBlockPublisher {
-				sb, err := dm.createAndPublishBlock()
+			if !dm.visor.Config.IsBlockPublisher {
+				continue
+			}
+
+			action, err := dm.checkBlockPolicy()
+			if err != nil {
+				logger.WithError(err).Error("checkBlockPolicy failed")
+				continue
+			}
+
+			if dm.consensus != nil {
+				if action == BlockActionNone {
+					continue
+				}
+
+				// Propose a block to the other block publishers instead of publishing it
+				sb, err := dm.proposeBlock(action == BlockActionEmpty)
+				if err != nil {
+					if err == visor.ErrPublisherKeyNotActive {
+						logger.WithError(err).Debug("Not proposing block")
+					} else if err.Error() != "No transactions" {
+						logger.WithError(err).Error("Failed to propose block")
+					}
+					continue
+				}
+				if sb != nil {
+					logger.WithFields(logrus.Fields{
+						"seq":  sb.Block.Head.BkSeq,
+						"time": sb.Block.Head.Time,
+					}).Info("Proposed a new block")
+				}
+			} else {
+				if !dm.checkPublisherLease(action != BlockActionNone) || action == BlockActionNone {
+					continue
+				}
+
+				var sb *coin.SignedBlock
+				if action == BlockActionEmpty {
+					sb, err = dm.createAndPublishEmptyBlock()
+				} else {
+					sb, err = dm.createAndPublishBlock()
+				}
 				if err != nil {
-					if(err.Error() != "No transactions") {
+					if err == visor.ErrPublisherKeyNotActive {
+						logger.WithError(err).Debug("Not creating block")
+					} else if err.Error() != "No transactions" {
 						logger.WithError(err).Error("Failed to create and publish block")
 					}
 					continue
@@ -705,7 +926,7 @@ loop:
 // the connectionErrors channel.
 func (dm *Daemon) connectToPeer(p pex.Peer) error {
 	if dm.config.DisableOutgoingConnections {
-		return errors.New("Outgoing connections disabled")
+		return ErrOutgoingConnectionsDisabled
 	}
 
 	a, _, err := iputil.SplitAddr(p.Addr)
@@ -718,12 +939,18 @@ func (dm *Daemon) connectToPeer(p pex.Peer) error {
 		return errors.New("Not localhost")
 	}
 
+	isOnion := iputil.IsOnion(a)
+	if isOnion && dm.pool.Config.Proxy == "" {
+		return errors.New("Onion peer requires a proxy")
+	}
+
 	if c := dm.connections.get(p.Addr); c != nil {
 		return errors.New("Already connected to this peer")
 	}
 
+	// Onion addresses do not reveal the peer's IP, so there is no base IP to limit
 	cnt := dm.connections.IPCount(a)
-	if !dm.config.LocalhostOnly && cnt != 0 {
+	if !dm.config.LocalhostOnly && !isOnion && cnt != 0 {
 		return errors.New("Already connected to a peer with this base IP")
 	}
 
@@ -815,22 +1042,41 @@ func (dm *Daemon) connectToRandomPeer() {
 	if dm.config.DisableOutgoingConnections {
 		return
 	}
-	if dm.connections.OutgoingLen() >= dm.config.MaxOutgoingConnections {
+	if dm.outgoingConnectionsFull() {
 		return
 	}
-	if dm.connections.PendingLen() >= dm.config.MaxPendingConnections {
-		return
-	}
-	if dm.connections.Len() >= dm.config.MaxConnections {
-		return
+
+	// Outgoing connections are made to distinct netgroups, so that an attacker controlling
+	// a few subnets can't take all of them
+	groups := make(map[string]struct{})
+	for _, c := range dm.connections.all() {
+		if c.Outgoing {
+			groups[pex.NetGroup(c.Addr)] = struct{}{}
+		}
 	}
 
-	// Make a connection to a random (public) peer
-	peers := dm.pex.RandomPublic(dm.config.MaxOutgoingConnections - dm.connections.OutgoingLen())
+	// Make a connection to a random (public) peer, preferring peers with a low RTT and a good record
+	// Only a few candidates are ranked per slot, since ranking the whole peerlist on every tick is expensive.
+	// Spare candidates cover peers that are skipped for sharing a netgroup or failing to connect.
+	n := dm.config.MaxOutgoingConnections - dm.connections.OutgoingLen()
+	peers := dm.pex.BestPublic(n * outgoingCandidatesPerSlot)
 	for _, p := range peers {
+		if n == 0 {
+			break
+		}
+
+		g := pex.NetGroup(p.Addr)
+		if _, ok := groups[g]; ok {
+			continue
+		}
+
 		if err := dm.connectToPeer(p); err != nil {
 			//logger.WithError(err).WithField("addr", p.Addr).Warning("connectToPeer failed")
+			continue
 		}
+
+		groups[g] = struct{}{}
+		n--
 	}
 
 	// TODO -- don't reset if not needed?
@@ -839,6 +1085,13 @@ func (dm *Daemon) connectToRandomPeer() {
 	}
 }
 
+// outgoingConnectionsFull returns true if no more outgoing connections can be made
+func (dm *Daemon) outgoingConnectionsFull() bool {
+	return dm.connections.OutgoingLen() >= dm.config.MaxOutgoingConnections ||
+		dm.connections.PendingLen() >= dm.config.MaxPendingConnections ||
+		dm.connections.Len() >= dm.config.MaxConnections
+}
+
 // Removes connections who haven't sent a version after connecting
 func (dm *Daemon) cullInvalidConnections() {
 	now := time.Now().UTC()
@@ -885,6 +1138,8 @@ func (dm *Daemon) handleEvent(e interface{}) {
 		dm.onDisconnectEvent(x)
 	case ConnectFailureEvent:
 		dm.onConnectFailure(x)
+	case connectRequestEvent:
+		x.Result <- dm.onConnectRequest(x.Addr)
 	default:
 		logger.WithFields(logrus.Fields{
 			"type":  fmt.Sprintf("%T", e),
@@ -977,6 +1232,7 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		dm.config.userAgent,
 		dm.config.UnconfirmedVerifyTxn,
 		dm.config.GenesisHash,
+		dm.services(),
 	)); err != nil {
 		logger.WithFields(fields).WithError(err).Error("Send IntroductionMessage failed")
 		return
@@ -991,6 +1247,8 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 	}
 	logger.WithFields(fields).Info("onDisconnectEvent")
 
+	dm.recordDisconnect(e)
+
 	if err := dm.connections.remove(e.Addr, e.GnetID); err != nil {
 		logger.WithError(err).WithFields(fields).Error("connections.Remove failed")
 		return
@@ -1017,6 +1275,34 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 	}
 }
 
+// recordDisconnect records the uptime and disconnect reason of an introduced connection in the peer's stats
+func (dm *Daemon) recordDisconnect(e DisconnectEvent) {
+	c := dm.connections.get(e.Addr)
+	if c == nil || c.gnetID != e.GnetID || !c.HasIntroduced() {
+		return
+	}
+
+	listenAddr := c.ListenAddr()
+	if listenAddr == "" {
+		return
+	}
+
+	dm.pex.RecordDisconnect(listenAddr, time.Since(c.ConnectedAt), disconnectReasonName(e.Reason))
+}
+
+// disconnectReasonName returns the name of a disconnect reason, for the peer's stats.
+// Read and write errors include the underlying error, which can contain addresses, so only their type is used
+func disconnectReasonName(r gnet.DisconnectReason) string {
+	switch r.(type) {
+	case gnet.ReadError, *gnet.ReadError:
+		return "read failed"
+	case gnet.WriteError, *gnet.WriteError:
+		return "write failed"
+	default:
+		return r.Error()
+	}
+}
+
 func (dm *Daemon) onConnectFailure(c ConnectFailureEvent) {
 	// Remove the pending connection from connections and update the retry times in pex
 	logger.WithField("addr", c.Addr).WithError(c.Error).Debug("onConnectFailure")
@@ -1063,7 +1349,7 @@ func (dm *Daemon) onGnetConnectFailure(addr string, solicited bool, err error) {
 }
 
 // Returns whether the ipCount maximum has been reached.
-// Always false when using LocalhostOnly config.
+// Always false when using LocalhostOnly config, or for onion addresses.
 func (dm *Daemon) ipCountMaxed(addr string) bool {
 	ip, _, err := iputil.SplitAddr(addr)
 	if err != nil {
@@ -1071,6 +1357,10 @@ func (dm *Daemon) ipCountMaxed(addr string) bool {
 		return true
 	}
 
+	if iputil.IsOnion(ip) {
+		return false
+	}
+
 	return !dm.config.LocalhostOnly && dm.connections.IPCount(ip) >= dm.config.IPCountsMax
 }
 
@@ -1120,11 +1410,16 @@ func (dm *Daemon) requestBlocks() error {
 
 	m := NewGetBlocksMessage(headSeq, dm.config.GetBlocksRequestCount)
 
-	if _, err := dm.broadcastMessage(m); err != nil {
+	gnetIDs, err := dm.broadcastMessage(m)
+	if err != nil {
 		logger.WithError(err).Debug("Broadcast GetBlocksMessage failed")
 		return err
 	}
 
+	for _, addr := range dm.connections.blocksRequested(gnetIDs, headSeq, time.Now()) {
+		dm.pex.RecordBlockRequest(addr)
+	}
+
 	return nil
 }
 
@@ -1160,20 +1455,41 @@ func (dm *Daemon) announceBlocks() error {
 // TODO -- refactor this method -- it should either always create a block and maybe broadcast it,
 // or use a database transaction to rollback block publishing if broadcast failed (however, this will cause a slow DB write)
 func (dm *Daemon) createAndPublishBlock() (*coin.SignedBlock, error) {
+	return dm.executeAndPublishBlock(dm.visor.CreateAndExecuteBlock)
+}
+
+// createAndPublishEmptyBlock creates a block with no transactions and sends it to the network.
+// See createAndPublishBlock
+func (dm *Daemon) createAndPublishEmptyBlock() (*coin.SignedBlock, error) {
+	return dm.executeAndPublishBlock(dm.visor.CreateAndExecuteEmptyBlock)
+}
+
+// executeAndPublishBlock creates and executes a block with createAndExecute and sends it to the network
+func (dm *Daemon) executeAndPublishBlock(createAndExecute func() (coin.SignedBlock, error)) (*coin.SignedBlock, error) {
 	if dm.config.DisableNetworking {
 		return nil, ErrNetworkingDisabled
 	}
 
-	sb, err := dm.visor.CreateAndExecuteBlock()
+	sb, err := createAndExecute()
 	if err != nil {
 		return nil, err
 	}
 
+	if dm.publisherLease != nil {
+		dm.publisherLease.setCreated(sb.Block.Head.BkSeq)
+	}
+
 	err = dm.broadcastBlock(sb)
 
 	return &sb, err
 }
 
+// CreateAndPublishBlock creates a block from unconfirmed transactions and sends it to the network.
+// Will panic if not running as a block publisher. See createAndPublishBlock
+func (dm *Daemon) CreateAndPublishBlock() (*coin.SignedBlock, error) {
+	return dm.createAndPublishBlock()
+}
+
 // ResendUnconfirmedTxns resends all unconfirmed transactions and returns the hashes that were successfully rebroadcast.
 // It does not return an error if broadcasting fails.
 func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
@@ -1221,8 +1537,18 @@ func (dm *Daemon) BroadcastTransaction(txn coin.Transaction) ([]uint64, error) {
 }
 
 // BroadcastUserTransaction broadcasts a single transaction to all peers.
+// If Dandelion relay is enabled, the transaction is sent to a single peer instead, see stemUserTransaction.
+// It is broadcast if no peer can relay it in the stem phase.
 // Returns an error if no peers that would propagate the transaction could be reached.
 func (dm *Daemon) BroadcastUserTransaction(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray) error {
+	if dm.config.Dandelion {
+		err := dm.stemUserTransaction(txn, head, inputs)
+		if err == nil {
+			return nil
+		}
+		logger.WithError(err).Info("stemUserTransaction failed, broadcasting the transaction")
+	}
+
 	ids, err := dm.BroadcastTransaction(txn)
 	if err != nil {
 		return err
@@ -1352,7 +1678,17 @@ func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 	}
 
 	m := NewGetBlocksMessage(headSeq, dm.config.GetBlocksRequestCount)
-	return dm.sendMessage(addr, m)
+	if err := dm.sendMessage(addr, m); err != nil {
+		return err
+	}
+
+	if c := dm.connections.get(addr); c != nil {
+		for _, a := range dm.connections.blocksRequested([]uint64{c.gnetID}, headSeq, time.Now()) {
+			dm.pex.RecordBlockRequest(a)
+		}
+	}
+
+	return nil
 }
 
 // broadcastBlock sends a signed block to all connections
@@ -1400,6 +1736,7 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 			logger.Critical().WithError(err).WithFields(fields).Error("pex.SetHasIncomingPort failed")
 			return nil, err
 		}
+		dm.pex.MarkTried(listenAddr)
 	} else {
 		// For successful incoming connections, add the peer to the peer list, with their self-reported listen port
 		if err := dm.pex.AddPeer(listenAddr); err != nil {
@@ -1415,9 +1752,37 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 
 	dm.pex.ResetRetryTimes(listenAddr)
 
+	if c.Services.Has(ServiceCompression) {
+		if err := dm.pool.Pool.EnableCompression(addr); err != nil {
+			logger.WithError(err).WithFields(fields).Error("pool.EnableCompression failed")
+			return nil, err
+		}
+	}
+
 	return c, nil
 }
 
+// services returns the optional protocol features advertised in the IntroductionMessage
+func (dm *Daemon) services() Services {
+	services := ServiceBloomFilter | ServiceIPv6Peers | ServiceDandelion | ServicePublisherKeys
+	if dm.pool.Pool.Config.CompressionThreshold > 0 {
+		services |= ServiceCompression
+	}
+	if dm.consensus != nil {
+		services |= ServiceConsensus
+	}
+	return services
+}
+
+// peerSupports returns true if an introduced peer advertised all of the services in s
+func (dm *Daemon) peerSupports(addr string, s Services) bool {
+	c := dm.connections.get(addr)
+	if c == nil || !c.HasIntroduced() {
+		return false
+	}
+	return c.Services.Has(s)
+}
+
 // sendRandomPeers sends a random sample of peers to another peer
 func (dm *Daemon) sendRandomPeers(addr string) error {
 	peers := dm.pex.RandomExchangeable(dm.pex.Config.ReplyCount)
@@ -1427,8 +1792,21 @@ func (dm *Daemon) sendRandomPeers(addr string) error {
 	}
 
 	m := NewGivePeersMessage(peers, dm.config.MaxOutgoingMessageLength)
+	if err := dm.sendMessage(addr, m); err != nil {
+		return err
+	}
+
+	// Older peers do not recognize GiveIPv6PeersMessage
+	if !dm.peerSupports(addr, ServiceIPv6Peers) {
+		return nil
+	}
+
+	m6 := NewGiveIPv6PeersMessage(peers, dm.config.MaxOutgoingMessageLength)
+	if len(m6.Peers) == 0 {
+		return nil
+	}
 
-	return dm.sendMessage(addr, m)
+	return dm.sendMessage(addr, m6)
 }
 
 // announceAllValidTxns broadcasts valid unconfirmed transactions
@@ -1446,12 +1824,15 @@ func (dm *Daemon) announceAllValidTxns() error {
 	return dm.announceTxnHashes(hashes)
 }
 
-// announceTxnHashes announces transaction hashes, splitting them into chunks if they exceed MaxTxnAnnounceNum
+// announceTxnHashes announces transaction hashes, splitting them into chunks if they exceed MaxTxnAnnounceNum.
+// Transactions in the Dandelion stem phase are not announced
 func (dm *Daemon) announceTxnHashes(hashes []cipher.SHA256) error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
 	}
 
+	hashes = dm.stemTxns.filter(hashes)
+
 	// Divide hashes into multiple sets of max size
 	hashesSet := divideHashes(hashes, dm.config.MaxTxnAnnounceNum)
 
@@ -1495,11 +1876,20 @@ func divideHashes(hashes []cipher.SHA256, n int) [][]cipher.SHA256 {
 }
 
 // sendMessage sends a Message to a Connection and pushes the result onto the SendResults channel.
+// If the connection has loaded a bloom filter, the message is filtered first, see filterMessage.
 func (dm *Daemon) sendMessage(addr string, msg gnet.Message) error {
+	if f := dm.connections.bloomFilter(addr); f != nil {
+		msg = dm.filterMessage(f, msg)
+		if msg == nil {
+			return nil
+		}
+	}
+
 	return dm.pool.Pool.SendMessage(addr, msg)
 }
 
 // broadcastMessage sends a Message to all introduced connections in the Pool.
+// Connections that have loaded a bloom filter are sent a filtered message, see filterMessage.
 // Returns the gnet IDs of connections that broadcast succeeded for.
 // Note that a connection could still fail to receive the message under certain network conditions,
 // there is no guarantee that a message was broadcast.
@@ -1510,13 +1900,34 @@ func (dm *Daemon) broadcastMessage(msg gnet.Message) ([]uint64, error) {
 
 	conns := dm.connections.all()
 	var addrs []string
+	var filtered []connection
 	for _, c := range conns {
-		if c.HasIntroduced() {
+		if !c.HasIntroduced() {
+			continue
+		}
+		if c.BloomFilter != nil {
+			filtered = append(filtered, c)
+		} else {
 			addrs = append(addrs, c.Addr)
 		}
 	}
 
-	return dm.pool.Pool.BroadcastMessage(msg, addrs)
+	// Connections with a bloom filter are sent a filtered message individually
+	var filteredIDs []uint64
+	for _, c := range filtered {
+		m := dm.filterMessage(c.BloomFilter, msg)
+		if m == nil {
+			continue
+		}
+		if err := dm.pool.Pool.SendMessage(c.Addr, m); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Debug("Send filtered message failed")
+			continue
+		}
+		filteredIDs = append(filteredIDs, c.gnetID)
+	}
+
+	ids, err := dm.pool.Pool.BroadcastMessage(msg, addrs)
+	return append(ids, filteredIDs...), err
 }
 
 // disconnectNow disconnects from a peer immediately without sending a DisconnectMessage. Any pending messages
@@ -1530,9 +1941,9 @@ func (dm *Daemon) pexConfig() pex.Config {
 	return dm.pex.Config
 }
 
-// addPeers adds peers to the pex
-func (dm *Daemon) addPeers(addrs []string) int {
-	return dm.pex.AddPeers(addrs)
+// addPeers adds peers sent by the peer at source to the pex
+func (dm *Daemon) addPeers(source string, addrs []string) int {
+	return dm.pex.AddPeersFrom(source, addrs)
 }
 
 // recordPeerHeight records the height of specific peer
@@ -1542,6 +1953,33 @@ func (dm *Daemon) recordPeerHeight(addr string, gnetID, height uint64) {
 	}
 }
 
+// recordBlocksReceived records that a peer answered a block request, if one was pending
+func (dm *Daemon) recordBlocksReceived(addr string, gnetID uint64) {
+	if listenAddr, ok := dm.connections.blocksReceived(addr, gnetID); ok && listenAddr != "" {
+		dm.pex.RecordBlockDelivery(listenAddr)
+	}
+}
+
+// recordPong records the RTT of a connection when it answers a ping
+func (dm *Daemon) recordPong(addr string, gnetID uint64) {
+	rtt, listenAddr, ok := dm.connections.pongReceived(addr, gnetID, time.Now())
+	if !ok {
+		return
+	}
+
+	if dm.config.LogPings {
+		logger.WithFields(logrus.Fields{
+			"addr":   addr,
+			"gnetID": gnetID,
+			"rtt":    rtt,
+		}).Debug("Recorded RTT")
+	}
+
+	if listenAddr != "" {
+		dm.pex.RecordRTT(listenAddr, rtt)
+	}
+}
+
 // getSignedBlocksSince returns N signed blocks since given seq
 func (dm *Daemon) getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, error) {
 	return dm.visor.GetSignedBlocksSince(seq, count)
@@ -1562,9 +2000,10 @@ func (dm *Daemon) filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.SHA256,
 	return dm.visor.FilterKnownUnconfirmed(txns)
 }
 
-// getKnownUnconfirmed returns unconfirmed txn hashes with known ones removed
+// getKnownUnconfirmed returns unconfirmed txn hashes with known ones removed.
+// Transactions in the Dandelion stem phase are withheld until they are broadcast
 func (dm *Daemon) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error) {
-	return dm.visor.GetKnownUnconfirmed(txns)
+	return dm.visor.GetKnownUnconfirmed(dm.stemTxns.filter(txns))
 }
 
 // injectTransaction records a coin.Transaction to the UnconfirmedTxnPool if the txn is not
@@ -1591,6 +2030,7 @@ type GnetConnectionDetails struct {
 	ID           uint64
 	LastSent     time.Time
 	LastReceived time.Time
+	Stats        gnet.ConnectionStats
 }
 
 func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection {
@@ -1606,6 +2046,7 @@ func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection
 			ID:           gc.ID,
 			LastSent:     gc.LastSent,
 			LastReceived: gc.LastReceived,
+			Stats:        gc.Stats,
 		}
 	}
 
@@ -1673,6 +2114,16 @@ func (dm *Daemon) GetConnections(f func(c Connection) bool) ([]Connection, error
 	return conns, nil
 }
 
+// GetNetworkStats returns the bytes and messages sent and received by all connections,
+// including connections that have been closed
+func (dm *Daemon) GetNetworkStats() (gnet.ConnectionStats, error) {
+	if dm.pool.Pool == nil {
+		return gnet.ConnectionStats{}, nil
+	}
+
+	return dm.pool.Pool.GetStats()
+}
+
 // GetDefaultConnections returns the default hardcoded connection addresses
 func (dm *Daemon) GetDefaultConnections() []string {
 	conns := make([]string, len(dm.config.DefaultConnections))
@@ -1710,6 +2161,107 @@ func (dm *Daemon) GetExchgConnection() []string {
 	return dm.pex.RandomExchangeable(0).ToAddrs()
 }
 
+// GetPeers returns all peers in the peer list
+func (dm *Daemon) GetPeers() []pex.Peer {
+	return dm.pex.All()
+}
+
+// SetPeer adds a peer to the peer list and sets its trusted and private flags.
+// If the peer is already in the peer list, only its flags are updated.
+// Trusted and private peers are connected to by the daemon's run loop.
+// Both flags are kept across restarts
+func (dm *Daemon) SetPeer(addr string, trusted, private bool) (*pex.Peer, error) {
+	a, err := dm.pex.CleanAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dm.pex.AddPeer(a); err != nil {
+		return nil, err
+	}
+
+	if err := dm.pex.SetUserTrusted(a, trusted); err != nil {
+		return nil, err
+	}
+
+	if err := dm.pex.SetPrivate(a, private); err != nil {
+		return nil, err
+	}
+
+	p, ok := dm.pex.GetPeer(a)
+	if !ok {
+		// The peer was removed by the daemon in the meantime
+		return nil, pex.ErrPeerNotFound
+	}
+
+	return &p, nil
+}
+
+// RemovePeer removes a peer from the peer list. The connection to the peer, if any, is not closed
+func (dm *Daemon) RemovePeer(addr string) error {
+	a, err := dm.pex.CleanAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := dm.pex.GetPeer(a); !ok {
+		return pex.ErrPeerNotFound
+	}
+
+	dm.pex.RemovePeer(a)
+	return nil
+}
+
+// ConnectToPeer makes an outgoing connection to a peer right away.
+// The peer is added to the peer list if it is not in it already.
+// The connection is subject to the same limits as the connections that the daemon makes itself.
+// The connection is made in the background, see GetConnection for its state
+func (dm *Daemon) ConnectToPeer(addr string) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	a, err := dm.pex.CleanAddr(addr)
+	if err != nil {
+		return err
+	}
+
+	// The connection state is owned by the daemon loop, so the connection is made from there
+	req := connectRequestEvent{
+		Addr:   a,
+		Result: make(chan error, 1),
+	}
+
+	select {
+	case dm.events <- req:
+	case <-dm.quit:
+		return ErrNetworkingDisabled
+	}
+
+	select {
+	case err := <-req.Result:
+		return err
+	case <-dm.quit:
+		return ErrNetworkingDisabled
+	}
+}
+
+// onConnectRequest makes an outgoing connection requested by ConnectToPeer
+func (dm *Daemon) onConnectRequest(addr string) error {
+	if dm.config.DisableOutgoingConnections {
+		return ErrOutgoingConnectionsDisabled
+	}
+	if dm.outgoingConnectionsFull() {
+		return ErrOutgoingConnectionsFull
+	}
+
+	if err := dm.pex.AddPeer(addr); err != nil && err != pex.ErrPeerlistFull {
+		return err
+	}
+
+	return dm.connectToPeer(pex.Peer{Addr: addr})
+}
+
 /* Peer Blockchain Status API */
 
 // BlockchainProgress is the current blockchain syncing status
@@ -1786,6 +2338,11 @@ func (dm *Daemon) InjectBroadcastTransaction(txn coin.Transaction) error {
 			return err
 		}
 
+		// There are no peers on a regtest chain, the transaction waits for MineBlocks
+		if dm.config.Regtest {
+			return nil
+		}
+
 		if err := dm.BroadcastUserTransaction(txn, head, inputs); err != nil {
 			logger.WithError(err).Error("BroadcastUserTransaction failed")
 			return err
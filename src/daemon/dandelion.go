@@ -0,0 +1,213 @@
+package daemon
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"../../src/cipher"
+	"../../src/coin"
+)
+
+// Dandelion relay hides the origin of transactions created by this node.
+// A new transaction is not broadcast right away. It is first sent in a StemTxnMessage to a
+// single random peer (the "stem" phase), which passes it on to another random peer, and so on,
+// until one of them broadcasts it with an AnnounceTxnsMessage (the "fluff" phase).
+// Each node on the stem broadcasts the transaction itself if it does not see it broadcast
+// before its embargo expires, so that a peer that drops the transaction can't stop it.
+// Until then, the transaction is not announced or given to peers that ask for it.
+
+// stemEmbargoCheckRate is how often the embargo of stem transactions is checked
+const stemEmbargoCheckRate = time.Second
+
+// stemTxnPool holds the hashes of the transactions in the stem phase, with their embargo
+type stemTxnPool struct {
+	sync.Mutex
+	txns map[cipher.SHA256]time.Time
+}
+
+func newStemTxnPool() *stemTxnPool {
+	return &stemTxnPool{
+		txns: make(map[cipher.SHA256]time.Time),
+	}
+}
+
+func (p *stemTxnPool) add(txid cipher.SHA256, embargo time.Time) {
+	p.Lock()
+	defer p.Unlock()
+	p.txns[txid] = embargo
+}
+
+func (p *stemTxnPool) has(txid cipher.SHA256) bool {
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.txns[txid]
+	return ok
+}
+
+// remove removes transactions, returning the hashes of those that were in the pool
+func (p *stemTxnPool) remove(txids []cipher.SHA256) []cipher.SHA256 {
+	p.Lock()
+	defer p.Unlock()
+
+	var removed []cipher.SHA256
+	for _, txid := range txids {
+		if _, ok := p.txns[txid]; ok {
+			delete(p.txns, txid)
+			removed = append(removed, txid)
+		}
+	}
+	return removed
+}
+
+// removeExpired removes the transactions whose embargo has expired, and returns their hashes
+func (p *stemTxnPool) removeExpired(now time.Time) []cipher.SHA256 {
+	p.Lock()
+	defer p.Unlock()
+
+	var expired []cipher.SHA256
+	for txid, embargo := range p.txns {
+		if !now.Before(embargo) {
+			delete(p.txns, txid)
+			expired = append(expired, txid)
+		}
+	}
+	return expired
+}
+
+// filter returns the hashes that are not in the pool
+func (p *stemTxnPool) filter(txids []cipher.SHA256) []cipher.SHA256 {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(p.txns) == 0 {
+		return txids
+	}
+
+	filtered := make([]cipher.SHA256, 0, len(txids))
+	for _, txid := range txids {
+		if _, ok := p.txns[txid]; !ok {
+			filtered = append(filtered, txid)
+		}
+	}
+	return filtered
+}
+
+// stemPeers returns the connections that a stem transaction can be sent to, in random order.
+// Only outgoing connections are used, so that an attacker can't become our stem peer by connecting to us
+func (dm *Daemon) stemPeers(exclude string) []connection {
+	var peers []connection
+	for _, c := range dm.connections.all() {
+		if c.Addr == exclude || !c.Outgoing || !c.HasIntroduced() || c.BloomFilter != nil {
+			continue
+		}
+		if !c.Services.Has(ServiceDandelion) {
+			continue
+		}
+		peers = append(peers, c)
+	}
+
+	rand.Shuffle(len(peers), func(i, j int) {
+		peers[i], peers[j] = peers[j], peers[i]
+	})
+
+	return peers
+}
+
+// sendStemTxn sends a transaction to a peer in a StemTxnMessage, and starts its embargo.
+// A random delay of up to DandelionEmbargo is added to the embargo, so that the nodes
+// on the stem do not all broadcast the transaction at the same time
+func (dm *Daemon) sendStemTxn(addr string, txn coin.Transaction) error {
+	if err := dm.sendMessage(addr, NewStemTxnMessage(txn)); err != nil {
+		return err
+	}
+
+	embargo := dm.config.DandelionEmbargo + time.Duration(rand.Int63n(int64(dm.config.DandelionEmbargo)+1))
+	dm.stemTxns.add(txn.Hash(), time.Now().Add(embargo))
+
+	return nil
+}
+
+// stemUserTransaction starts the stem phase of a transaction created by this node.
+// The transaction is sent to a random peer that would accept it, see checkBroadcastTxnRecipients
+func (dm *Daemon) stemUserTransaction(txn coin.Transaction, head *coin.SignedBlock, inputs coin.UxArray) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	for _, c := range dm.stemPeers("") {
+		if _, err := checkBroadcastTxnRecipients(dm.connections, []uint64{c.gnetID}, txn, head, inputs); err != nil {
+			continue
+		}
+
+		if err := dm.sendStemTxn(c.Addr, txn); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Warning("Send StemTxnMessage failed")
+			continue
+		}
+
+		logger.WithField("txid", txn.Hash().Hex()).Debug("Sent transaction in the stem phase")
+		return nil
+	}
+
+	return ErrNoStemPeer
+}
+
+// relayStemTxn passes on a transaction received in a StemTxnMessage, or broadcasts it.
+// known is true if the transaction was already in the unconfirmed pool
+func (dm *Daemon) relayStemTxn(addr string, txn coin.Transaction, known bool) {
+	txid := txn.Hash()
+	fields := logrus.Fields{
+		"addr": addr,
+		"txid": txid.Hex(),
+	}
+
+	if known {
+		// A transaction that comes back along the stem is in a loop, so the stem ends here.
+		// Transactions that were already broadcast are ignored
+		if len(dm.stemTxns.remove([]cipher.SHA256{txid})) != 0 {
+			logger.WithFields(fields).Debug("Stem transaction looped, broadcasting it")
+			dm.fluffTxns([]cipher.SHA256{txid})
+		}
+		return
+	}
+
+	if rand.Float64() >= dm.config.DandelionFluffProbability {
+		for _, c := range dm.stemPeers(addr) {
+			if err := dm.sendStemTxn(c.Addr, txn); err != nil {
+				logger.WithError(err).WithField("addr", c.Addr).Warning("Send StemTxnMessage failed")
+				continue
+			}
+			return
+		}
+	}
+
+	logger.WithFields(fields).Debug("Stem transaction reached its fluff phase, broadcasting it")
+	dm.fluffTxns([]cipher.SHA256{txid})
+}
+
+// stemTxnsFluffed ends the stem phase of transactions that a peer has broadcast
+func (dm *Daemon) stemTxnsFluffed(txids []cipher.SHA256) {
+	if removed := dm.stemTxns.remove(txids); len(removed) != 0 {
+		logger.Debugf("%d stem transactions were broadcast by peers", len(removed))
+	}
+}
+
+// fluffExpiredStemTxns broadcasts the stem transactions whose embargo has expired
+func (dm *Daemon) fluffExpiredStemTxns() {
+	expired := dm.stemTxns.removeExpired(time.Now())
+	if len(expired) == 0 {
+		return
+	}
+
+	logger.Infof("Embargo of %d stem transactions expired, broadcasting them", len(expired))
+	dm.fluffTxns(expired)
+}
+
+// fluffTxns announces transactions that have left the stem phase
+func (dm *Daemon) fluffTxns(txids []cipher.SHA256) {
+	if err := dm.announceTxnHashes(txids); err != nil {
+		logger.WithError(err).Warning("announceTxnHashes failed")
+	}
+}
@@ -47,6 +47,10 @@ var (
 	ErrDisconnectInvalidMaxTransactionSize gnet.DisconnectReason = errors.New("Invalid max transaction size in introduction message")
 	// ErrDisconnectInvalidMaxDropletPrecision invalid max droplet precision in introduction message
 	ErrDisconnectInvalidMaxDropletPrecision gnet.DisconnectReason = errors.New("Invalid max droplet precision in introduction message")
+	// ErrDisconnectInvalidBloomFilter the peer sent an invalid bloom filter, or added to a filter it did not load
+	ErrDisconnectInvalidBloomFilter gnet.DisconnectReason = errors.New("Invalid bloom filter")
+	// ErrDisconnectNoBlocksServed the peer did not answer a request for the blocks that it advertises
+	ErrDisconnectNoBlocksServed gnet.DisconnectReason = errors.New("Did not serve advertised blocks")
 
 	// ErrDisconnectUnknownReason used when mapping an unknown reason code to an error. Is not sent over the network.
 	ErrDisconnectUnknownReason gnet.DisconnectReason = errors.New("Unknown DisconnectReason")
@@ -73,17 +77,20 @@ var (
 		ErrDisconnectInvalidBurnFactor:             17,
 		ErrDisconnectInvalidMaxTransactionSize:     18,
 		ErrDisconnectInvalidMaxDropletPrecision:    19,
+		ErrDisconnectInvalidBloomFilter:            20,
+		ErrDisconnectNoBlocksServed:                21,
 
 		// gnet codes are registered here, but they are not sent in a DISC
 		// message by gnet. Only daemon sends a DISC packet.
 		// If gnet chooses to disconnect it will not send a DISC packet.
-		gnet.ErrDisconnectSetReadDeadlineFailed:  1001,
-		gnet.ErrDisconnectInvalidMessageLength:   1002,
-		gnet.ErrDisconnectMalformedMessage:       1003,
-		gnet.ErrDisconnectUnknownMessage:         1004,
-		gnet.ErrDisconnectShutdown:               1005,
-		gnet.ErrDisconnectMessageDecodeUnderflow: 1006,
-		gnet.ErrDisconnectTruncatedMessageID:     1007,
+		gnet.ErrDisconnectSetReadDeadlineFailed:      1001,
+		gnet.ErrDisconnectInvalidMessageLength:       1002,
+		gnet.ErrDisconnectMalformedMessage:           1003,
+		gnet.ErrDisconnectUnknownMessage:             1004,
+		gnet.ErrDisconnectShutdown:                   1005,
+		gnet.ErrDisconnectMessageDecodeUnderflow:     1006,
+		gnet.ErrDisconnectTruncatedMessageID:         1007,
+		gnet.ErrDisconnectMalformedCompressedMessage: 1008,
 	}
 
 	disconnectCodeReasons map[uint16]gnet.DisconnectReason
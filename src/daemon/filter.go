@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"../../src/cipher"
+	"../../src/coin"
+	"../../src/daemon/bloom"
+	"../../src/daemon/gnet"
+)
+
+// setBloomFilter sets the bloom filter of a connection. Set to nil to clear the filter
+func (dm *Daemon) setBloomFilter(addr string, gnetID uint64, f *bloom.Filter) error {
+	return dm.connections.SetBloomFilter(addr, gnetID, f)
+}
+
+// addToBloomFilter adds an element to the bloom filter of a connection.
+// Returns ErrNoBloomFilter if the connection has not loaded a filter
+func (dm *Daemon) addToBloomFilter(addr string, gnetID uint64, element []byte) error {
+	c := dm.connections.get(addr)
+	if c == nil {
+		return ErrConnectionNotExist
+	}
+	if c.gnetID != gnetID {
+		return ErrConnectionGnetIDMismatch
+	}
+
+	f := dm.connections.bloomFilter(addr)
+	if f == nil {
+		return ErrNoBloomFilter
+	}
+
+	f.Add(element)
+	return nil
+}
+
+// filterMessage returns the message to send to a connection that has loaded a bloom filter.
+// GiveBlocksMessage is replaced by a FilteredBlocksMessage, and transactions that do not match
+// the filter are removed from GiveTxnsMessage and AnnounceTxnsMessage.
+// Returns nil if there is nothing left to send. Other messages are returned unchanged.
+func (dm *Daemon) filterMessage(f *bloom.Filter, msg gnet.Message) gnet.Message {
+	switch m := msg.(type) {
+	case *GiveBlocksMessage:
+		blocks := make([]FilteredBlock, len(m.Blocks))
+		for i := range m.Blocks {
+			blocks[i] = filterBlock(f, m.Blocks[i])
+		}
+
+		fm := NewFilteredBlocksMessage(blocks, dm.config.MaxOutgoingMessageLength)
+		if len(fm.Blocks) != len(blocks) {
+			logger.Warningf("NewFilteredBlocksMessage truncated %d blocks to %d blocks", len(blocks), len(fm.Blocks))
+		}
+		return fm
+
+	case *GiveTxnsMessage:
+		var txns []coin.Transaction
+		for i := range m.Transactions {
+			txnHash := m.Transactions[i].Hash()
+			if matchTransaction(f, &m.Transactions[i], txnHash, txnHash) {
+				txns = append(txns, m.Transactions[i])
+			}
+		}
+
+		if len(txns) == 0 {
+			return nil
+		}
+		return &GiveTxnsMessage{
+			Transactions: txns,
+		}
+
+	case *AnnounceTxnsMessage:
+		txns, err := dm.getKnownUnconfirmed(m.Transactions)
+		if err != nil {
+			logger.WithError(err).Error("filterMessage: getKnownUnconfirmed failed")
+			return nil
+		}
+
+		var hashes []cipher.SHA256
+		for i := range txns {
+			txnHash := txns[i].Hash()
+			if matchTransaction(f, &txns[i], txnHash, txnHash) {
+				hashes = append(hashes, txnHash)
+			}
+		}
+
+		if len(hashes) == 0 {
+			return nil
+		}
+		return &AnnounceTxnsMessage{
+			Transactions: hashes,
+		}
+
+	default:
+		return msg
+	}
+}
+
+// filterBlock returns the transactions of a block that match the filter, with their merkle branches
+func filterBlock(f *bloom.Filter, b coin.SignedBlock) FilteredBlock {
+	fb := FilteredBlock{
+		Head: b.Head,
+		Sig:  b.Sig,
+	}
+
+	txns := b.Body.Transactions
+	hashes := make([]cipher.SHA256, len(txns))
+	for i := range txns {
+		hashes[i] = txns[i].Hash()
+	}
+
+	for i := range txns {
+		// The genesis block uses the null hash as the SrcTransaction of its outputs, see coin.CreateUnspents
+		var srcTxn cipher.SHA256
+		if b.Head.BkSeq != 0 {
+			srcTxn = hashes[i]
+		}
+
+		if matchTransaction(f, &txns[i], hashes[i], srcTxn) {
+			fb.Transactions = append(fb.Transactions, FilteredTransaction{
+				Transaction: txns[i],
+				Index:       uint32(i),
+				Branch:      cipher.MerkleBranch(hashes, i),
+			})
+		}
+	}
+
+	return fb
+}
+
+// matchTransaction returns true if the filter matches the transaction's hash, the ID of one of its inputs,
+// or the address of one of its outputs. If an output's address matches and the filter's flags
+// are bloom.UpdateOutputs, the output's ID is added to the filter, so that the transaction spending it matches too.
+// srcTxn is the SrcTransaction of the transaction's outputs
+func matchTransaction(f *bloom.Filter, txn *coin.Transaction, txnHash, srcTxn cipher.SHA256) bool {
+	matched := f.Matches(txnHash[:])
+
+	for _, o := range txn.Out {
+		if !f.Matches(o.Address.Bytes()) {
+			continue
+		}
+
+		matched = true
+		if f.Flags() == bloom.UpdateOutputs {
+			ux := coin.UxBody{
+				SrcTransaction: srcTxn,
+				Address:        o.Address,
+				Coins:          o.Coins,
+				Hours:          o.Hours,
+			}
+			uxID := ux.Hash()
+			f.Add(uxID[:])
+		}
+	}
+
+	if matched {
+		return true
+	}
+
+	for _, in := range txn.In {
+		if f.Matches(in[:]) {
+			return true
+		}
+	}
+
+	return false
+}
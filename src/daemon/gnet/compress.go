@@ -0,0 +1,85 @@
+package gnet
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"../../../src/cipher/encoder"
+)
+
+// Compressed messages are sent in an envelope with the message ID compressedMessageID.
+// The envelope's body is the message ID of the wrapped message, followed by the
+// flate compressed body of the wrapped message:
+//
+//	[length prefix uint32]["CMPR"][wrapped message ID][flate compressed body]
+//
+// A connection only sends compressed messages after EnableCompression is called for it,
+// which should only be done once the peer has indicated that it accepts them.
+
+// compressedMessageID is the message ID of the compressed message envelope
+var compressedMessageID = MessagePrefixFromString("CMPR")
+
+// compressMessage wraps an encoded message, including its length prefix, in a compressed envelope.
+// If compression does not make the message smaller, the original message is returned.
+func compressMessage(m []byte) ([]byte, error) {
+	if len(m) < messageLengthPrefixSize+messagePrefixLength {
+		return nil, errors.New("compressMessage: message is too short")
+	}
+
+	var buf bytes.Buffer
+	// Reserve space for the length prefix and the envelope and wrapped message IDs
+	buf.Write(make([]byte, messageLengthPrefixSize))
+	buf.Write(compressedMessageID[:])
+	buf.Write(m[messageLengthPrefixSize : messageLengthPrefixSize+messagePrefixLength])
+
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(m[messageLengthPrefixSize+messagePrefixLength:]); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() >= len(m) {
+		return m, nil
+	}
+
+	c := buf.Bytes()
+	copy(c[:messageLengthPrefixSize], encoder.SerializeUint32(uint32(len(c)-messageLengthPrefixSize)))
+
+	return c, nil
+}
+
+// decompressMessage unwraps the body of a compressed envelope, returning the wrapped message ID and body.
+// The decompressed message may be at most maxMsgLength bytes long, including its message ID.
+func decompressMessage(body []byte, maxMsgLength int) ([]byte, error) {
+	if len(body) < messagePrefixLength {
+		return nil, ErrDisconnectMalformedCompressedMessage
+	}
+
+	r := flate.NewReader(bytes.NewReader(body[messagePrefixLength:]))
+	defer r.Close()
+
+	// Read one byte past the limit to detect messages that are too long
+	limit := int64(maxMsgLength - messagePrefixLength + 1)
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit))
+	if err != nil {
+		return nil, ErrDisconnectMalformedCompressedMessage
+	}
+
+	if int64(len(data)) >= limit {
+		return nil, ErrDisconnectInvalidMessageLength
+	}
+
+	msg := make([]byte, messagePrefixLength+len(data))
+	copy(msg[:messagePrefixLength], body[:messagePrefixLength])
+	copy(msg[messagePrefixLength:], data)
+
+	return msg, nil
+}
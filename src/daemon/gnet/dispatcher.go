@@ -34,16 +34,26 @@ func newSendResult(addr string, m Message, err error) SendResult {
 	}
 }
 
-// Serializes a Message over a net.Conn
-func sendMessage(conn net.Conn, msg Message, timeout time.Duration, maxMsgLength int) error {
+// Serializes a Message over a net.Conn. Returns the number of bytes sent.
+// Messages longer than compressionThreshold are compressed. Set compressionThreshold to 0 to never compress.
+func sendMessage(conn net.Conn, msg Message, timeout time.Duration, maxMsgLength, compressionThreshold int) (int, error) {
 	m, err := EncodeMessage(msg)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(m) > maxMsgLength {
-		return ErrMsgExceedsMaxLen
+		return 0, ErrMsgExceedsMaxLen
+	}
+	if compressionThreshold > 0 && len(m) > compressionThreshold {
+		m, err = compressMessage(m)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := sendByteMessage(conn, m, timeout); err != nil {
+		return 0, err
 	}
-	return sendByteMessage(conn, m, timeout)
+	return len(m), nil
 }
 
 // msgIDStringSafe formats msgID bytes to a string that is safe for logging (e.g. not impacted by ascii control chars)
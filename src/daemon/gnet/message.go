@@ -71,6 +71,9 @@ func RegisterMessage(prefix MessagePrefix, msg interface{}) {
 	t := reflect.TypeOf(msg)
 	id := MessagePrefix{}
 	copy(id[:], prefix[:])
+	if id == compressedMessageID {
+		logger.Panicf("Message prefix %s is reserved for compressed messages", string(id[:]))
+	}
 	_, exists := MessageIDReverseMap[id]
 	if exists {
 		logger.Panicf("Attempted to register message prefix %s twice", string(id[:]))
@@ -85,6 +88,12 @@ func RegisterMessage(prefix MessagePrefix, msg interface{}) {
 	registeredMsgsCount++
 }
 
+// IsMessageRegistered returns true if the message type is registered with the prefix
+func IsMessageRegistered(prefix MessagePrefix, msg interface{}) bool {
+	t, ok := MessageIDReverseMap[prefix]
+	return ok && t == reflect.TypeOf(msg)
+}
+
 // VerifyMessages calls logger.Panic if message registration violates sanity checks
 func VerifyMessages() {
 	if registeredMsgsCount != len(MessageIDMap) {
@@ -11,6 +11,7 @@ import (
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"io"
@@ -20,6 +21,7 @@ import (
 	"../../../src/cipher/encoder"
 	"../../../src/daemon/strand"
 	"../../../src/util/elapse"
+	"../../../src/util/iputil"
 	"../../../src/util/logging"
 )
 
@@ -48,6 +50,8 @@ var (
 	ErrDisconnectMessageDecodeUnderflow DisconnectReason = errors.New("Message data did not fully decode to a message object")
 	// ErrDisconnectTruncatedMessageID message data was too short to contain a message ID
 	ErrDisconnectTruncatedMessageID DisconnectReason = errors.New("Message data was too short to contain a message ID")
+	// ErrDisconnectMalformedCompressedMessage compressed message could not be decompressed
+	ErrDisconnectMalformedCompressedMessage DisconnectReason = errors.New("Malformed compressed message")
 
 	// ErrConnectionPoolClosed error message indicates the connection pool is closed
 	ErrConnectionPoolClosed = errors.New("Connection pool is closed")
@@ -111,6 +115,12 @@ type Config struct {
 	// Timeout is the timeout for dialing new connections.  Use a
 	// timeout of 0 to ignore timeout.
 	DialTimeout time.Duration
+	// Dial outgoing connections through this proxy, e.g. socks5://127.0.0.1:9050.
+	// Leave empty to dial directly. Onion addresses can only be dialed through a proxy.
+	// Ignored if Transport is set
+	Proxy string
+	// Transport used to listen and dial. Leave nil to use TCP
+	Transport Transport
 	// Timeout for reading from a connection. Set to 0 to default to the
 	// system's timeout
 	ReadTimeout time.Duration
@@ -122,6 +132,17 @@ type Config struct {
 	// Individual connections' send queue size.  This should be increased
 	// if send volume per connection is high, so as not to block
 	ConnectionWriteQueueSize int
+	// Maximum upload rate of all connections combined, in bytes per second. Set to 0 for no limit
+	MaxUploadRate int
+	// Maximum download rate of all connections combined, in bytes per second. Set to 0 for no limit
+	MaxDownloadRate int
+	// Maximum upload rate of each connection, in bytes per second. Set to 0 for no limit
+	MaxConnectionUploadRate int
+	// Maximum download rate of each connection, in bytes per second. Set to 0 for no limit
+	MaxConnectionDownloadRate int
+	// Messages longer than this are compressed, if compression is enabled for the connection.
+	// Set to 0 to never compress
+	CompressionThreshold int
 	// Triggered on client disconnect
 	DisconnectCallback DisconnectCallback
 	// Triggered on client connect
@@ -150,6 +171,7 @@ func NewConfig() Config {
 		WriteTimeout:                      time.Second * 30,
 		SendResultsSize:                   2048,
 		ConnectionWriteQueueSize:          128,
+		CompressionThreshold:              1024,
 		DisconnectCallback:                nil,
 		ConnectCallback:                   nil,
 		DebugPrint:                        false,
@@ -179,19 +201,29 @@ type Connection struct {
 	// Message send queue.
 	WriteQueue chan Message
 	Solicited  bool
+	// Bytes and messages sent and received
+	Stats ConnectionStats
+	// Set to 1 if the peer accepts compressed messages. Accessed atomically, since it is read by the sendLoop
+	compress int32
+	// Rate limiters of this connection, applied along with the ConnectionPool's. nil if not limited
+	uploadLimiter   *rateLimiter
+	downloadLimiter *rateLimiter
 }
 
 // NewConnection creates a new Connection tied to a ConnectionPool
 func NewConnection(pool *ConnectionPool, id uint64, conn net.Conn, writeQueueSize int, solicited bool) *Connection {
 	return &Connection{
-		ID:             id,
-		Conn:           conn,
-		Buffer:         &bytes.Buffer{},
-		ConnectionPool: pool,
-		LastReceived:   Now(),
-		LastSent:       Now(),
-		WriteQueue:     make(chan Message, writeQueueSize),
-		Solicited:      solicited,
+		ID:              id,
+		Conn:            conn,
+		Buffer:          &bytes.Buffer{},
+		ConnectionPool:  pool,
+		LastReceived:    Now(),
+		LastSent:        Now(),
+		WriteQueue:      make(chan Message, writeQueueSize),
+		Solicited:       solicited,
+		Stats:           newConnectionStats(),
+		uploadLimiter:   newRateLimiter(pool.Config.MaxConnectionUploadRate),
+		downloadLimiter: newRateLimiter(pool.Config.MaxConnectionDownloadRate),
 	}
 }
 
@@ -200,6 +232,11 @@ func (conn *Connection) Addr() string {
 	return conn.Conn.RemoteAddr().String()
 }
 
+// CompressionEnabled returns true if compressed messages may be sent to the connection
+func (conn *Connection) CompressionEnabled() bool {
+	return atomic.LoadInt32(&conn.compress) == 1
+}
+
 // String returns connection address
 func (conn *Connection) String() string {
 	return conn.Addr()
@@ -243,6 +280,13 @@ type ConnectionPool struct {
 	// Listening connection
 	listener     net.Listener
 	listenerLock sync.Mutex
+	// Dialer for outgoing connections when a proxy is configured
+	proxy *socks5Dialer
+	// Bytes and messages sent and received by all connections, including closed connections
+	stats ConnectionStats
+	// Rate limiters shared by all connections. nil if not limited
+	uploadLimiter   *rateLimiter
+	downloadLimiter *rateLimiter
 	// operations channel
 	reqC chan strand.Request
 	// quit channel
@@ -264,6 +308,19 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		return nil, errors.New("MaxConnections must be >= MaxOutgoingConnections + MaxDefaultPeerOutgoingConnections")
 	}
 
+	var proxy *socks5Dialer
+	if c.Transport == nil {
+		c.Transport = tcpTransport{}
+
+		if c.Proxy != "" {
+			var err error
+			proxy, err = newSOCKS5Dialer(c.Proxy, c.DialTimeout)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	return &ConnectionPool{
 		Config:                     c,
 		pool:                       make(map[uint64]*Connection),
@@ -276,6 +333,10 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		done:                       make(chan struct{}),
 		strandDone:                 make(chan struct{}),
 		reqC:                       make(chan strand.Request),
+		proxy:                      proxy,
+		stats:                      newConnectionStats(),
+		uploadLimiter:              newRateLimiter(c.MaxUploadRate),
+		downloadLimiter:            newRateLimiter(c.MaxDownloadRate),
 	}, nil
 }
 
@@ -293,10 +354,10 @@ func (pool *ConnectionPool) Run() error {
 	}()
 
 	// start the connection accept loop
-	addr := fmt.Sprintf("%s:%v", pool.Config.Address, pool.Config.Port)
+	addr := iputil.JoinAddr(pool.Config.Address, pool.Config.Port)
 	logger.Infof("Listening for connections on %s...", addr)
 
-	ln, err := net.Listen("tcp", addr)
+	ln, err := pool.Config.Transport.Listen(addr)
 	if err != nil {
 		return err
 	}
@@ -622,6 +683,10 @@ func (pool *ConnectionPool) readLoop(conn *Connection, msgChan chan []byte, qc c
 			continue
 		}
 
+		if !waitRateLimits(len(data), pool.quit, qc, conn.downloadLimiter, pool.downloadLimiter) {
+			return nil
+		}
+
 		// write data to buffer
 		if _, err := conn.Buffer.Write(data); err != nil {
 			return err
@@ -665,13 +730,18 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, ma
 				continue
 			}
 
-			err := sendMessage(conn.Conn, m, timeout, maxMsgLength)
+			compressionThreshold := 0
+			if conn.CompressionEnabled() {
+				compressionThreshold = pool.Config.CompressionThreshold
+			}
+
+			n, err := sendMessage(conn.Conn, m, timeout, maxMsgLength, compressionThreshold)
 
 			// Update last sent before writing to SendResult,
 			// this allows a write to SendResult to be used as a sync marker,
 			// since no further action in this block will happen after the write.
 			if err == nil {
-				if err := pool.updateLastSent(conn.Addr(), Now()); err != nil {
+				if err := pool.updateLastSent(conn.Addr(), messageType(m), n, Now()); err != nil {
 					logger.WithField("addr", conn.Addr()).WithError(err).Warning("updateLastSent failed")
 				}
 			}
@@ -688,6 +758,10 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, ma
 			if err != nil {
 				return err
 			}
+
+			if !waitRateLimits(n, pool.quit, qc, conn.uploadLimiter, pool.uploadLimiter) {
+				return nil
+			}
 		}
 	}
 }
@@ -774,24 +848,44 @@ func (pool *ConnectionPool) isMaxOutgoingDefaultConnectionsReached() bool {
 	return len(pool.defaultOutgoingConnections) >= pool.Config.MaxDefaultPeerOutgoingConnections
 }
 
-func (pool *ConnectionPool) updateLastSent(addr string, t time.Time) error {
+// updateLastSent updates the last sent time and records a sent message of n bytes
+func (pool *ConnectionPool) updateLastSent(addr, msgType string, n int, t time.Time) error {
 	return pool.strand("updateLastSent", func() error {
+		pool.stats.recordSent(msgType, n)
 		if conn, ok := pool.addresses[addr]; ok {
 			conn.LastSent = t
+			conn.Stats.recordSent(msgType, n)
 		}
 		return nil
 	})
 }
 
-func (pool *ConnectionPool) updateLastRecv(addr string, t time.Time) error {
+// updateLastRecv updates the last received time and records a received message of n bytes
+func (pool *ConnectionPool) updateLastRecv(addr, msgType string, n int, t time.Time) error {
 	return pool.strand("updateLastRecv", func() error {
+		pool.stats.recordReceived(msgType, n)
 		if conn, ok := pool.addresses[addr]; ok {
 			conn.LastReceived = t
+			conn.Stats.recordReceived(msgType, n)
 		}
 		return nil
 	})
 }
 
+// GetStats returns the bytes and messages sent and received by all connections,
+// including connections that have been closed
+func (pool *ConnectionPool) GetStats() (ConnectionStats, error) {
+	var stats ConnectionStats
+	if err := pool.strand("GetStats", func() error {
+		stats = pool.stats.Copy()
+		return nil
+	}); err != nil {
+		return ConnectionStats{}, err
+	}
+
+	return stats, nil
+}
+
 // GetConnection returns a connection copy if exist
 func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 	var conn *Connection
@@ -799,6 +893,7 @@ func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 		if c, ok := pool.addresses[addr]; ok {
 			// copy connection
 			cc := *c
+			cc.Stats = c.Stats.Copy()
 			conn = &cc
 		}
 		return nil
@@ -809,6 +904,19 @@ func (pool *ConnectionPool) GetConnection(addr string) (*Connection, error) {
 	return conn, nil
 }
 
+// EnableCompression allows messages longer than Config.CompressionThreshold to be sent compressed to a connection.
+// This should only be called once the peer has indicated that it accepts compressed messages.
+func (pool *ConnectionPool) EnableCompression(addr string) error {
+	return pool.strand("EnableCompression", func() error {
+		c, ok := pool.addresses[addr]
+		if !ok {
+			return errors.New("EnableCompression: connection does not exist")
+		}
+		atomic.StoreInt32(&c.compress, 1)
+		return nil
+	})
+}
+
 // Connect to an address
 func (pool *ConnectionPool) Connect(address string) error {
 	if err := pool.strand("canConnect", func() error {
@@ -818,7 +926,7 @@ func (pool *ConnectionPool) Connect(address string) error {
 	}
 
 	logger.WithField("addr", address).Debugf("Making TCP connection")
-	conn, err := net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	conn, err := pool.dial(address)
 	if err != nil {
 		return err
 	}
@@ -836,6 +944,19 @@ func (pool *ConnectionPool) Connect(address string) error {
 	return nil
 }
 
+// dial opens a connection to address with the pool's Transport, or through the proxy if one is configured
+func (pool *ConnectionPool) dial(address string) (net.Conn, error) {
+	if pool.proxy != nil {
+		return pool.proxy.Dial(address)
+	}
+
+	if iputil.IsOnionAddr(address) {
+		return nil, ErrOnionRequiresProxy
+	}
+
+	return pool.Config.Transport.Dial(address, pool.Config.DialTimeout)
+}
+
 // Disconnect removes a connection from the pool by address and invokes DisconnectCallback
 func (pool *ConnectionPool) Disconnect(addr string, r DisconnectReason) error {
 	return pool.strand("Disconnect", func() error {
@@ -908,7 +1029,9 @@ func (pool *ConnectionPool) GetConnections() ([]Connection, error) {
 	conns := []Connection{}
 	if err := pool.strand("GetConnections", func() error {
 		for _, conn := range pool.pool {
-			conns = append(conns, *conn)
+			cc := *conn
+			cc.Stats = conn.Stats.Copy()
+			conns = append(conns, cc)
 		}
 		return nil
 	}); err != nil {
@@ -1008,18 +1131,33 @@ func (pool *ConnectionPool) BroadcastMessage(msg Message, addrs []string) ([]uin
 // first return value.  Otherwise, error will be nil and DisconnectReason will
 // be the value returned from the message handler.
 func (pool *ConnectionPool) receiveMessage(c *Connection, msg []byte) error {
+	// msg is stripped of its length prefix, add it back to count the bytes received
+	n := messageLengthPrefixSize + len(msg)
+
+	if len(msg) >= messagePrefixLength && bytes.Equal(msg[:messagePrefixLength], compressedMessageID[:]) {
+		var err error
+		msg, err = decompressMessage(msg[messagePrefixLength:], pool.Config.MaxIncomingMessageLength)
+		if err != nil {
+			logger.WithError(err).WithField("addr", c.Addr()).Warning("Failed to decompress message")
+			return err
+		}
+	}
+
 	m, err := convertToMessage(c.ID, msg, pool.Config.DebugPrint)
 	if err != nil {
 		return err
 	}
-	if err := pool.updateLastRecv(c.Addr(), Now()); err != nil {
+	if err := pool.updateLastRecv(c.Addr(), messageType(m), n, Now()); err != nil {
 		return err
 	}
 	return m.Handle(NewMessageContext(c), pool.messageState)
 }
 
-// SendPings sends a ping if our last message sent was over pingRate ago
-func (pool *ConnectionPool) SendPings(rate time.Duration, msg Message) error {
+// SendPings sends a ping if our last message sent was over pingRate ago.
+// If queued is not nil, it is called with the address of each connection right before its ping is queued,
+// so that the caller can record the ping time before the pong can be received.
+// Returns the addresses that a ping was queued for
+func (pool *ConnectionPool) SendPings(rate time.Duration, msg Message, queued func(addr string)) ([]string, error) {
 	now := time.Now().UTC()
 	var addrs []string
 	if err := pool.strand("SendPings", func() error {
@@ -1030,16 +1168,20 @@ func (pool *ConnectionPool) SendPings(rate time.Duration, msg Message) error {
 		}
 		return nil
 	}); err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, a := range addrs {
+	for i, a := range addrs {
+		if queued != nil {
+			queued(a)
+		}
+
 		if err := pool.SendMessage(a, msg); err != nil {
-			return err
+			return addrs[:i], err
 		}
 	}
 
-	return nil
+	return addrs, nil
 }
 
 // GetStaleConnections returns connections that have been idle for longer than idleLimit
@@ -0,0 +1,296 @@
+package gnet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// SOCKS5 client, implementing the parts of RFC 1928 and RFC 1929 needed to
+// open outgoing TCP connections through a proxy such as Tor
+
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone         = 0x00
+	socks5AuthUserPass     = 0x02
+	socks5AuthNoAcceptable = 0xff
+
+	socks5UserPassVersion = 0x01
+
+	socks5CmdConnect = 0x01
+
+	socks5AtypIPv4   = 0x01
+	socks5AtypDomain = 0x03
+	socks5AtypIPv6   = 0x04
+
+	socks5ReplySucceeded = 0x00
+)
+
+var (
+	// ErrInvalidProxyURL the proxy URL could not be parsed, or its scheme is not supported
+	ErrInvalidProxyURL = errors.New("Invalid proxy URL, expected socks5://[user:pass@]host:port")
+	// ErrSOCKS5NoAcceptableAuth the SOCKS5 proxy did not accept any of the offered authentication methods
+	ErrSOCKS5NoAcceptableAuth = errors.New("SOCKS5 proxy did not accept any authentication method")
+	// ErrSOCKS5AuthFailed the SOCKS5 proxy rejected the username and password
+	ErrSOCKS5AuthFailed = errors.New("SOCKS5 proxy authentication failed")
+	// ErrSOCKS5InvalidReply the SOCKS5 proxy sent a malformed reply
+	ErrSOCKS5InvalidReply = errors.New("SOCKS5 proxy sent an invalid reply")
+	// ErrOnionRequiresProxy an onion address can only be reached through a proxy
+	ErrOnionRequiresProxy = errors.New("Onion addresses can only be dialed through a proxy")
+
+	socks5ReplyErrors = map[byte]string{
+		0x01: "general SOCKS server failure",
+		0x02: "connection not allowed by ruleset",
+		0x03: "network unreachable",
+		0x04: "host unreachable",
+		0x05: "connection refused",
+		0x06: "TTL expired",
+		0x07: "command not supported",
+		0x08: "address type not supported",
+	}
+)
+
+// SOCKS5Error is returned when the SOCKS5 proxy fails to establish a connection
+type SOCKS5Error struct {
+	Code byte
+}
+
+func (e SOCKS5Error) Error() string {
+	if msg, ok := socks5ReplyErrors[e.Code]; ok {
+		return fmt.Sprintf("SOCKS5 proxy: %s", msg)
+	}
+	return fmt.Sprintf("SOCKS5 proxy: unknown error code %d", e.Code)
+}
+
+// socks5Dialer dials addresses through a SOCKS5 proxy
+type socks5Dialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	timeout   time.Duration
+}
+
+// newSOCKS5Dialer creates a socks5Dialer from a socks5://[user:pass@]host:port URL
+func newSOCKS5Dialer(proxyURL string, timeout time.Duration) (*socks5Dialer, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, ErrInvalidProxyURL
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+	default:
+		return nil, ErrInvalidProxyURL
+	}
+
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, ErrInvalidProxyURL
+	}
+
+	d := &socks5Dialer{
+		proxyAddr: u.Host,
+		timeout:   timeout,
+	}
+
+	if u.User != nil {
+		d.username = u.User.Username()
+		d.password, _ = u.User.Password()
+		if len(d.username) == 0 || len(d.username) > 255 || len(d.password) > 255 {
+			return nil, ErrInvalidProxyURL
+		}
+	}
+
+	return d, nil
+}
+
+// Dial connects to address through the proxy. The returned net.Conn reports
+// address as its RemoteAddr, rather than the address of the proxy.
+// Hostnames (such as onion addresses) are resolved by the proxy.
+func (d *socks5Dialer) Dial(address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", d.proxyAddr, d.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.handshake(conn, host, uint16(port)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &proxiedConn{
+		Conn:       conn,
+		remoteAddr: proxiedAddr(address),
+	}, nil
+}
+
+// handshake negotiates authentication and sends the CONNECT request
+func (d *socks5Dialer) handshake(conn net.Conn, host string, port uint16) error {
+	if d.timeout != 0 {
+		if err := conn.SetDeadline(time.Now().Add(d.timeout)); err != nil {
+			return err
+		}
+		defer conn.SetDeadline(time.Time{}) //nolint:errcheck
+	}
+
+	// Offer the authentication methods
+	methods := []byte{socks5AuthNone}
+	if d.username != "" {
+		methods = append(methods, socks5AuthUserPass)
+	}
+
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return ErrSOCKS5InvalidReply
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthUserPass:
+		if d.username == "" {
+			return ErrSOCKS5InvalidReply
+		}
+		if err := d.authenticate(conn); err != nil {
+			return err
+		}
+	case socks5AuthNoAcceptable:
+		return ErrSOCKS5NoAcceptableAuth
+	default:
+		return ErrSOCKS5InvalidReply
+	}
+
+	// Send the CONNECT request
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AtypIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AtypIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("SOCKS5 destination hostname too long")
+		}
+		req = append(req, socks5AtypDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+
+	portBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBytes, port)
+	req = append(req, portBytes...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// Read the reply header: VER REP RSV ATYP
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return ErrSOCKS5InvalidReply
+	}
+	if header[1] != socks5ReplySucceeded {
+		return SOCKS5Error{Code: header[1]}
+	}
+
+	// Discard the bound address and port
+	var addrLen int
+	switch header[3] {
+	case socks5AtypIPv4:
+		addrLen = net.IPv4len
+	case socks5AtypIPv6:
+		addrLen = net.IPv6len
+	case socks5AtypDomain:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return err
+		}
+		addrLen = int(n[0])
+	default:
+		return ErrSOCKS5InvalidReply
+	}
+
+	if _, err := io.ReadFull(conn, make([]byte, addrLen+2)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// authenticate performs RFC 1929 username/password authentication
+func (d *socks5Dialer) authenticate(conn net.Conn) error {
+	req := []byte{socks5UserPassVersion, byte(len(d.username))}
+	req = append(req, d.username...)
+	req = append(req, byte(len(d.password)))
+	req = append(req, d.password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5UserPassVersion {
+		return ErrSOCKS5InvalidReply
+	}
+	if reply[1] != 0x00 {
+		return ErrSOCKS5AuthFailed
+	}
+
+	return nil
+}
+
+// proxiedConn is a net.Conn opened through a proxy.
+// The ConnectionPool indexes connections by their remote address, so this reports
+// the proxied destination instead of the proxy's own address.
+type proxiedConn struct {
+	net.Conn
+	remoteAddr proxiedAddr
+}
+
+// RemoteAddr returns the proxied destination address
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// proxiedAddr is the net.Addr of a proxied destination, which may be a hostname
+type proxiedAddr string
+
+// Network implements net.Addr
+func (a proxiedAddr) Network() string {
+	return "tcp"
+}
+
+// String implements net.Addr
+func (a proxiedAddr) String() string {
+	return string(a)
+}
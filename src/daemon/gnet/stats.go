@@ -0,0 +1,156 @@
+package gnet
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// MessageStats counts the messages and bytes of a single message type
+type MessageStats struct {
+	Count uint64
+	Bytes uint64
+}
+
+// ConnectionStats counts the bytes and messages sent and received, in total and per message type.
+// Message types are keyed by their message ID prefix, e.g. "GIVB".
+// Byte counts include the message length prefix and message ID.
+type ConnectionStats struct {
+	BytesSent        uint64
+	BytesReceived    uint64
+	MessagesSent     uint64
+	MessagesReceived uint64
+	SentByType       map[string]MessageStats
+	ReceivedByType   map[string]MessageStats
+}
+
+func newConnectionStats() ConnectionStats {
+	return ConnectionStats{
+		SentByType:     make(map[string]MessageStats),
+		ReceivedByType: make(map[string]MessageStats),
+	}
+}
+
+// recordSent adds a sent message of n bytes
+func (s *ConnectionStats) recordSent(msgType string, n int) {
+	s.BytesSent += uint64(n)
+	s.MessagesSent++
+
+	ms := s.SentByType[msgType]
+	ms.Count++
+	ms.Bytes += uint64(n)
+	s.SentByType[msgType] = ms
+}
+
+// recordReceived adds a received message of n bytes
+func (s *ConnectionStats) recordReceived(msgType string, n int) {
+	s.BytesReceived += uint64(n)
+	s.MessagesReceived++
+
+	ms := s.ReceivedByType[msgType]
+	ms.Count++
+	ms.Bytes += uint64(n)
+	s.ReceivedByType[msgType] = ms
+}
+
+// Copy returns a deep copy of the stats
+func (s ConnectionStats) Copy() ConnectionStats {
+	c := s
+	c.SentByType = make(map[string]MessageStats, len(s.SentByType))
+	for k, v := range s.SentByType {
+		c.SentByType[k] = v
+	}
+	c.ReceivedByType = make(map[string]MessageStats, len(s.ReceivedByType))
+	for k, v := range s.ReceivedByType {
+		c.ReceivedByType[k] = v
+	}
+	return c
+}
+
+// messageType returns the message ID prefix of a Message, for stats keys
+func messageType(msg Message) string {
+	msgID, ok := MessageIDMap[reflect.ValueOf(msg).Elem().Type()]
+	if !ok {
+		return "unknown"
+	}
+	return msgIDStringSafe(msgID)
+}
+
+// rateLimiter limits a byte stream to a number of bytes per second.
+// Each Connection has its own rateLimiters, and the ConnectionPool has rateLimiters
+// shared by all connections. A nil *rateLimiter does not limit.
+type rateLimiter struct {
+	sync.Mutex
+	rate int64
+	// bytes that may be transferred without waiting. May be negative, in which case
+	// the next transfer waits until it has been repaid.
+	allowance int64
+	last      time.Time
+}
+
+// newRateLimiter creates a rateLimiter for bytesPerSecond. Returns nil if bytesPerSecond is 0
+func newRateLimiter(bytesPerSecond int) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		rate:      int64(bytesPerSecond),
+		allowance: int64(bytesPerSecond),
+		last:      time.Now(),
+	}
+}
+
+// reserve takes n bytes from the allowance and returns how long to wait
+// before transferring more data
+func (r *rateLimiter) reserve(n int) time.Duration {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+	r.allowance += int64(now.Sub(r.last).Seconds() * float64(r.rate))
+	r.last = now
+
+	// Allow at most a second's worth of burst
+	if r.allowance > r.rate {
+		r.allowance = r.rate
+	}
+
+	r.allowance -= int64(n)
+	if r.allowance >= 0 {
+		return 0
+	}
+
+	return time.Duration(float64(-r.allowance) / float64(r.rate) * float64(time.Second))
+}
+
+// waitRateLimits records the transfer of n bytes with each of the limiters and blocks until
+// all of them allow another transfer. Returns false if interrupted by one of the quit channels.
+func waitRateLimits(n int, quit, qc <-chan struct{}, limiters ...*rateLimiter) bool {
+	var d time.Duration
+	for _, r := range limiters {
+		if r == nil {
+			continue
+		}
+
+		if x := r.reserve(n); x > d {
+			d = x
+		}
+	}
+
+	if d == 0 {
+		return true
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-quit:
+		return false
+	case <-qc:
+		return false
+	}
+}
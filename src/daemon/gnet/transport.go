@@ -0,0 +1,29 @@
+package gnet
+
+import (
+	"net"
+	"time"
+)
+
+// Transport opens the listener and outgoing connections of a ConnectionPool.
+// The default transport uses TCP. Other transports can be used to run several
+// pools in one process, e.g. over an in-memory network in tests and simulations.
+type Transport interface {
+	// Listen listens for incoming connections on address
+	Listen(address string) (net.Listener, error)
+	// Dial opens a connection to address. A timeout of 0 means no timeout
+	Dial(address string, timeout time.Duration) (net.Conn, error)
+}
+
+// tcpTransport is the default Transport
+type tcpTransport struct{}
+
+// Listen listens for incoming TCP connections on address
+func (tcpTransport) Listen(address string) (net.Listener, error) {
+	return net.Listen("tcp", address)
+}
+
+// Dial opens a TCP connection to address
+func (tcpTransport) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("tcp", address, timeout)
+}
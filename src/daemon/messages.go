@@ -12,11 +12,13 @@ import (
 	"../../src/cipher"
 	"../../src/cipher/encoder"
 	"../../src/coin"
+	"../../src/daemon/bloom"
 	"../../src/daemon/gnet"
 	"../../src/daemon/pex"
 	"../../src/params"
 	"../../src/util/iputil"
 	"../../src/util/useragent"
+	"../../src/visor"
 )
 
 // Message represent a packet to be serialized over the network by
@@ -46,6 +48,7 @@ func NewMessageConfig(prefix string, m interface{}) MessageConfig {
 
 //go:generate laqencoder -unexported -struct IntroductionMessage
 //go:generate laqencoder -unexported -struct GivePeersMessage
+//go:generate laqencoder -unexported -struct GiveIPv6PeersMessage
 //go:generate laqencoder -unexported -struct GetBlocksMessage
 //go:generate laqencoder -unexported -struct GiveBlocksMessage
 //go:generate laqencoder -unexported -struct AnnounceBlocksMessage
@@ -53,7 +56,15 @@ func NewMessageConfig(prefix string, m interface{}) MessageConfig {
 //go:generate laqencoder -unexported -struct GiveTxnsMessage
 //go:generate laqencoder -unexported -struct AnnounceTxnsMessage
 //go:generate laqencoder -unexported -struct DisconnectMessage
+//go:generate laqencoder -unexported -struct FilterLoadMessage
+//go:generate laqencoder -unexported -struct FilterAddMessage
+//go:generate laqencoder -unexported -struct FilteredBlocksMessage
+//go:generate laqencoder -unexported -struct GiveBlockCandidateMessage
+//go:generate laqencoder -unexported -struct GiveBlockHeaderMessage
+//go:generate laqencoder -unexported -struct GetBlockCandidateMessage
+//go:generate laqencoder -unexported -struct GivePublisherKeyChangesMessage
 //go:generate laqencoder -unexported -struct IPAddr
+//go:generate laqencoder -unexported -struct IPv6Addr
 //go:generate laqencoder -unexported -output-path . -package daemon -struct SignedBlock ../../src/coin
 //go:generate laqencoder -unexported -output-path . -package daemon -struct Transaction ../../src/coin
 
@@ -72,6 +83,16 @@ func getMessageConfigs() []MessageConfig {
 		NewMessageConfig("GIVT", GiveTxnsMessage{}),
 		NewMessageConfig("ANNT", AnnounceTxnsMessage{}),
 		NewMessageConfig("DISC", DisconnectMessage{}),
+		NewMessageConfig("GIV6", GiveIPv6PeersMessage{}),
+		NewMessageConfig("FLTL", FilterLoadMessage{}),
+		NewMessageConfig("FLTA", FilterAddMessage{}),
+		NewMessageConfig("FLTC", FilterClearMessage{}),
+		NewMessageConfig("GIVF", FilteredBlocksMessage{}),
+		NewMessageConfig("STEM", StemTxnMessage{}),
+		NewMessageConfig("GIVC", GiveBlockCandidateMessage{}),
+		NewMessageConfig("GIVH", GiveBlockHeaderMessage{}),
+		NewMessageConfig("GETC", GetBlockCandidateMessage{}),
+		NewMessageConfig("GIVK", GivePublisherKeyChangesMessage{}),
 	}
 }
 
@@ -91,6 +112,10 @@ func NewMessagesConfig() MessagesConfig {
 // Register registers our Messages with gnet
 func (msc *MessagesConfig) Register() {
 	for _, mc := range msc.Messages {
+		// The message registry is global, and is shared by all of the daemons in the process
+		if gnet.IsMessageRegistered(mc.Prefix, mc.Message) {
+			continue
+		}
 		gnet.RegisterMessage(mc.Prefix, mc.Message)
 	}
 	gnet.VerifyMessages()
@@ -108,6 +133,13 @@ func NewMessages(c MessagesConfig) *Messages {
 	}
 }
 
+var (
+	// ErrNotIPv4Addr is returned by NewIPAddr if the address is not an IPv4 address
+	ErrNotIPv4Addr = errors.New("Not an IPv4 address")
+	// ErrNotIPv6Addr is returned by NewIPv6Addr if the address is not an IPv6 address
+	ErrNotIPv6Addr = errors.New("Not an IPv6 address")
+)
+
 // IPAddr compact representation of IP:Port
 type IPAddr struct {
 	IP   uint32
@@ -121,10 +153,9 @@ func NewIPAddr(addr string) (ipaddr IPAddr, err error) {
 		return
 	}
 
-	// TODO -- support ipv6
 	ipb := net.ParseIP(ips).To4()
 	if ipb == nil {
-		err = errors.New("Ignoring IPv6 address")
+		err = ErrNotIPv4Addr
 		return
 	}
 
@@ -141,6 +172,35 @@ func (ipa IPAddr) String() string {
 	return fmt.Sprintf("%s:%d", net.IP(ipb).String(), ipa.Port)
 }
 
+// IPv6Addr compact representation of an IPv6 [ip]:port
+type IPv6Addr struct {
+	IP   [16]byte
+	Port uint16
+}
+
+// NewIPv6Addr returns an IPv6Addr from an [ip]:port string.
+func NewIPv6Addr(addr string) (ipaddr IPv6Addr, err error) {
+	ips, port, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return
+	}
+
+	ip := net.ParseIP(ips)
+	if ip == nil || ip.To4() != nil {
+		err = ErrNotIPv6Addr
+		return
+	}
+
+	copy(ipaddr.IP[:], ip.To16())
+	ipaddr.Port = port
+	return
+}
+
+// String returns IPv6Addr as "[ip]:port"
+func (ipa IPv6Addr) String() string {
+	return iputil.JoinAddr(net.IP(ipa.IP[:]).String(), ipa.Port)
+}
+
 // asyncMessage messages that perform an action when received must implement this interface.
 // process() is called after the message is pulled off of messageEvent channel.
 // Messages should place themselves on the messageEvent channel in their
@@ -208,7 +268,10 @@ func NewGivePeersMessage(peers []pex.Peer, maxMsgLength uint64) *GivePeersMessag
 	for _, ps := range peers {
 		ipaddr, err := NewIPAddr(ps.Addr)
 		if err != nil {
-			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersMessage skipping invalid address")
+			// IPv6 peers are sent in a GiveIPv6PeersMessage instead
+			if err != ErrNotIPv4Addr {
+				logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersMessage skipping invalid address")
+			}
 			continue
 		}
 		ipaddrs = append(ipaddrs, ipaddr)
@@ -320,7 +383,131 @@ func (gpm *GivePeersMessage) process(d daemoner) {
 		"count":  len(peers),
 	}).Debug("Received peers via PEX")
 
-	d.addPeers(peers)
+	d.addPeers(gpm.c.Addr, peers)
+}
+
+// GiveIPv6PeersMessage sent in response to GetPeersMessage, carrying IPv6 peers.
+// It is only sent to peers that advertise ServiceIPv6Peers,
+// since older peers do not recognize this message. IPv4 peers are sent in a GivePeersMessage.
+type GiveIPv6PeersMessage struct {
+	Peers []IPv6Addr           `enc:",maxlen=512"`
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveIPv6PeersMessage []*pex.Peer is converted to []IPv6Addr for binary transmission.
+// Peers that do not have an IPv6 address are skipped.
+// If the size of the message would exceed maxMsgLength, the IPv6Addr slice is truncated.
+func NewGiveIPv6PeersMessage(peers []pex.Peer, maxMsgLength uint64) *GiveIPv6PeersMessage {
+	if len(peers) > 512 {
+		peers = peers[:512]
+	}
+
+	ipaddrs := make([]IPv6Addr, 0, len(peers))
+	for _, ps := range peers {
+		ipaddr, err := NewIPv6Addr(ps.Addr)
+		if err != nil {
+			if err != ErrNotIPv6Addr {
+				logger.WithError(err).WithField("addr", ps.Addr).Warning("GiveIPv6PeersMessage skipping invalid address")
+			}
+			continue
+		}
+		ipaddrs = append(ipaddrs, ipaddr)
+	}
+
+	m := &GiveIPv6PeersMessage{
+		Peers: ipaddrs,
+	}
+	truncateGiveIPv6PeersMessage(m, maxMsgLength)
+	return m
+}
+
+// truncateGiveIPv6PeersMessage truncates the peers in GiveIPv6PeersMessage to fit inside of MaxOutgoingMessageLength
+func truncateGiveIPv6PeersMessage(m *GiveIPv6PeersMessage, maxMsgLength uint64) {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return
+	}
+
+	// Measure the size of an empty message
+	var mm GiveIPv6PeersMessage
+	size := mm.EncodeSize()
+
+	// Measure the size of the peers, advancing the slice index until it reaches capacity
+	index := -1
+	for i, ip := range m.Peers {
+		x := encodeSizeIPv6Addr(&ip)
+		if size+x > maxMsgLength {
+			break
+		}
+		size += x
+		index = i
+	}
+
+	m.Peers = m.Peers[:index+1]
+
+	if len(m.Peers) == 0 {
+		logger.Critical().Error("truncateGiveIPv6PeersMessage truncated peers to an empty slice")
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (gpm *GiveIPv6PeersMessage) EncodeSize() uint64 {
+	return encodeSizeGiveIPv6PeersMessage(gpm)
+}
+
+// Encode implements gnet.Serializer
+func (gpm *GiveIPv6PeersMessage) Encode(buf []byte) error {
+	return encodeGiveIPv6PeersMessageToBuffer(buf, gpm)
+}
+
+// Decode implements gnet.Serializer
+func (gpm *GiveIPv6PeersMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveIPv6PeersMessage(buf, gpm)
+}
+
+// GetPeers returns the peers contained in the message as an array of "[ip]:port" strings.
+func (gpm *GiveIPv6PeersMessage) GetPeers() []string {
+	peers := make([]string, len(gpm.Peers))
+	for i, ipaddr := range gpm.Peers {
+		peers[i] = ipaddr.String()
+	}
+	return peers
+}
+
+// Handle handle message
+func (gpm *GiveIPv6PeersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gpm.c = mc
+	return daemon.(daemoner).recordMessageEvent(gpm, mc)
+}
+
+// process Notifies the Pex instance that peers were received
+func (gpm *GiveIPv6PeersMessage) process(d daemoner) {
+	if d.pexConfig().Disabled {
+		return
+	}
+
+	peers := gpm.GetPeers()
+
+	if len(peers) == 0 {
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":   gpm.c.Addr,
+		"gnetID": gpm.c.ConnID,
+		"count":  len(peers),
+	}).Debug("Received IPv6 peers via PEX")
+
+	d.addPeers(gpm.c.Addr, peers)
 }
 
 // IntroductionMessage is sent on first connect by both parties
@@ -329,12 +516,13 @@ type IntroductionMessage struct {
 	UserAgent            useragent.Data       `enc:"-"`
 	UnconfirmedVerifyTxn params.VerifyTxn     `enc:"-"`
 	GenesisHash          cipher.SHA256        `enc:"-"`
+	Services             Services             `enc:"-"`
 
 	// Mirror is a random value generated on client startup that is used to identify self-connections
 	Mirror uint32
 	// ListenPort is the port that this client is listening on
 	ListenPort uint16
-	// Protocol version
+	// Protocol version. Optional features, such as GiveIPv6PeersMessage, are negotiated with Services instead
 	ProtocolVersion int32
 
 	// Extra is extra bytes added to the struct to accommodate multiple versions of this packet.
@@ -348,20 +536,21 @@ type IntroductionMessage struct {
 	// MaxDropletPrecision uint8 // maximum number of decimal places for announced txns
 	// UserAgent           string `enc:",maxlen=256"`
 	// GenesisHash         cipher.SHA256 // genesis block hash
+	// Services            uint64 // bitfield of optional protocol features supported by the sender, see Services
 	Extra []byte `enc:",omitempty"`
 }
 
 // NewIntroductionMessage creates introduction message
-func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256) *IntroductionMessage {
+func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256, services Services) *IntroductionMessage {
 	return &IntroductionMessage{
 		Mirror:          mirror,
 		ProtocolVersion: version,
 		ListenPort:      port,
-		Extra:           newIntroductionMessageExtra(pubkey, userAgent, verifyParams, genesisHash),
+		Extra:           newIntroductionMessageExtra(pubkey, userAgent, verifyParams, genesisHash, services),
 	}
 }
 
-func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256) []byte {
+func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256, services Services) []byte {
 	if len(userAgent) > useragent.MaxLen {
 		logger.WithFields(logrus.Fields{
 			"userAgent": userAgent,
@@ -380,7 +569,9 @@ func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, verifyP
 	userAgentSerialized := encoder.SerializeString(userAgent)
 	verifyParamsSerialized := encoder.Serialize(verifyParams)
 
-	extra := make([]byte, len(pubkey)+len(userAgentSerialized)+len(verifyParamsSerialized)+len(genesisHash))
+	servicesSerialized := encoder.SerializeAtomic(uint64(services))
+
+	extra := make([]byte, len(pubkey)+len(userAgentSerialized)+len(verifyParamsSerialized)+len(genesisHash)+len(servicesSerialized))
 
 	copy(extra[:len(pubkey)], pubkey[:])
 	i := len(pubkey)
@@ -389,6 +580,8 @@ func newIntroductionMessageExtra(pubkey cipher.PubKey, userAgent string, verifyP
 	copy(extra[i:], userAgentSerialized)
 	i += len(userAgentSerialized)
 	copy(extra[i:i+len(genesisHash)], genesisHash[:])
+	i += len(genesisHash)
+	copy(extra[i:], servicesSerialized)
 
 	return extra
 }
@@ -481,6 +674,10 @@ func (intro *IntroductionMessage) process(d daemoner) {
 	if err := d.announceAllValidTxns(); err != nil {
 		logger.WithError(err).Warning("announceAllValidTxns failed")
 	}
+
+	if err := d.sendPublisherKeyChanges(addr); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("sendPublisherKeyChanges failed")
+	}
 }
 
 // Verify checks if the introduction message is valid returning the appropriate error
@@ -579,6 +776,22 @@ func (intro *IntroductionMessage) Verify(dc DaemonConfig, logFields logrus.Field
 		return ErrDisconnectInvalidExtraData
 	}
 	copy(intro.GenesisHash[:], intro.Extra[i:])
+	i += len(intro.GenesisHash)
+
+	// Older clients do not send services. Data after the services is reserved for future use
+	remainingLen = extraLen - i
+	if remainingLen > 0 && remainingLen < 8 {
+		logger.WithFields(logFields).Warning("Extra data services could not be deserialized: not enough data")
+		return ErrDisconnectInvalidExtraData
+	}
+	if remainingLen > 0 {
+		var services uint64
+		if _, err := encoder.DeserializeAtomic(intro.Extra[i:i+8], &services); err != nil {
+			logger.WithError(err).WithFields(logFields).Warning("Extra data services could not be deserialized")
+			return ErrDisconnectInvalidExtraData
+		}
+		intro.Services = Services(services)
+	}
 
 	return nil
 }
@@ -624,7 +837,7 @@ func (ping *PingMessage) process(d daemoner) {
 	}
 }
 
-// PongMessage Sent in reply to a PingMessage.  No action is taken when this is received.
+// PongMessage Sent in reply to a PingMessage. The time since the PingMessage was sent is recorded as the peer's RTT
 type PongMessage struct {
 }
 
@@ -645,9 +858,12 @@ func (pong *PongMessage) Decode(buf []byte) (uint64, error) {
 
 // Handle handles message
 func (pong *PongMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
-	// There is nothing to do; gnet updates Connection.LastMessage internally
-	// when this is received
-	if daemon.(daemoner).DaemonConfig().LogPings {
+	// gnet updates Connection.LastMessage internally when this is received.
+	// The RTT is recorded here rather than in the daemon run loop, so that it does not include the time spent in the event queue
+	d := daemon.(daemoner)
+	d.recordPong(mc.Addr, mc.ConnID)
+
+	if d.DaemonConfig().LogPings {
 		logger.WithFields(logrus.Fields{
 			"addr":   mc.Addr,
 			"gnetID": mc.ConnID,
@@ -881,10 +1097,16 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
+	if len(m.Blocks) != 0 {
+		d.recordBlocksReceived(m.c.Addr, m.c.ConnID)
+	}
+
 	// These DB queries are not performed in a transaction for performance reasons.
 	// It is not necessary that the blocks be executed together in a single transaction.
 
 	processed := 0
+	// Blocks that are not executed are checked for a conflict with our chain, until one conflicts
+	checkConflict := true
 	maxSeq, ok, err := d.headBkSeq()
 	if err != nil {
 		logger.WithError(err).Error("d.headBkSeq failed")
@@ -903,6 +1125,9 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		// the reply with 15 was received first, we would toss the one with 20
 		// even though we could process it at the time.
 		if b.Seq() <= maxSeq {
+			if checkConflict {
+				checkConflict = d.checkBlockConflict(m.c.Addr, b)
+			}
 			continue
 		}
 
@@ -912,6 +1137,9 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 			processed++
 		} else {
 			logger.Critical().WithError(err).WithField("seq", b.Block.Head.BkSeq).Error("Failed to execute received block")
+			if checkConflict {
+				d.checkBlockConflict(m.c.Addr, b)
+			}
 			// Blocks must be received in order, so if one fails its assumed
 			// the rest are failing
 			break
@@ -1132,6 +1360,9 @@ func (atm *AnnounceTxnsMessage) process(d daemoner) {
 		"gnetID": atm.c.ConnID,
 	}
 
+	// The peer has broadcast these transactions, so any of them in the stem phase can be broadcast too
+	d.stemTxnsFluffed(atm.Transactions)
+
 	unknown, err := d.filterKnownUnconfirmed(atm.Transactions)
 	if err != nil {
 		logger.WithError(err).Error("AnnounceTxnsMessage d.filterKnownUnconfirmed failed")
@@ -1351,6 +1582,8 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		return
 	}
 
+	d.stemTxnsFluffed(gtm.GetFiltered())
+
 	hashes := make([]cipher.SHA256, 0, len(gtm.Transactions))
 	// Update unconfirmed pool with these transactions
 	for _, txn := range gtm.Transactions {
@@ -1388,3 +1621,548 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		logger.Debugf("Announced %d transactions to %d peers", len(hashes), len(ids))
 	}
 }
+
+// StemTxnMessage relays a transaction in the stem phase of Dandelion relay.
+// The receiver passes the transaction on to one of its peers, or broadcasts it, see Daemon.relayStemTxn.
+// It is only sent to peers that advertise ServiceDandelion
+type StemTxnMessage struct {
+	Transaction coin.Transaction
+	c           *gnet.MessageContext `enc:"-"`
+}
+
+// NewStemTxnMessage creates a StemTxnMessage
+func NewStemTxnMessage(txn coin.Transaction) *StemTxnMessage {
+	return &StemTxnMessage{
+		Transaction: txn,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (stm *StemTxnMessage) EncodeSize() uint64 {
+	return encodeSizeStemTxnMessage(stm)
+}
+
+// Encode implements gnet.Serializer
+func (stm *StemTxnMessage) Encode(buf []byte) error {
+	return encodeStemTxnMessageToBuffer(buf, stm)
+}
+
+// Decode implements gnet.Serializer
+func (stm *StemTxnMessage) Decode(buf []byte) (uint64, error) {
+	return decodeStemTxnMessage(buf, stm)
+}
+
+// Handle handle message
+func (stm *StemTxnMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	stm.c = mc
+	return daemon.(daemoner).recordMessageEvent(stm, mc)
+}
+
+// process adds the transaction to the unconfirmed pool and relays it
+func (stm *StemTxnMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   stm.c.Addr,
+		"gnetID": stm.c.ConnID,
+		"txid":   stm.Transaction.Hash().Hex(),
+	}
+
+	known, softErr, err := d.injectTransaction(stm.Transaction)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Warning("Failed to record stem transaction")
+		return
+	} else if softErr != nil {
+		logger.WithError(softErr).WithFields(fields).Warning("Stem transaction soft violation")
+	}
+
+	d.relayStemTxn(stm.c.Addr, stm.Transaction, known)
+}
+
+// GiveBlockCandidateMessage sends a block proposed by a block publisher for the next sequence.
+// The block is signed by the publisher that proposed it. It is not executed until the block
+// publishers agree on it, see Daemon.receiveBlockCandidate.
+// It is only sent to peers that advertise ServiceConsensus
+type GiveBlockCandidateMessage struct {
+	Block coin.SignedBlock
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockCandidateMessage creates a GiveBlockCandidateMessage
+func NewGiveBlockCandidateMessage(b coin.SignedBlock) *GiveBlockCandidateMessage {
+	return &GiveBlockCandidateMessage{
+		Block: b,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GiveBlockCandidateMessage) EncodeSize() uint64 {
+	return encodeSizeGiveBlockCandidateMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveBlockCandidateMessage) Encode(buf []byte) error {
+	return encodeGiveBlockCandidateMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveBlockCandidateMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveBlockCandidateMessage(buf, m)
+}
+
+// Handle handle message
+func (m *GiveBlockCandidateMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process records the block candidate
+func (m *GiveBlockCandidateMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	d.receiveBlockCandidate(m.c.Addr, m.Block)
+}
+
+// GiveBlockHeaderMessage sends a block publisher's signature of a block candidate's header, in a round of the consensus.
+// The candidate signed by a quorum of the block publishers in the same round is executed, see Daemon.receiveBlockHeader.
+// It is only sent to peers that advertise ServiceConsensus
+type GiveBlockHeaderMessage struct {
+	// Seq is the sequence of the block
+	Seq uint64
+	// Round is the consensus round of the signature
+	Round uint32
+	// Hash is the header hash of the block
+	Hash cipher.SHA256
+	// Sig is a block publisher's signature of Hash in round 0, or of roundVoteHash in later rounds
+	Sig cipher.Sig
+	c   *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockHeaderMessage creates a GiveBlockHeaderMessage
+func NewGiveBlockHeaderMessage(seq uint64, round uint32, hash cipher.SHA256, sig cipher.Sig) *GiveBlockHeaderMessage {
+	return &GiveBlockHeaderMessage{
+		Seq:   seq,
+		Round: round,
+		Hash:  hash,
+		Sig:   sig,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GiveBlockHeaderMessage) EncodeSize() uint64 {
+	return encodeSizeGiveBlockHeaderMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveBlockHeaderMessage) Encode(buf []byte) error {
+	return encodeGiveBlockHeaderMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveBlockHeaderMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveBlockHeaderMessage(buf, m)
+}
+
+// Handle handle message
+func (m *GiveBlockHeaderMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process records the block header signature
+func (m *GiveBlockHeaderMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	d.receiveBlockHeader(m.c.Addr, m.Seq, m.Round, m.Hash, m.Sig)
+}
+
+// GetBlockCandidateMessage asks for a block candidate for the next sequence, by its header hash.
+// It is sent when the block publishers selected a candidate that has not arrived, see Daemon.executeSelectedBlock.
+// The candidate is sent back in a GiveBlockCandidateMessage, if the peer has it.
+// It is only sent to peers that advertise ServiceConsensus
+type GetBlockCandidateMessage struct {
+	// Seq is the sequence of the block
+	Seq uint64
+	// Hash is the header hash of the block
+	Hash cipher.SHA256
+	c    *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockCandidateMessage creates a GetBlockCandidateMessage
+func NewGetBlockCandidateMessage(seq uint64, hash cipher.SHA256) *GetBlockCandidateMessage {
+	return &GetBlockCandidateMessage{
+		Seq:  seq,
+		Hash: hash,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GetBlockCandidateMessage) EncodeSize() uint64 {
+	return encodeSizeGetBlockCandidateMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GetBlockCandidateMessage) Encode(buf []byte) error {
+	return encodeGetBlockCandidateMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GetBlockCandidateMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGetBlockCandidateMessage(buf, m)
+}
+
+// Handle handle message
+func (m *GetBlockCandidateMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process sends the block candidate, if this node has it
+func (m *GetBlockCandidateMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	d.sendBlockCandidate(m.c.Addr, m.Seq, m.Hash)
+}
+
+// GivePublisherKeyChangesMessage sends block publisher key changes, sorted by ascending seq.
+// The committed and proposed changes are sent after the introduction, and new changes are relayed
+// when they are received, see Daemon.receivePublisherKeyChanges.
+// It is only sent to peers that advertise ServicePublisherKeys
+type GivePublisherKeyChangesMessage struct {
+	Changes []visor.PublisherKeyChange `enc:",maxlen=256"`
+	c       *gnet.MessageContext       `enc:"-"`
+}
+
+// NewGivePublisherKeyChangesMessage creates a GivePublisherKeyChangesMessage
+func NewGivePublisherKeyChangesMessage(kcs []visor.PublisherKeyChange) *GivePublisherKeyChangesMessage {
+	return &GivePublisherKeyChangesMessage{
+		Changes: kcs,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GivePublisherKeyChangesMessage) EncodeSize() uint64 {
+	return encodeSizeGivePublisherKeyChangesMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GivePublisherKeyChangesMessage) Encode(buf []byte) error {
+	return encodeGivePublisherKeyChangesMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GivePublisherKeyChangesMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGivePublisherKeyChangesMessage(buf, m)
+}
+
+// Handle handle message
+func (m *GivePublisherKeyChangesMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process adds the publisher key changes
+func (m *GivePublisherKeyChangesMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	d.receivePublisherKeyChanges(m.c.Addr, m.Changes)
+}
+
+// FilterLoadMessage is sent by a light client to load a bloom filter into its connection.
+// Once a filter is loaded, blocks are sent to the connection as FilteredBlocksMessage,
+// and only transactions that match the filter are sent or announced.
+// A transaction matches if the filter matches its hash, the ID of one of its inputs,
+// or the address bytes (cipher.Address.Bytes()) of one of its outputs.
+type FilterLoadMessage struct {
+	// Filter is the bloom filter's bit array
+	Filter []byte `enc:",maxlen=36000"`
+	// HashFuncs is the number of hash functions of the filter
+	HashFuncs uint32
+	// Tweak is a random value that seeds the hash functions
+	Tweak uint32
+	// Flags are the filter's bloom.UpdateFlags
+	Flags uint8
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewFilterLoadMessage creates a FilterLoadMessage
+func NewFilterLoadMessage(f *bloom.Filter) *FilterLoadMessage {
+	return &FilterLoadMessage{
+		Filter:    f.Data(),
+		HashFuncs: f.HashFuncs(),
+		Tweak:     f.Tweak(),
+		Flags:     uint8(f.Flags()),
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (flm *FilterLoadMessage) EncodeSize() uint64 {
+	return encodeSizeFilterLoadMessage(flm)
+}
+
+// Encode implements gnet.Serializer
+func (flm *FilterLoadMessage) Encode(buf []byte) error {
+	return encodeFilterLoadMessageToBuffer(buf, flm)
+}
+
+// Decode implements gnet.Serializer
+func (flm *FilterLoadMessage) Decode(buf []byte) (uint64, error) {
+	return decodeFilterLoadMessage(buf, flm)
+}
+
+// Handle handles message
+func (flm *FilterLoadMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	flm.c = mc
+	return daemon.(daemoner).recordMessageEvent(flm, mc)
+}
+
+// process loads the bloom filter into the connection
+func (flm *FilterLoadMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   flm.c.Addr,
+		"gnetID": flm.c.ConnID,
+	}
+
+	flags := bloom.UpdateFlags(flm.Flags)
+	f, err := bloom.Load(flm.Filter, flm.HashFuncs, flm.Tweak, flags)
+	if err == nil && flags != bloom.UpdateNone && flags != bloom.UpdateOutputs {
+		err = errors.New("Invalid bloom filter flags")
+	}
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Info("Disconnecting peer for invalid FilterLoadMessage")
+		if err := d.Disconnect(flm.c.Addr, ErrDisconnectInvalidBloomFilter); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Disconnect")
+		}
+		return
+	}
+
+	if err := d.setBloomFilter(flm.c.Addr, flm.c.ConnID, f); err != nil {
+		logger.WithError(err).WithFields(fields).Error("setBloomFilter failed")
+		return
+	}
+
+	logger.WithFields(fields).WithField("size", len(flm.Filter)).Debug("Loaded bloom filter")
+}
+
+// FilterAddMessage is sent by a light client to add an element to the bloom filter loaded into its connection
+type FilterAddMessage struct {
+	Data []byte               `enc:",maxlen=520"`
+	c    *gnet.MessageContext `enc:"-"`
+}
+
+// NewFilterAddMessage creates a FilterAddMessage
+func NewFilterAddMessage(data []byte) *FilterAddMessage {
+	return &FilterAddMessage{
+		Data: data,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (fam *FilterAddMessage) EncodeSize() uint64 {
+	return encodeSizeFilterAddMessage(fam)
+}
+
+// Encode implements gnet.Serializer
+func (fam *FilterAddMessage) Encode(buf []byte) error {
+	return encodeFilterAddMessageToBuffer(buf, fam)
+}
+
+// Decode implements gnet.Serializer
+func (fam *FilterAddMessage) Decode(buf []byte) (uint64, error) {
+	return decodeFilterAddMessage(buf, fam)
+}
+
+// Handle handles message
+func (fam *FilterAddMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	fam.c = mc
+	return daemon.(daemoner).recordMessageEvent(fam, mc)
+}
+
+// process adds the element to the connection's bloom filter
+func (fam *FilterAddMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   fam.c.Addr,
+		"gnetID": fam.c.ConnID,
+	}
+
+	if err := d.addToBloomFilter(fam.c.Addr, fam.c.ConnID, fam.Data); err != nil {
+		switch err {
+		case ErrNoBloomFilter:
+			logger.WithFields(fields).Info("Disconnecting peer for FilterAddMessage without a loaded filter")
+			if err := d.Disconnect(fam.c.Addr, ErrDisconnectInvalidBloomFilter); err != nil {
+				logger.WithError(err).WithFields(fields).Warning("Disconnect")
+			}
+		default:
+			logger.WithError(err).WithFields(fields).Error("addToBloomFilter failed")
+		}
+	}
+}
+
+// FilterClearMessage is sent by a light client to remove the bloom filter from its connection.
+// The connection receives all blocks and transactions again.
+type FilterClearMessage struct {
+	c *gnet.MessageContext `enc:"-"`
+}
+
+// EncodeSize implements gnet.Serializer
+func (fcm *FilterClearMessage) EncodeSize() uint64 {
+	return 0
+}
+
+// Encode implements gnet.Serializer
+func (fcm *FilterClearMessage) Encode(buf []byte) error {
+	return nil
+}
+
+// Decode implements gnet.Serializer
+func (fcm *FilterClearMessage) Decode(buf []byte) (uint64, error) {
+	return 0, nil
+}
+
+// Handle handles message
+func (fcm *FilterClearMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	fcm.c = mc
+	return daemon.(daemoner).recordMessageEvent(fcm, mc)
+}
+
+// process removes the connection's bloom filter
+func (fcm *FilterClearMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	if err := d.setBloomFilter(fcm.c.Addr, fcm.c.ConnID, nil); err != nil {
+		logger.WithError(err).WithFields(logrus.Fields{
+			"addr":   fcm.c.Addr,
+			"gnetID": fcm.c.ConnID,
+		}).Error("setBloomFilter failed")
+	}
+}
+
+// FilteredTransaction is a transaction that matched a bloom filter, with the merkle branch
+// that proves it is included in its block
+type FilteredTransaction struct {
+	Transaction coin.Transaction
+	// Index of the transaction in the block
+	Index uint32
+	// Branch is the merkle branch from the transaction hash to the block's BodyHash, see cipher.VerifyMerkleBranch
+	Branch []cipher.SHA256 `enc:",maxlen=32"`
+}
+
+// FilteredBlock is a signed block header with the transactions of the block that matched a bloom filter.
+// A light client verifies Sig against the header hash and the blockchain pubkey,
+// then verifies each transaction's branch against Head.BodyHash.
+type FilteredBlock struct {
+	Head         coin.BlockHeader
+	Transactions []FilteredTransaction `enc:",maxlen=65535"`
+	Sig          cipher.Sig
+}
+
+// FilteredBlocksMessage is sent instead of GiveBlocksMessage to connections that loaded a bloom filter
+type FilteredBlocksMessage struct {
+	Blocks []FilteredBlock      `enc:",maxlen=128"`
+	c      *gnet.MessageContext `enc:"-"`
+}
+
+// NewFilteredBlocksMessage creates FilteredBlocksMessage.
+// If the size of message would exceed maxMsgLength, the block slice is truncated.
+func NewFilteredBlocksMessage(blocks []FilteredBlock, maxMsgLength uint64) *FilteredBlocksMessage {
+	if len(blocks) > 128 {
+		blocks = blocks[:128]
+	}
+	m := &FilteredBlocksMessage{
+		Blocks: blocks,
+	}
+	truncateFilteredBlocksMessage(m, maxMsgLength)
+	return m
+}
+
+// truncateFilteredBlocksMessage truncates the blocks in FilteredBlocksMessage to fit inside of MaxOutgoingMessageLength
+func truncateFilteredBlocksMessage(m *FilteredBlocksMessage, maxMsgLength uint64) {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return
+	}
+
+	// Measure the size of an empty message
+	var mm FilteredBlocksMessage
+	emptySize := mm.EncodeSize()
+	size := emptySize
+
+	// Measure the size of the blocks, advancing the slice index until it reaches capacity
+	index := -1
+	for i, b := range m.Blocks {
+		x := encodeSizeFilteredBlocksMessage(&FilteredBlocksMessage{
+			Blocks: []FilteredBlock{b},
+		}) - emptySize
+		if size+x > maxMsgLength {
+			break
+		}
+		size += x
+		index = i
+	}
+
+	m.Blocks = m.Blocks[:index+1]
+
+	if len(m.Blocks) == 0 {
+		logger.Critical().Error("truncateFilteredBlocksMessage truncated blocks to an empty slice")
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (fbm *FilteredBlocksMessage) EncodeSize() uint64 {
+	return encodeSizeFilteredBlocksMessage(fbm)
+}
+
+// Encode implements gnet.Serializer
+func (fbm *FilteredBlocksMessage) Encode(buf []byte) error {
+	return encodeFilteredBlocksMessageToBuffer(buf, fbm)
+}
+
+// Decode implements gnet.Serializer
+func (fbm *FilteredBlocksMessage) Decode(buf []byte) (uint64, error) {
+	return decodeFilteredBlocksMessage(buf, fbm)
+}
+
+// Handle handles message
+func (fbm *FilteredBlocksMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	fbm.c = mc
+	return daemon.(daemoner).recordMessageEvent(fbm, mc)
+}
+
+// process ignores the message. Full nodes do not load bloom filters, so they do not expect filtered blocks
+func (fbm *FilteredBlocksMessage) process(d daemoner) {
+	logger.WithFields(logrus.Fields{
+		"addr":   fbm.c.Addr,
+		"gnetID": fbm.c.ConnID,
+		"blocks": len(fbm.Blocks),
+	}).Debug("Ignoring unsolicited FilteredBlocksMessage")
+}
@@ -0,0 +1,177 @@
+package pex
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"../../../src/util/iputil"
+)
+
+// The peer list is split into "new" addresses, that we have heard of, and "tried" addresses,
+// that we have made an outgoing connection to. Each table is made of buckets with a fixed capacity.
+// An address learned from a peer is put in one of the few new buckets chosen by the netgroup of
+// that peer, so a single source can only fill a small part of the new table.
+// An address is moved to one of the few tried buckets chosen by its own netgroup,
+// so the tried table holds addresses from many netgroups.
+// Buckets are chosen by hashing with a secret key, so an attacker can't aim at a bucket.
+// The key is generated on startup, and the buckets are rebuilt when the peer list is loaded.
+const (
+	// newBucketCount is the number of buckets of new addresses
+	newBucketCount = 256
+	// triedBucketCount is the number of buckets of tried addresses
+	triedBucketCount = 64
+	// bucketSize is the number of addresses that fit in a bucket
+	bucketSize = 64
+	// newBucketsPerSourceGroup is the number of new buckets that the addresses sent by a netgroup can be put in
+	newBucketsPerSourceGroup = 4
+	// triedBucketsPerGroup is the number of tried buckets that the addresses of a netgroup can be put in
+	triedBucketsPerGroup = 4
+)
+
+// NetGroup returns the network group of an address. Connections to peers in different
+// network groups are unlikely to be controlled by the same attacker.
+// Public IPv4 addresses are grouped by /16 and public IPv6 addresses by /32.
+// Loopback and private addresses are not grouped, since they are not reachable from the internet.
+// Onion addresses are grouped by their first character
+func NetGroup(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if iputil.IsOnion(host) {
+		return "onion:" + host[:1]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+
+	if ip.IsLoopback() {
+		return addr
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		if isPrivateIPv4(ip4) {
+			return host
+		}
+		return ip4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+
+	// Unique local addresses, fc00::/7
+	if ip[0]&0xfe == 0xfc {
+		return host
+	}
+
+	return ip.Mask(net.CIDRMask(32, 128)).String() + "/32"
+}
+
+// isPrivateIPv4 returns true for the RFC 1918 private ranges
+func isPrivateIPv4(ip net.IP) bool {
+	return ip[0] == 10 ||
+		(ip[0] == 172 && ip[1]&0xf0 == 16) ||
+		(ip[0] == 192 && ip[1] == 168)
+}
+
+// buckets indexes the addresses of the peer list by bucket
+type buckets struct {
+	key   [32]byte
+	new   [newBucketCount]map[string]struct{}
+	tried [triedBucketCount]map[string]struct{}
+	index map[string]bucketRef
+}
+
+// bucketRef identifies the bucket of an address
+type bucketRef struct {
+	tried bool
+	i     int
+}
+
+func newBuckets() buckets {
+	var b buckets
+	if _, err := rand.Read(b.key[:]); err != nil {
+		logger.WithError(err).Panic("Failed to generate the peer bucket key")
+	}
+
+	for i := range b.new {
+		b.new[i] = make(map[string]struct{})
+	}
+	for i := range b.tried {
+		b.tried[i] = make(map[string]struct{})
+	}
+	b.index = make(map[string]bucketRef)
+
+	return b
+}
+
+// hash hashes strings with the secret key
+func (b *buckets) hash(s ...interface{}) uint64 {
+	h := sha256.New()
+	h.Write(b.key[:]) // nolint: errcheck
+	for _, x := range s {
+		fmt.Fprintf(h, "%v\x00", x)
+	}
+	return binary.LittleEndian.Uint64(h.Sum(nil))
+}
+
+// newBucket returns the new bucket of an address sent by a peer in sourceGroup.
+// Addresses that were not sent by a peer are treated as if they were sent by their own netgroup
+func (b *buckets) newBucket(addr, sourceGroup string) int {
+	group := NetGroup(addr)
+	if sourceGroup == "" {
+		sourceGroup = group
+	}
+	n := b.hash(group, sourceGroup) % newBucketsPerSourceGroup
+	return int(b.hash(sourceGroup, n) % newBucketCount)
+}
+
+// triedBucket returns the tried bucket of an address
+func (b *buckets) triedBucket(addr string) int {
+	n := b.hash(addr) % triedBucketsPerGroup
+	return int(b.hash(NetGroup(addr), n) % triedBucketCount)
+}
+
+// bucket returns the bucket that the peer belongs in
+func (b *buckets) bucket(p *Peer) map[string]struct{} {
+	if p.Tried {
+		return b.tried[b.triedBucket(p.Addr)]
+	}
+	return b.new[b.newBucket(p.Addr, p.Source)]
+}
+
+// insert adds a peer to its bucket, even if the bucket is full
+func (b *buckets) insert(p *Peer) {
+	b.remove(p.Addr)
+
+	ref := bucketRef{tried: p.Tried}
+	if p.Tried {
+		ref.i = b.triedBucket(p.Addr)
+	} else {
+		ref.i = b.newBucket(p.Addr, p.Source)
+	}
+
+	b.get(ref)[p.Addr] = struct{}{}
+	b.index[p.Addr] = ref
+}
+
+// remove removes an address from its bucket
+func (b *buckets) remove(addr string) {
+	ref, ok := b.index[addr]
+	if !ok {
+		return
+	}
+
+	delete(b.get(ref), addr)
+	delete(b.index, addr)
+}
+
+func (b *buckets) get(ref bucketRef) map[string]struct{} {
+	if ref.tried {
+		return b.tried[ref.i]
+	}
+	return b.new[ref.i]
+}
@@ -0,0 +1,99 @@
+package pex
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"../../../src/util/iputil"
+)
+
+// Resolver resolves hostnames to IP addresses. *net.Resolver satisfies this interface.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolver returns the configured Resolver, or net.DefaultResolver if none is set
+func (px *Pex) resolver() Resolver {
+	if px.Config.Resolver != nil {
+		return px.Config.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// dnsSeedsEnabled returns true if the DNS seeds should be queried
+func (px *Pex) dnsSeedsEnabled() bool {
+	return len(px.Config.DNSSeeds) != 0 && !px.Config.Disabled && !px.Config.NetworkDisabled
+}
+
+// isLow returns true if the peer list has fewer than DNSSeedMinPeers peers
+func (px *Pex) isLow() bool {
+	px.RLock()
+	defer px.RUnlock()
+	return px.peerlist.len() < px.Config.DNSSeedMinPeers
+}
+
+// resolveDNSSeeds looks up the A and AAAA records of the DNS seeds and adds the results to the peer list.
+// The lookups stop when ctx is canceled. Returns the number of peers added.
+func (px *Pex) resolveDNSSeeds(ctx context.Context) int {
+	// Resolving the seeds would reveal the node to the DNS server, bypassing the proxy
+	if px.Config.Proxy != "" {
+		logger.Info("Proxy is configured, not resolving DNS seeds")
+		return 0
+	}
+
+	var peers []string
+	for _, seed := range px.Config.DNSSeeds {
+		if ctx.Err() != nil {
+			return 0
+		}
+
+		addrs, err := resolveDNSSeed(ctx, px.resolver(), seed, px.Config.DNSSeedPort, px.Config.DNSSeedTimeout)
+		if err != nil {
+			logger.WithError(err).WithField("seed", seed).Warning("Failed to resolve DNS seed")
+			continue
+		}
+
+		logger.WithField("seed", seed).Infof("Resolved DNS seed, got %d peers", len(addrs))
+		peers = append(peers, addrs...)
+	}
+
+	n := px.AddPeers(peers)
+	logger.WithField("seeds", len(px.Config.DNSSeeds)).Infof("Added %d/%d peers from DNS seeds", n, len(peers))
+
+	return n
+}
+
+// resolveDNSSeed resolves a DNS seed to a list of ip:port addresses.
+// The seed is a hostname, optionally with a port. If the port is omitted, defaultPort is used.
+func resolveDNSSeed(ctx context.Context, r Resolver, seed string, defaultPort uint16, timeout time.Duration) ([]string, error) {
+	host := seed
+	port := defaultPort
+	if h, p, err := net.SplitHostPort(seed); err == nil {
+		port64, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, ErrInvalidAddress
+		}
+		host = h
+		port = uint16(port64)
+	}
+
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ips, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, iputil.JoinAddr(ip.IP.String(), port))
+	}
+
+	return addrs, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"../../../src/util/file"
+	"../../../src/util/iputil"
 	"../../../src/util/useragent"
 )
 
@@ -26,14 +27,16 @@ func (ps Peers) ToAddrs() []string {
 	return addrs
 }
 
-// peerlist is a map of addresses to *PeerStates
+// peerlist is a map of addresses to *PeerStates, indexed by buckets
 type peerlist struct {
-	peers map[string]*Peer
+	peers   map[string]*Peer
+	buckets buckets
 }
 
 func newPeerlist() peerlist {
 	return peerlist{
-		peers: make(map[string]*Peer),
+		peers:   make(map[string]*Peer),
+		buckets: newBuckets(),
 	}
 }
 
@@ -94,6 +97,7 @@ func (pl *peerlist) setPeers(peers []Peer) {
 	for _, p := range peers {
 		np := p
 		pl.peers[p.Addr] = &np
+		pl.buckets.insert(&np)
 	}
 }
 
@@ -110,6 +114,77 @@ func (pl *peerlist) addPeer(addr string) {
 
 	peer := NewPeer(addr)
 	pl.peers[addr] = peer
+	pl.buckets.insert(peer)
+}
+
+// addPeerFrom adds an address sent by a peer in sourceGroup. If the address's bucket is full,
+// the oldest peer in it is removed to make room.
+// Returns false if no room could be made
+func (pl *peerlist) addPeerFrom(addr, sourceGroup string) bool {
+	if p, ok := pl.peers[addr]; ok && p != nil {
+		p.Seen()
+		return true
+	}
+
+	peer := NewPeer(addr)
+	peer.Source = sourceGroup
+
+	if b := pl.buckets.bucket(peer); len(b) >= bucketSize {
+		oldest := pl.oldestInBucket(b)
+		if oldest == nil {
+			return false
+		}
+		pl.removePeer(oldest.Addr)
+	}
+
+	pl.peers[addr] = peer
+	pl.buckets.insert(peer)
+	return true
+}
+
+// markTried moves a peer to the tried table. If its tried bucket is full, the oldest peer
+// in the bucket is moved back to the new table to make room
+func (pl *peerlist) markTried(addr string) {
+	p, ok := pl.peers[addr]
+	if !ok || p.Tried {
+		return
+	}
+
+	// Take p out of its new bucket first, so that it is not evicted to make room for a demoted peer
+	pl.buckets.remove(p.Addr)
+	p.Tried = true
+
+	if b := pl.buckets.bucket(p); len(b) >= bucketSize {
+		if oldest := pl.oldestInBucket(b); oldest != nil {
+			oldest.Tried = false
+			pl.buckets.remove(oldest.Addr)
+
+			if nb := pl.buckets.bucket(oldest); len(nb) >= bucketSize {
+				if o := pl.oldestInBucket(nb); o != nil {
+					pl.removePeer(o.Addr)
+				}
+			}
+			pl.buckets.insert(oldest)
+		}
+	}
+
+	pl.buckets.insert(p)
+}
+
+// oldestInBucket returns the public untrusted peer in a bucket that was seen the longest time ago
+func (pl *peerlist) oldestInBucket(b map[string]struct{}) *Peer {
+	var oldest *Peer
+	for addr := range b {
+		p := pl.peers[addr]
+		if p == nil || p.Trusted || p.Private {
+			continue
+		}
+
+		if oldest == nil || p.LastSeen < oldest.LastSeen {
+			oldest = p
+		}
+	}
+	return oldest
 }
 
 func (pl *peerlist) addPeers(addrs []string) {
@@ -181,12 +256,26 @@ func canTry(p Peer) bool {
 	return p.CanTry()
 }
 
-// isExchangeable filters exchangeable peers
-var isExchangeable = []Filter{hasIncomingPort, isPublic}
+func isTried(p Peer) bool {
+	return p.Tried
+}
+
+func isNew(p Peer) bool {
+	return !p.Tried
+}
+
+func isNotOnion(p Peer) bool {
+	return !iputil.IsOnionAddr(p.Addr)
+}
+
+// isExchangeable filters exchangeable peers.
+// Onion peers can not be represented in a GivePeersMessage, so they are not exchanged.
+var isExchangeable = []Filter{hasIncomingPort, isPublic, isNotOnion}
 
 // removePeer removes peer
 func (pl *peerlist) removePeer(addr string) {
 	delete(pl.peers, addr)
+	pl.buckets.remove(addr)
 }
 
 // SetPrivate sets specific peer as private
@@ -209,10 +298,21 @@ func (pl *peerlist) setTrusted(addr string, trusted bool) error {
 	return fmt.Errorf("set peer.Trusted failed: %v does not exist in peer list", addr)
 }
 
-// setAllUntrusted unsets the trusted field on all peers
+// setUserTrusted sets the trusted and user trusted fields of a peer
+func (pl *peerlist) setUserTrusted(addr string, trusted bool) error {
+	if p, ok := pl.peers[addr]; ok {
+		p.Trusted = trusted
+		p.UserTrusted = trusted
+		return nil
+	}
+
+	return fmt.Errorf("set peer.UserTrusted failed: %v does not exist in peer list", addr)
+}
+
+// setAllUntrusted unsets the trusted field on all peers, except the peers trusted by the user
 func (pl *peerlist) setAllUntrusted() {
 	for _, p := range pl.peers {
-		p.Trusted = false
+		p.Trusted = p.UserTrusted
 	}
 }
 
@@ -258,7 +358,7 @@ func (pl *peerlist) clearOld(timeAgo time.Duration) {
 	for addr, peer := range pl.peers {
 		lastSeen := time.Unix(peer.LastSeen, 0)
 		if !peer.Private && !peer.Trusted && t.Sub(lastSeen) > timeAgo {
-			delete(pl.peers, addr)
+			pl.removePeer(addr)
 		}
 	}
 }
@@ -355,9 +455,13 @@ type PeerJSON struct {
 	LastSeen        interface{}
 	Private         bool  // Whether it should omitted from public requests
 	Trusted         bool  // Whether this peer is trusted
+	UserTrusted     bool  // Whether this peer was marked trusted by the user
 	HasIncomePort   *bool `json:"HasIncomePort,omitempty"` // Whether this peer has incoming port [DEPRECATED]
 	HasIncomingPort *bool // Whether this peer has incoming port
 	UserAgent       useragent.Data
+	Stats           PeerStats
+	Tried           bool   // Whether an outgoing connection to this peer has succeeded
+	Source          string // NetGroup of the peer that sent us this address
 }
 
 // newPeerJSON returns a PeerJSON from a Peer
@@ -367,8 +471,12 @@ func newPeerJSON(p Peer) PeerJSON {
 		LastSeen:        p.LastSeen,
 		Private:         p.Private,
 		Trusted:         p.Trusted,
+		UserTrusted:     p.UserTrusted,
 		HasIncomingPort: &p.HasIncomingPort,
 		UserAgent:       p.UserAgent,
+		Stats:           p.Stats,
+		Tried:           p.Tried,
+		Source:          p.Source,
 	}
 }
 
@@ -411,7 +519,11 @@ func newPeerFromJSON(p PeerJSON) (*Peer, error) {
 		LastSeen:        lastSeen,
 		Private:         p.Private,
 		Trusted:         p.Trusted,
+		UserTrusted:     p.UserTrusted,
 		HasIncomingPort: hasIncomingPort,
 		UserAgent:       p.UserAgent,
+		Stats:           p.Stats,
+		Tried:           p.Tried,
+		Source:          p.Source,
 	}, nil
 }
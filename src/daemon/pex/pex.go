@@ -2,6 +2,7 @@
 package pex
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -9,6 +10,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -20,6 +22,7 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/sirupsen/logrus"
 
+	"../../../src/util/iputil"
 	"../../../src/util/logging"
 	"../../../src/util/useragent"
 )
@@ -55,36 +58,46 @@ var (
 	ErrPortTooLow = errors.New("Port must be >= 1024")
 	// ErrBlacklistedAddress returned when attempting to add a blacklisted peer
 	ErrBlacklistedAddress = errors.New("Blacklisted address")
+	// ErrPeerNotFound is returned when a peer is not in the peer list
+	ErrPeerNotFound = errors.New("Peer not found")
 
 	// Logging. See http://godoc.org/github.com/op/go-logging for
 	// instructions on how to include this log's output
 	logger = logging.MustGetLogger("pex")
-	// Default rng
-	rnum = rand.New(rand.NewSource(time.Now().Unix()))
 	// For removing inadvertent whitespace from addresses
 	whitespaceFilter = regexp.MustCompile(`\s`)
 )
 
-// validateAddress returns a sanitized address if valid, otherwise an error
+// validateAddress returns a sanitized address if valid, otherwise an error.
+// IPv6 addresses must be enclosed in brackets, e.g. "[2001:db8::1]:6000".
 func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 	ipPort = whitespaceFilter.ReplaceAllString(ipPort, "")
-	pts := strings.Split(ipPort, ":")
-	if len(pts) != 2 {
+	host, portStr, err := net.SplitHostPort(ipPort)
+	if err != nil || host == "" {
 		return "", ErrInvalidAddress
 	}
 
-	ip := net.ParseIP(pts[0])
-	if ip == nil {
-		return "", ErrInvalidAddress
-	} else if ip.IsLoopback() {
-		if !allowLocalhost {
-			return "", ErrNoLocalhost
+	// Onion addresses are hostnames, not IPs, so the IP checks do not apply to them
+	if iputil.IsOnion(host) {
+		host = strings.ToLower(host)
+	} else {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return "", ErrInvalidAddress
+		} else if ip.IsLoopback() {
+			if !allowLocalhost {
+				return "", ErrNoLocalhost
+			}
+		} else if !ip.IsGlobalUnicast() {
+			return "", ErrNotExternalIP
 		}
-	} else if !ip.IsGlobalUnicast() {
-		return "", ErrNotExternalIP
+
+		// Use the canonical form of the IP, so that the same peer is not
+		// added twice with different representations of an IPv6 address
+		host = ip.String()
 	}
 
-	port, err := strconv.ParseUint(pts[1], 10, 16)
+	port, err := strconv.ParseUint(portStr, 10, 16)
 	if err != nil {
 		return "", ErrInvalidAddress
 	}
@@ -93,7 +106,7 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", ErrPortTooLow
 	}
 
-	return ipPort, nil
+	return iputil.JoinAddr(host, uint16(port)), nil
 }
 
 // Peer represents a known peer
@@ -102,8 +115,12 @@ type Peer struct {
 	LastSeen        int64          // Unix timestamp when this peer was last seen
 	Private         bool           // Whether it should omitted from public requests
 	Trusted         bool           // Whether this peer is trusted
+	UserTrusted     bool           // Whether this peer was marked trusted with SetUserTrusted. It stays trusted across restarts
 	HasIncomingPort bool           // Whether this peer has accessible public port
 	UserAgent       useragent.Data // Peer's last reported user agent
+	Stats           PeerStats      // Latency and reliability of the connections to this peer
+	Tried           bool           // Whether an outgoing connection to this peer has succeeded
+	Source          string         // NetGroup of the peer that sent us this address, empty if it was not sent by a peer
 	RetryTimes      int            `json:"-"` // records the retry times
 }
 
@@ -147,7 +164,7 @@ func (peer *Peer) CanTry() bool {
 
 	// Random time elapsed
 	now := time.Now().UTC().Unix()
-	t := rnum.Int63n(int64(mod))
+	t := rand.Int63n(int64(mod)) // math/rand's global source is safe for concurrent use
 	return now-peer.LastSeen > t
 }
 
@@ -184,6 +201,20 @@ type Config struct {
 	DownloadPeerList bool
 	// Download peers list from this URL
 	PeerListURL string
+	// Download the peers list through this proxy (socks5://host:port)
+	Proxy string
+	// DNS seed hostnames whose A and AAAA records are added as peers, optionally with a port
+	DNSSeeds []string
+	// Port of the peers returned by a DNS seed that does not specify a port
+	DNSSeedPort uint16
+	// Query the DNS seeds when the peer list has fewer peers than this
+	DNSSeedMinPeers int
+	// How often to check if the peer list is low and the DNS seeds should be queried
+	DNSSeedRate time.Duration
+	// Timeout of a DNS seed lookup
+	DNSSeedTimeout time.Duration
+	// Resolver used to look up the DNS seeds. If nil, net.DefaultResolver is used
+	Resolver Resolver
 	// Set all peers as untrusted (even if loaded from DefaultConnections)
 	DisableTrustedPeers bool
 	// Load peers from this file on disk. NOTE: this is different from the peers file cache in the data directory
@@ -208,6 +239,10 @@ func NewConfig() Config {
 		NetworkDisabled:     false,
 		DownloadPeerList:    true,
 		PeerListURL:         DefaultPeerListURL,
+		DNSSeedPort:         6000,
+		DNSSeedMinPeers:     32,
+		DNSSeedRate:         time.Minute * 5,
+		DNSSeedTimeout:      time.Second * 10,
 		DisableTrustedPeers: false,
 		CustomPeersFile:     "",
 	}
@@ -239,7 +274,7 @@ func New(cfg Config) (*Pex, error) {
 	}
 
 	// Unset trusted status from any existing peers, regenerate
-	// them from the DefaultConnections
+	// them from the DefaultConnections. Peers trusted with SetUserTrusted stay trusted
 	pex.setAllUntrusted()
 
 	// Load default hardcoded peers, mark them as trusted
@@ -249,14 +284,14 @@ func New(cfg Config) (*Pex, error) {
 			logger.Critical().WithError(err).Error("Add default peer failed")
 			return nil, err
 		}
-		if err := pex.setTrusted(addr); err != nil {
-			logger.Critical().WithError(err).Error("pex.setTrusted for default peer failed")
+		if err := pex.SetTrusted(addr, true); err != nil {
+			logger.Critical().WithError(err).Error("pex.SetTrusted for default peer failed")
 			return nil, err
 		}
 	}
 
 	if cfg.DisableTrustedPeers {
-		// Unset trusted status from any existing peers
+		// Unset trusted status from the default peers
 		pex.setAllUntrusted()
 	}
 
@@ -301,8 +336,35 @@ func (px *Pex) Run() error {
 
 	clearOldTicker := time.NewTicker(px.Config.ClearOldRate)
 
+	// The DNS seed lookups are canceled and waited for when Run returns
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	// Resolve the DNS seeds in the background, then again if the peer list runs low
+	var dnsSeedTicker <-chan time.Time
+	if px.dnsSeedsEnabled() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			px.resolveDNSSeeds(ctx)
+		}()
+
+		if px.Config.DNSSeedRate > 0 {
+			t := time.NewTicker(px.Config.DNSSeedRate)
+			defer t.Stop()
+			dnsSeedTicker = t.C
+		}
+	}
+
 	for {
 		select {
+		case <-dnsSeedTicker:
+			if px.isLow() {
+				logger.Info("Peer list is low, resolving DNS seeds")
+				px.resolveDNSSeeds(ctx)
+			}
 		case <-clearOldTicker.C:
 			// Remove peers we haven't seen in a while
 			if !px.Config.Disabled && !px.Config.NetworkDisabled {
@@ -327,7 +389,7 @@ func (px *Pex) Shutdown() {
 }
 
 func (px *Pex) downloadPeers() error {
-	body, err := backoffDownloadText(px.Config.PeerListURL)
+	body, err := backoffDownloadText(px.Config.PeerListURL, px.Config.Proxy)
 	if err != nil {
 		logger.WithError(err).WithField("url", px.Config.PeerListURL).Error("Failed to download peers")
 		return err
@@ -469,9 +531,40 @@ func (px *Pex) AddPeers(addrs []string) int {
 	px.Lock()
 	defer px.Unlock()
 
+	addrs = px.prepareAddrs(addrs)
+	px.peerlist.addPeers(addrs)
+	return len(addrs)
+}
+
+// AddPeersFrom adds peers sent by another peer, such as in a GivePeersMessage.
+// Unlike AddPeers, the peers are put in the buckets of the sender's netgroup, and peers
+// that don't fit in their bucket are not added, see buckets.
+// Returns the number of peers that were added or already known
+func (px *Pex) AddPeersFrom(source string, addrs []string) int {
+	px.Lock()
+	defer px.Unlock()
+
+	sourceGroup := NetGroup(source)
+
+	n := 0
+	for _, a := range px.prepareAddrs(addrs) {
+		if px.peerlist.addPeerFrom(a, sourceGroup) {
+			n++
+		}
+	}
+
+	if n != len(addrs) {
+		logger.WithField("source", source).Debugf("Added %d/%d peers", n, len(addrs))
+	}
+
+	return n
+}
+
+// prepareAddrs validates and shuffles addresses to be added, and caps them to the room left in the peer list
+func (px *Pex) prepareAddrs(addrs []string) []string {
 	if px.Config.Max > 0 && px.peerlist.len() >= px.Config.Max {
 		logger.Warning("Add peers failed, peer list is full")
-		return 0
+		return nil
 	}
 
 	// validate the addresses
@@ -498,8 +591,14 @@ func (px *Pex) AddPeers(addrs []string) int {
 		}
 	}
 
-	px.peerlist.addPeers(addrs)
-	return len(addrs)
+	return addrs
+}
+
+// MarkTried moves a peer to the tried table, after an outgoing connection to it has succeeded
+func (px *Pex) MarkTried(addr string) {
+	px.Lock()
+	defer px.Unlock()
+	px.peerlist.markTried(addr)
 }
 
 // SetPrivate updates peer's private value
@@ -516,8 +615,8 @@ func (px *Pex) SetPrivate(addr string, private bool) error {
 	return px.peerlist.setPrivate(cleanAddr, private)
 }
 
-// setTrusted marks a peer as a default peer by setting its trusted flag to true
-func (px *Pex) setTrusted(addr string) error {
+// SetTrusted updates peer's trusted value
+func (px *Pex) SetTrusted(addr string, trusted bool) error {
 	px.Lock()
 	defer px.Unlock()
 
@@ -527,10 +626,25 @@ func (px *Pex) setTrusted(addr string) error {
 		return ErrInvalidAddress
 	}
 
-	return px.peerlist.setTrusted(cleanAddr, true)
+	return px.peerlist.setTrusted(cleanAddr, trusted)
 }
 
-// setAllUntrusted unsets the trusted field on all peers
+// SetUserTrusted updates peer's trusted value on behalf of the user, e.g. through the API.
+// Unlike SetTrusted, the value is kept across restarts
+func (px *Pex) SetUserTrusted(addr string, trusted bool) error {
+	px.Lock()
+	defer px.Unlock()
+
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	if err != nil {
+		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+		return ErrInvalidAddress
+	}
+
+	return px.peerlist.setUserTrusted(cleanAddr, trusted)
+}
+
+// setAllUntrusted unsets the trusted field on all peers, except the peers trusted with SetUserTrusted
 func (px *Pex) setAllUntrusted() {
 	px.Lock()
 	defer px.Unlock()
@@ -579,6 +693,11 @@ func (px *Pex) RemovePeer(addr string) {
 	px.peerlist.removePeer(addr)
 }
 
+// CleanAddr validates an address and returns it in the form that is used in the peer list
+func (px *Pex) CleanAddr(addr string) (string, error) {
+	return validateAddress(addr, px.Config.AllowLocalhost)
+}
+
 // GetPeer returns peer of given address
 func (px *Pex) GetPeer(addr string) (Peer, bool) {
 	px.RLock()
@@ -586,6 +705,13 @@ func (px *Pex) GetPeer(addr string) (Peer, bool) {
 	return px.peerlist.getPeer(addr)
 }
 
+// All returns all peers
+func (px *Pex) All() Peers {
+	px.RLock()
+	defer px.RUnlock()
+	return px.peerlist.getPeers(nil)
+}
+
 // Trusted returns trusted peers
 func (px *Pex) Trusted() Peers {
 	px.RLock()
@@ -655,11 +781,24 @@ func (px *Pex) isFull() bool {
 	return px.Config.Max > 0 && px.peerlist.len() >= px.Config.Max
 }
 
-// downloadText downloads a text format file from url.
+// downloadText downloads a text format file from url, through proxyURL if not empty.
 // Returns the raw response body as a string.
 // TODO -- move to util, add backoff options
-func downloadText(url string) (string, error) {
-	resp, err := http.Get(url) //nolint:gosec
+func downloadText(url, proxyURL string) (string, error) {
+	client := http.DefaultClient
+	if proxyURL != "" {
+		proxy, err := neturl.Parse(proxyURL)
+		if err != nil {
+			return "", err
+		}
+		client = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyURL(proxy),
+			},
+		}
+	}
+
+	resp, err := client.Get(url) //nolint:gosec
 	if err != nil {
 		return "", err
 	}
@@ -673,7 +812,7 @@ func downloadText(url string) (string, error) {
 	return string(body), nil
 }
 
-func backoffDownloadText(url string) (string, error) {
+func backoffDownloadText(url, proxyURL string) (string, error) {
 	var body string
 	if len(url) == 0 {
 		return body, nil
@@ -688,7 +827,7 @@ func backoffDownloadText(url string) (string, error) {
 	operation := func() error {
 		logger.WithField("url", url).Info("Trying to download peers list")
 		var err error
-		body, err = downloadText(url)
+		body, err = downloadText(url, proxyURL)
 		return err
 	}
 
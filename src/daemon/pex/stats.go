@@ -0,0 +1,216 @@
+package pex
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// rttSmoothing is the weight of a new sample in the moving average of the RTT, as in TCP's SRTT
+	rttSmoothing = 0.125
+	// referenceRTT is the RTT that halves a peer's latency score
+	referenceRTT = 250 * time.Millisecond
+	// referenceUptime is the average connection uptime that halves a peer's stability score
+	referenceUptime = 10 * time.Minute
+	// minScore is the lowest score used when choosing peers, so that every peer has a chance to be chosen
+	minScore = 0.01
+)
+
+// PeerStats are statistics about the connections made to a peer, used to prefer fast and reliable peers
+type PeerStats struct {
+	// RTT is the moving average of the ping round-trip time
+	RTT time.Duration
+	// Uptime is the total time spent connected to the peer
+	Uptime time.Duration
+	// Connections is the number of connections to the peer that have ended
+	Connections uint64
+	// BlocksRequested is the number of block requests sent to the peer while it reported more blocks than us
+	BlocksRequested uint64
+	// BlocksDelivered is the number of those requests that the peer answered with blocks
+	BlocksDelivered uint64
+	// Disconnects counts the reasons that the connections to the peer ended.
+	// The map is replaced rather than modified, so copies of PeerStats can be read without a lock
+	Disconnects map[string]uint64
+}
+
+// Score rates the peer between 0 and 1, from its latency, block delivery and connection stability.
+// Each of the three factors is 0.5 for a peer without stats
+func (s PeerStats) Score() float64 {
+	latency := 0.5
+	if s.RTT > 0 {
+		latency = float64(referenceRTT) / float64(referenceRTT+s.RTT)
+	}
+
+	delivered := s.BlocksDelivered
+	if delivered > s.BlocksRequested {
+		delivered = s.BlocksRequested
+	}
+	delivery := float64(delivered+1) / float64(s.BlocksRequested+2)
+
+	stability := 0.5
+	if s.Connections > 0 {
+		avg := s.Uptime / time.Duration(s.Connections)
+		stability = float64(avg) / float64(avg+referenceUptime)
+	}
+
+	return latency * delivery * stability
+}
+
+// recordRTT adds a round-trip time sample to the moving average
+func (s *PeerStats) recordRTT(rtt time.Duration) {
+	if s.RTT == 0 {
+		s.RTT = rtt
+		return
+	}
+	s.RTT += time.Duration(rttSmoothing * float64(rtt-s.RTT))
+}
+
+// recordDisconnect records the end of a connection
+func (s *PeerStats) recordDisconnect(uptime time.Duration, reason string) {
+	s.Connections++
+	s.Uptime += uptime
+
+	disconnects := make(map[string]uint64, len(s.Disconnects)+1)
+	for k, v := range s.Disconnects {
+		disconnects[k] = v
+	}
+	disconnects[reason]++
+	s.Disconnects = disconnects
+}
+
+// RecordRTT records a ping round-trip time of a peer
+func (px *Pex) RecordRTT(addr string, rtt time.Duration) {
+	px.Lock()
+	defer px.Unlock()
+
+	if p, ok := px.peerlist.peers[addr]; ok {
+		p.Stats.recordRTT(rtt)
+	}
+}
+
+// RecordDisconnect records the uptime of a connection to a peer and the reason that it ended
+func (px *Pex) RecordDisconnect(addr string, uptime time.Duration, reason string) {
+	px.Lock()
+	defer px.Unlock()
+
+	if p, ok := px.peerlist.peers[addr]; ok {
+		p.Stats.recordDisconnect(uptime, reason)
+	}
+}
+
+// RecordBlockRequest records that blocks were requested from a peer
+func (px *Pex) RecordBlockRequest(addr string) {
+	px.Lock()
+	defer px.Unlock()
+
+	if p, ok := px.peerlist.peers[addr]; ok {
+		p.Stats.BlocksRequested++
+	}
+}
+
+// RecordBlockDelivery records that a peer answered a block request
+func (px *Pex) RecordBlockDelivery(addr string) {
+	px.Lock()
+	defer px.Unlock()
+
+	if p, ok := px.peerlist.peers[addr]; ok {
+		p.Stats.BlocksDelivered++
+	}
+}
+
+// BestPublic returns N public untrusted peers, chosen randomly with a preference for peers with a higher Score.
+// Peers without stats can still be chosen, so that new peers are tried.
+// Tried and new peers are equally likely to be chosen, so that addresses sent by attackers to fill
+// the new table don't crowd out the peers that we have connected to before.
+// If n is 0, all of the peers are returned
+func (px *Pex) BestPublic(n int) Peers {
+	px.RLock()
+	defer px.RUnlock()
+
+	// Each table can supply all n peers, so only n peers need to be sampled from each
+	tried := px.peerlist.weighted(n, []Filter{isPublic, isTried})
+	untried := px.peerlist.weighted(n, []Filter{isPublic, isNew})
+
+	if n == 0 || n > len(tried)+len(untried) {
+		n = len(tried) + len(untried)
+	}
+
+	ps := make(Peers, 0, n)
+	for len(ps) < n {
+		if len(untried) == 0 || (len(tried) != 0 && rand.Intn(2) == 0) {
+			ps = append(ps, tried[0])
+			tried = tried[1:]
+		} else {
+			ps = append(ps, untried[0])
+			untried = untried[1:]
+		}
+	}
+
+	return ps
+}
+
+// weighted returns n peers sampled without replacement, weighted by their Score.
+// If count is 0, all of the peers are returned
+func (pl *peerlist) weighted(count int, flts []Filter) Peers {
+	ps := pl.getCanTryPeers(flts)
+	if len(ps) == 0 {
+		return Peers{}
+	}
+
+	// Weighted random sampling: take the peers with the largest u^(1/w) for a uniform random u (Efraimidis and Spirakis)
+	keyed := make([]keyedPeer, len(ps))
+	for i, p := range ps {
+		w := math.Max(p.Stats.Score(), minScore)
+		keyed[i] = keyedPeer{peer: p, key: math.Pow(rand.Float64(), 1/w)}
+	}
+
+	// Keep the count largest keys in a min-heap, instead of sorting every peer
+	if count > 0 && count < len(keyed) {
+		h := keyedPeerHeap(keyed[:count])
+		heap.Init(&h)
+		for _, kp := range keyed[count:] {
+			if kp.key > h[0].key {
+				h[0] = kp
+				heap.Fix(&h, 0)
+			}
+		}
+		keyed = h
+	}
+
+	sort.Slice(keyed, func(i, j int) bool {
+		return keyed[i].key > keyed[j].key
+	})
+
+	ps = ps[:len(keyed)]
+	for i, kp := range keyed {
+		ps[i] = kp.peer
+	}
+	return ps
+}
+
+// keyedPeer is a peer with its weighted sampling key
+type keyedPeer struct {
+	peer Peer
+	key  float64
+}
+
+// keyedPeerHeap is a min-heap of keyedPeers, ordered by key
+type keyedPeerHeap []keyedPeer
+
+func (h keyedPeerHeap) Len() int           { return len(h) }
+func (h keyedPeerHeap) Less(i, j int) bool { return h[i].key < h[j].key }
+func (h keyedPeerHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keyedPeerHeap) Push(x interface{}) {
+	*h = append(*h, x.(keyedPeer))
+}
+
+func (h *keyedPeerHeap) Pop() interface{} {
+	old := *h
+	kp := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return kp
+}
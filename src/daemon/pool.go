@@ -10,6 +10,10 @@ import (
 type PoolConfig struct {
 	// Timeout when trying to connect to new peers through the pool
 	DialTimeout time.Duration
+	// Dial outgoing connections through this proxy (socks5://host:port)
+	Proxy string
+	// Transport used to listen and dial, instead of TCP. Used to run nodes over a simulated network
+	Transport gnet.Transport
 	// How often to process message buffers and generate events
 	MessageHandlingRate time.Duration
 	// How long to wait before sending another ping
@@ -34,6 +38,16 @@ type PoolConfig struct {
 	MaxIncomingMessageLength int
 	// Maximum length of outgoing messages in bytes
 	MaxOutgoingMessageLength int
+	// Maximum upload rate of all connections combined, in bytes per second. 0 is unlimited
+	MaxUploadRate int
+	// Maximum download rate of all connections combined, in bytes per second. 0 is unlimited
+	MaxDownloadRate int
+	// Maximum upload rate of each connection, in bytes per second. 0 is unlimited
+	MaxConnectionUploadRate int
+	// Maximum download rate of each connection, in bytes per second. 0 is unlimited
+	MaxConnectionDownloadRate int
+	// Messages longer than this are sent compressed to peers that accept compressed messages. 0 disables compression
+	CompressionThreshold int
 	// These should be assigned by the controlling daemon
 	address string
 	port    int
@@ -56,6 +70,7 @@ func NewPoolConfig() PoolConfig {
 		MaxDefaultPeerOutgoingConnections: 1,
 		MaxOutgoingMessageLength:          256 * 1024,
 		MaxIncomingMessageLength:          1024 * 1024,
+		CompressionThreshold:              1024,
 	}
 }
 
@@ -69,6 +84,8 @@ type Pool struct {
 func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	gnetCfg := gnet.NewConfig()
 	gnetCfg.DialTimeout = cfg.DialTimeout
+	gnetCfg.Proxy = cfg.Proxy
+	gnetCfg.Transport = cfg.Transport
 	gnetCfg.Port = uint16(cfg.port)
 	gnetCfg.Address = cfg.address
 	gnetCfg.ConnectCallback = d.onGnetConnect
@@ -80,6 +97,11 @@ func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	gnetCfg.DefaultConnections = cfg.DefaultConnections
 	gnetCfg.MaxIncomingMessageLength = cfg.MaxIncomingMessageLength
 	gnetCfg.MaxOutgoingMessageLength = cfg.MaxOutgoingMessageLength
+	gnetCfg.MaxUploadRate = cfg.MaxUploadRate
+	gnetCfg.MaxDownloadRate = cfg.MaxDownloadRate
+	gnetCfg.MaxConnectionUploadRate = cfg.MaxConnectionUploadRate
+	gnetCfg.MaxConnectionDownloadRate = cfg.MaxConnectionDownloadRate
+	gnetCfg.CompressionThreshold = cfg.CompressionThreshold
 
 	pool, err := gnet.NewConnectionPool(gnetCfg, d)
 	if err != nil {
@@ -111,9 +133,10 @@ func (pool *Pool) RunOffline() error {
 	return pool.Pool.RunOffline()
 }
 
-// sendPings send a ping if our last message sent was over pingRate ago
-func (pool *Pool) sendPings() {
-	if err := pool.Pool.SendPings(pool.Config.PingRate, &PingMessage{}); err != nil {
+// sendPings send a ping if our last message sent was over pingRate ago.
+// queued is called with the address of each connection right before its ping is queued
+func (pool *Pool) sendPings(queued func(addr string)) {
+	if _, err := pool.Pool.SendPings(pool.Config.PingRate, &PingMessage{}, queued); err != nil {
 		logger.WithError(err).Error("sendPings failed")
 	}
 }
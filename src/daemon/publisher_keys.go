@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"../../src/visor"
+)
+
+// Block publisher key changes hand off block publishing from the blockchain pubkey to a new key,
+// from a given sequence. A change is signed by the key it replaces, so it can be relayed by any peer.
+// Peers exchange their committed and proposed changes after the introduction, and relay the changes
+// that are new to them. A received change is proposed, and committed by the block at its seq
+// if that block is signed by the new key, see visor.PublisherKeyChange. A node that syncs the blockchain
+// receives the changes committed by its peers, and commits them with the blocks that use them.
+
+// maxPublisherKeyChanges is the number of publisher key changes sent in one GivePublisherKeyChangesMessage
+const maxPublisherKeyChanges = 256
+
+// InjectPublisherKeyChange verifies and proposes a block publisher key change, and broadcasts it to peers
+func (dm *Daemon) InjectPublisherKeyChange(kc visor.PublisherKeyChange) error {
+	if err := dm.visor.AddPublisherKeyChange(kc); err != nil {
+		return err
+	}
+
+	logger.Critical().WithFields(logrus.Fields{
+		"seq":    kc.Seq,
+		"pubkey": kc.Pubkey.Hex(),
+	}).Info("Proposed block publisher key change")
+
+	if dm.config.DisableNetworking {
+		return nil
+	}
+
+	dm.relayPublisherKeyChanges("", []visor.PublisherKeyChange{kc})
+	return nil
+}
+
+// sendPublisherKeyChanges sends the committed and proposed publisher key changes to a peer that advertises ServicePublisherKeys
+func (dm *Daemon) sendPublisherKeyChanges(addr string) error {
+	if !dm.peerSupports(addr, ServicePublisherKeys) {
+		return nil
+	}
+
+	kcs := dm.visor.GetPublisherKeyChanges()
+	for len(kcs) > 0 {
+		n := len(kcs)
+		if n > maxPublisherKeyChanges {
+			n = maxPublisherKeyChanges
+		}
+
+		if err := dm.sendMessage(addr, NewGivePublisherKeyChangesMessage(kcs[:n])); err != nil {
+			return err
+		}
+
+		kcs = kcs[n:]
+	}
+
+	return nil
+}
+
+// receivePublisherKeyChanges proposes the publisher key changes received from a peer,
+// and relays the ones that were not known to the other peers
+func (dm *Daemon) receivePublisherKeyChanges(addr string, kcs []visor.PublisherKeyChange) {
+	var added []visor.PublisherKeyChange
+	for _, kc := range kcs {
+		fields := logrus.Fields{
+			"addr": addr,
+			"seq":  kc.Seq,
+		}
+
+		switch err := dm.visor.AddPublisherKeyChange(kc); err {
+		case nil:
+			logger.Critical().WithFields(fields).WithField("pubkey", kc.Pubkey.Hex()).Info("Proposed block publisher key change")
+			added = append(added, kc)
+		case visor.ErrPublisherKeyChangeKnown:
+		default:
+			logger.WithError(err).WithFields(fields).Warning("Rejected block publisher key change")
+		}
+	}
+
+	if len(added) != 0 {
+		dm.relayPublisherKeyChanges(addr, added)
+	}
+}
+
+// relayPublisherKeyChanges sends publisher key changes to the introduced connections
+// that advertise ServicePublisherKeys, except for the connection that they came from
+func (dm *Daemon) relayPublisherKeyChanges(from string, kcs []visor.PublisherKeyChange) {
+	for _, c := range dm.connections.all() {
+		if c.Addr == from || !c.HasIntroduced() || !c.Services.Has(ServicePublisherKeys) {
+			continue
+		}
+
+		if err := dm.sendMessage(c.Addr, NewGivePublisherKeyChangesMessage(kcs)); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Warning("Send GivePublisherKeyChangesMessage failed")
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package daemon
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"../../src/cipher"
+	"../../src/coin"
+	"../../src/util/file"
+	"../../src/visor"
+)
+
+// A block publisher can run as a hot standby of another block publisher with the same key,
+// so that block creation fails over without moving the key by hand.
+// The block publisher that holds the lease creates the blocks. The lease is renewed when the head block
+// advances, and while the block policy does not call for a block, see BlockPolicy. It expires when a block
+// is due for PublisherLeaseIntervals block creation intervals without a new block, and a standby then takes over.
+// The block publishers must use the same block policy.
+//
+// The published chain head fences the block publishers. A block publisher that sees the head block advance
+// by a block that it did not create gives up the lease, since another block publisher has taken over.
+// No block is created while peers report a higher height than the head block, and the lease does not
+// expire either. A primary block publisher starts with the lease, unless the head block is more recent than
+// the lease timeout, in which case a standby may hold it. A standby waits one more block creation interval
+// than a primary before it takes over, so that a primary restarted at the same time takes over first.
+//
+// Two block publishers that both hold the lease and create a block at the same seq, e.g. after a partition,
+// never see the head block advance by the other's block, as neither executes it. Their chains conflict:
+// a block from a peer differs from the block at its seq, and its previous block is on our chain.
+// The block with the lower hash wins, so that only one of the block publishers stands by. The other one
+// has a head block that is not on the winner's chain. It can't roll back its block, so it records the conflict
+// and stands by until its block at that seq has been replaced, i.e. its chain has been synced again from
+// the winner's chain, see checkBlockConflict. The conflict is saved in PublisherConflictFilename, so that the block
+// publisher does not take the lease on its forked chain after a restart.
+//
+// The lease is not used when the block publishers agree on each block, see blockConsensus.
+
+// PublisherConflictFilename is the file in the data directory that records a conflict lost by the block publisher
+const PublisherConflictFilename = "publisher_conflict.json"
+
+// PublisherRole is the role of the node in block creation
+type PublisherRole string
+
+const (
+	// PublisherRoleNone the node is not a block publisher
+	PublisherRoleNone PublisherRole = "none"
+	// PublisherRoleActive the node holds the lease and creates blocks
+	PublisherRoleActive PublisherRole = "active"
+	// PublisherRoleStandby the node creates blocks only after the lease of the active block publisher expires
+	PublisherRoleStandby PublisherRole = "standby"
+)
+
+// publisherLease tracks which block publisher creates the blocks
+type publisherLease struct {
+	sync.Mutex
+	primary bool
+	// timeout is how long a block may be due without a new block before the lease expires
+	timeout time.Duration
+	role    PublisherRole
+	started bool
+	headSeq uint64
+	// renewedAt is when the lease was last renewed
+	renewedAt time.Time
+	// createdSeq is the seq of the last block created by this node, if created is true
+	createdSeq uint64
+	created    bool
+	// conflict is set if a block of another block publisher won over our chain.
+	// The lease is not acquired while it is set
+	conflict *publisherConflict
+}
+
+// publisherConflict is our block that lost to a conflicting block of another block publisher
+type publisherConflict struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// newPublisherLease creates a publisherLease. The role is decided by the first update, once the head block is known
+func newPublisherLease(standby bool, interval time.Duration, leaseIntervals uint64) *publisherLease {
+	timeout := interval * time.Duration(leaseIntervals)
+	if standby {
+		timeout += interval
+	}
+
+	return &publisherLease{
+		primary: !standby,
+		timeout: timeout,
+		role:    PublisherRoleStandby,
+	}
+}
+
+// update updates the lease from the head block, whether a block is due under the block policy
+// and whether the head block is at the highest height reported by peers. Returns the previous and the new role
+func (l *publisherLease) update(headSeq uint64, headTime time.Time, due, synced bool, now time.Time) (PublisherRole, PublisherRole) {
+	l.Lock()
+	defer l.Unlock()
+
+	prev := l.role
+
+	if !l.started {
+		l.started = true
+		l.headSeq = headSeq
+		l.renewedAt = now
+		if l.primary {
+			if now.Sub(headTime) < l.timeout {
+				l.renewedAt = headTime
+			} else {
+				l.role = PublisherRoleActive
+			}
+		}
+	}
+
+	switch {
+	case headSeq != l.headSeq:
+		l.headSeq = headSeq
+		l.renewedAt = now
+		if !l.created || headSeq != l.createdSeq {
+			l.role = PublisherRoleStandby
+		}
+	case !due || !synced:
+		l.renewedAt = now
+	case l.role == PublisherRoleStandby && l.conflict == nil && now.Sub(l.renewedAt) >= l.timeout:
+		l.role = PublisherRoleActive
+	}
+
+	return prev, l.role
+}
+
+// setCreated records a block created by this node
+func (l *publisherLease) setCreated(seq uint64) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.createdSeq = seq
+	l.created = true
+}
+
+// setConflict stands by until the conflict is cleared, because a block of another block publisher won over our chain.
+// Returns false if it already stood by for a conflict
+func (l *publisherLease) setConflict(c publisherConflict) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	l.role = PublisherRoleStandby
+
+	if l.conflict != nil {
+		return false
+	}
+
+	l.conflict = &c
+
+	return true
+}
+
+func (l *publisherLease) getConflict() *publisherConflict {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.conflict == nil {
+		return nil
+	}
+
+	c := *l.conflict
+	return &c
+}
+
+func (l *publisherLease) clearConflict() {
+	l.Lock()
+	defer l.Unlock()
+	l.conflict = nil
+}
+
+// loadPublisherConflict loads the conflict saved in PublisherConflictFilename.
+// Returns nil if the block publisher has not lost a conflict
+func loadPublisherConflict(dir string) (*publisherConflict, error) {
+	var c publisherConflict
+	if err := file.LoadJSON(filepath.Join(dir, PublisherConflictFilename), &c); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if _, err := cipher.SHA256FromHex(c.Hash); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// checkPublisherConflict clears the conflict lost by the block publisher, once its block at the seq of the conflict
+// has been replaced by syncing the chain again
+func (dm *Daemon) checkPublisherConflict() {
+	c := dm.publisherLease.getConflict()
+	if c == nil {
+		return
+	}
+
+	b, err := dm.visor.GetSignedBlockBySeq(c.Seq)
+	if err != nil {
+		logger.WithError(err).Error("checkPublisherConflict: visor.GetSignedBlockBySeq failed")
+		return
+	}
+	if b == nil || b.HashHeader().Hex() == c.Hash {
+		return
+	}
+
+	fn := filepath.Join(dm.config.DataDirectory, PublisherConflictFilename)
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		logger.WithError(err).Error("checkPublisherConflict: remove publisher conflict file failed")
+		return
+	}
+
+	dm.publisherLease.clearConflict()
+
+	logger.Critical().WithFields(logrus.Fields{
+		"seq":  c.Seq,
+		"hash": b.HashHeader().Hex(),
+	}).Info("Our chain has been synced with the block that won the conflict, the block publisher may take the lease again")
+}
+
+func (l *publisherLease) getRole() PublisherRole {
+	l.Lock()
+	defer l.Unlock()
+	return l.role
+}
+
+// checkPublisherLease updates the publisher lease with whether a block is due under the block policy,
+// and returns true if this node holds the lease and its head block is at the highest height reported by peers,
+// so it may create the next block
+func (dm *Daemon) checkPublisherLease(due bool) bool {
+	metadata, err := dm.visor.GetBlockchainMetadata()
+	if err != nil {
+		logger.WithError(err).Error("checkPublisherLease: visor.GetBlockchainMetadata failed")
+		return false
+	}
+
+	dm.checkPublisherConflict()
+
+	head := metadata.HeadBlock.Head
+	headTime := time.Unix(int64(head.Time), 0)
+	highest := EstimateBlockchainHeight(head.BkSeq, newPeerBlockchainHeights(dm.connections.all()))
+	synced := highest <= head.BkSeq
+
+	prev, role := dm.publisherLease.update(head.BkSeq, headTime, due, synced, time.Now())
+
+	fields := logrus.Fields{
+		"headSeq": head.BkSeq,
+		"highest": highest,
+	}
+
+	switch {
+	case role == PublisherRoleActive && prev != PublisherRoleActive:
+		logger.Critical().WithFields(fields).Info("Block publisher lease acquired, creating blocks")
+	case role != PublisherRoleActive && prev == PublisherRoleActive:
+		logger.Critical().WithFields(fields).Info("Another block publisher created the head block, standing by")
+	}
+
+	if role != PublisherRoleActive {
+		return false
+	}
+
+	if !synced {
+		logger.WithFields(fields).Debug("Not creating block, peers report a higher height")
+		return false
+	}
+
+	return true
+}
+
+// checkBlockConflict checks a block received from a peer that was not executed, because its seq is not above
+// the head block or because it failed to execute, for a conflict with our chain. If the block's previous block
+// is on our chain but the block differs from ours at its seq, the chains fork at its seq. The block publisher
+// stands by if the block has a lower hash than ours, so that of two block publishers that created a block
+// at the same seq, only one stands by. If the block's previous block is not on our chain, the chains fork
+// at a lower seq, and the previous blocks are requested from the peer to find it.
+// Returns false if the block conflicts with our chain, so the caller does not check the blocks that follow it
+func (dm *Daemon) checkBlockConflict(addr string, b coin.SignedBlock) bool {
+	if dm.publisherLease == nil || b.Seq() == 0 {
+		return true
+	}
+
+	fields := logrus.Fields{
+		"addr": addr,
+		"seq":  b.Seq(),
+	}
+
+	prev, err := dm.visor.GetSignedBlockBySeq(b.Seq() - 1)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Error("checkBlockConflict: visor.GetSignedBlockBySeq failed")
+		return true
+	}
+	if prev == nil {
+		return true
+	}
+
+	ours, err := dm.visor.GetSignedBlockBySeq(b.Seq())
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Error("checkBlockConflict: visor.GetSignedBlockBySeq failed")
+		return true
+	}
+
+	hash := b.HashHeader()
+	forked := b.Head.PrevHash != prev.HashHeader()
+	if !forked && (ours == nil || ours.HashHeader() == hash) {
+		return true
+	}
+
+	// Only blocks signed by a block publisher can make this node stand by or request blocks
+	if err := visor.VerifyBlockSignature(b, dm.visor.PublisherPubkeysAt(b.Seq())); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("checkBlockConflict: block signature is invalid")
+		return false
+	}
+
+	if forked {
+		if prev.Seq() == 0 {
+			return false
+		}
+
+		logger.WithFields(fields).Info("Received a block that does not follow our chain, requesting the previous blocks")
+		if err := dm.sendMessage(addr, NewGetBlocksMessage(prev.Seq()-1, dm.config.GetBlocksRequestCount)); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("checkBlockConflict: send GetBlocksMessage failed")
+		}
+		return false
+	}
+
+	oursHash := ours.HashHeader()
+	fields["hash"] = hash.Hex()
+	fields["ourHash"] = oursHash.Hex()
+
+	if bytes.Compare(hash[:], oursHash[:]) > 0 {
+		logger.WithFields(fields).Warning("Another block publisher created a conflicting block, our block wins")
+		return false
+	}
+
+	c := publisherConflict{
+		Seq:  b.Seq(),
+		Hash: oursHash.Hex(),
+	}
+
+	if !dm.publisherLease.setConflict(c) {
+		logger.WithFields(fields).Debug("Another block publisher created a conflicting block that wins over ours")
+		return false
+	}
+
+	fn := filepath.Join(dm.config.DataDirectory, PublisherConflictFilename)
+	if err := file.SaveJSON(fn, c, 0600); err != nil {
+		logger.WithError(err).WithFields(fields).Error("checkBlockConflict: save publisher conflict file failed")
+	}
+
+	logger.Critical().WithFields(fields).Error("Another block publisher created a conflicting block that wins over ours, standing by until our chain is synced again. Delete the database and restart to sync it")
+	return false
+}
+
+// GetPublisherRole returns the role of the node in block creation
+func (dm *Daemon) GetPublisherRole() PublisherRole {
+	switch {
+	case !dm.visor.Config.IsBlockPublisher:
+		return PublisherRoleNone
+	case dm.publisherLease == nil:
+		return PublisherRoleActive
+	default:
+		return dm.publisherLease.getRole()
+	}
+}
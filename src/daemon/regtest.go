@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"../../src/coin"
+)
+
+// A regtest chain is a throwaway local chain for application development. The node is the only
+// block publisher, networking is disabled and blocks are created on demand by MineBlocks,
+// instead of at the block creation interval. The block time can be moved forward so that
+// coin hours accrue without waiting.
+
+var (
+	// ErrNotRegtest is returned by MineBlocks if the daemon is not running a regtest chain
+	ErrNotRegtest = errors.New("Blocks can only be mined on a regtest chain")
+)
+
+// MineBlocks moves the block time forward by advance, then creates and executes n blocks.
+// A block is created from the valid unconfirmed transactions if there are any, otherwise
+// an empty block is created. The time of each block is at least one second after the previous block,
+// moving the block time forward further if needed.
+// The blocks created before an error are returned with the error
+func (dm *Daemon) MineBlocks(n int, advance time.Duration) ([]coin.SignedBlock, error) {
+	if !dm.config.Regtest {
+		return nil, ErrNotRegtest
+	}
+
+	if n < 1 {
+		return nil, errors.New("MineBlocks n must be >= 1")
+	}
+
+	if advance < 0 {
+		return nil, errors.New("MineBlocks advance must be >= 0")
+	}
+
+	dm.visor.AdvanceBlockTime(advance)
+
+	blocks := make([]coin.SignedBlock, 0, n)
+	for i := 0; i < n; i++ {
+		sb, err := dm.mineBlock()
+		if err != nil {
+			return blocks, err
+		}
+
+		logger.Critical().WithFields(logrus.Fields{
+			"version": sb.Block.Head.Version,
+			"seq":     sb.Block.Head.BkSeq,
+			"time":    sb.Block.Head.Time,
+			"txns":    len(sb.Block.Body.Transactions),
+		}).Info("Mined a regtest block")
+
+		blocks = append(blocks, sb)
+	}
+
+	return blocks, nil
+}
+
+// mineBlock creates and executes the next regtest block
+func (dm *Daemon) mineBlock() (coin.SignedBlock, error) {
+	metadata, err := dm.visor.GetBlockchainMetadata()
+	if err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	// Blocks created within the same second would have the same time
+	if headTime, now := metadata.HeadBlock.Head.Time, dm.visor.BlockTime(); now <= headTime {
+		dm.visor.AdvanceBlockTime(time.Second * time.Duration(headTime-now+1))
+	}
+
+	pending, _, err := dm.visor.GetValidUnconfirmedFee()
+	if err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	if pending == 0 {
+		return dm.visor.CreateAndExecuteEmptyBlock()
+	}
+
+	return dm.visor.CreateAndExecuteBlock()
+}
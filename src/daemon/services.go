@@ -0,0 +1,51 @@
+package daemon
+
+import (
+	"sort"
+)
+
+// Services is a bitfield of optional protocol features supported by a peer.
+// It is sent in the IntroductionMessage, so that new features can be rolled out
+// without raising the protocol version. Unknown bits are ignored.
+type Services uint64
+
+const (
+	// ServiceCompression the peer accepts compressed messages
+	ServiceCompression Services = 1 << 0
+	// ServiceBloomFilter the peer serves bloom filtered connections to light clients
+	ServiceBloomFilter Services = 1 << 1
+	// ServiceIPv6Peers the peer accepts GiveIPv6PeersMessage
+	ServiceIPv6Peers Services = 1 << 2
+	// ServiceDandelion the peer relays transactions sent in StemTxnMessage
+	ServiceDandelion Services = 1 << 3
+	// ServiceConsensus the peer takes part in the agreement of the block publishers on the next block
+	ServiceConsensus Services = 1 << 4
+	// ServicePublisherKeys the peer accepts GivePublisherKeyChangesMessage
+	ServicePublisherKeys Services = 1 << 5
+)
+
+var serviceNames = map[Services]string{
+	ServiceCompression:   "compression",
+	ServiceBloomFilter:   "bloom_filter",
+	ServiceIPv6Peers:     "ipv6_peers",
+	ServiceDandelion:     "dandelion",
+	ServiceConsensus:     "consensus",
+	ServicePublisherKeys: "publisher_keys",
+}
+
+// Has returns true if all of the services in x are set
+func (s Services) Has(x Services) bool {
+	return s&x == x
+}
+
+// Names returns the names of the known services that are set, sorted
+func (s Services) Names() []string {
+	names := []string{}
+	for x, name := range serviceNames {
+		if s.Has(x) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
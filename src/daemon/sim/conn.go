@@ -0,0 +1,297 @@
+package sim
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// connQueueSize is the number of writes that can be in flight on a connection
+const connQueueSize = 1024
+
+// timeoutError is returned by Read and Write when the deadline is exceeded
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// packet is a write waiting to be delivered to the peer
+type packet struct {
+	data []byte
+	at   time.Time
+}
+
+// conn is one end of a simulated connection. It implements net.Conn
+type conn struct {
+	n      *Network
+	local  addr
+	remote addr
+	peer   *conn
+	// in holds the data delivered by the peer
+	in *pipe
+	// queue holds the writes waiting to be delivered to the peer. It is closed by Close,
+	// after which deliver flushes it
+	queue chan packet
+	// queueLock is held for reading by Write while it queues data, so that Close
+	// does not close the queue under it
+	queueLock sync.RWMutex
+
+	writeDeadlineLock sync.Mutex
+	writeDeadline     time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newConnPair creates both ends of a connection between two addresses
+func newConnPair(n *Network, local, remote addr) (*conn, *conn) {
+	a := newConn(n, local, remote)
+	b := newConn(n, remote, local)
+	a.peer = b
+	b.peer = a
+
+	go a.deliver()
+	go b.deliver()
+
+	return a, b
+}
+
+func newConn(n *Network, local, remote addr) *conn {
+	return &conn{
+		n:      n,
+		local:  local,
+		remote: remote,
+		in:     newPipe(),
+		queue:  make(chan packet, connQueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// deliver copies writes to the peer once their latency has elapsed. Once the connection is closed,
+// the writes that were queued before are still delivered, then the peer reads io.EOF
+func (c *conn) deliver() {
+	for p := range c.queue {
+		if d := time.Until(p.at); d > 0 {
+			time.Sleep(d)
+		}
+		c.peer.in.write(p.data)
+	}
+
+	c.peer.in.close()
+}
+
+// Read reads data delivered by the peer
+func (c *conn) Read(b []byte) (int, error) {
+	return c.in.read(b)
+}
+
+// Write sends data to the peer. The data may be delayed or dropped according to the
+// Link between the hosts. Write does not report lost data
+func (c *conn) Write(b []byte) (int, error) {
+	c.queueLock.RLock()
+	defer c.queueLock.RUnlock()
+
+	select {
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	c.writeDeadlineLock.Lock()
+	deadline := c.writeDeadline
+	c.writeDeadlineLock.Unlock()
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		return 0, timeoutError{}
+	}
+
+	delay, ok := c.n.route(c.local.host(), c.remote.host())
+	if !ok {
+		return len(b), nil
+	}
+
+	data := make([]byte, len(b))
+	copy(data, b)
+
+	select {
+	case <-c.done:
+		return 0, io.ErrClosedPipe
+	case c.queue <- packet{
+		data: data,
+		at:   time.Now().Add(delay),
+	}:
+	}
+
+	return len(b), nil
+}
+
+// Close closes the connection. Like closing a TCP socket, data that was already written is not discarded:
+// the peer reads it once its latency has elapsed, then io.EOF. Data written by the peer is discarded
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		c.queueLock.Lock()
+		defer c.queueLock.Unlock()
+
+		close(c.done)
+		close(c.queue)
+		c.in.close()
+	})
+	return nil
+}
+
+// LocalAddr returns the local address
+func (c *conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr returns the peer's address
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline sets the read and write deadlines
+func (c *conn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline
+func (c *conn) SetReadDeadline(t time.Time) error {
+	c.in.setDeadline(t)
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline. Writes do not block, so the deadline
+// only makes writes fail once it has passed
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadlineLock.Lock()
+	defer c.writeDeadlineLock.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+// pipe is a buffer that blocks readers until data is written, the pipe is closed or the deadline passes
+type pipe struct {
+	sync.Mutex
+	cond     *sync.Cond
+	buf      bytes.Buffer
+	closed   bool
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newPipe() *pipe {
+	p := &pipe{}
+	p.cond = sync.NewCond(&p.Mutex)
+	return p
+}
+
+func (p *pipe) read(b []byte) (int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	for {
+		if p.buf.Len() > 0 {
+			return p.buf.Read(b)
+		}
+		if p.closed {
+			return 0, io.EOF
+		}
+		if !p.deadline.IsZero() && !time.Now().Before(p.deadline) {
+			return 0, timeoutError{}
+		}
+		p.cond.Wait()
+	}
+}
+
+func (p *pipe) write(data []byte) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return
+	}
+	p.buf.Write(data)
+	p.cond.Broadcast()
+}
+
+func (p *pipe) close() {
+	p.Lock()
+	defer p.Unlock()
+
+	p.closed = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.cond.Broadcast()
+}
+
+func (p *pipe) setDeadline(t time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.deadline = t
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if !t.IsZero() {
+		p.timer = time.AfterFunc(time.Until(t), func() {
+			p.Lock()
+			defer p.Unlock()
+			p.cond.Broadcast()
+		})
+	}
+	p.cond.Broadcast()
+}
+
+// listener accepts simulated connections. It implements net.Listener
+type listener struct {
+	n         *Network
+	addr      addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept waits for the next connection
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case <-l.done:
+		return nil, ErrListenerClosed
+	case c := <-l.conns:
+		return c, nil
+	}
+}
+
+// Close stops listening. Connections waiting to be accepted are closed
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		l.n.Lock()
+		if l.n.listeners[l.addr.String()] == l {
+			delete(l.n.listeners, l.addr.String())
+		}
+		l.n.Unlock()
+
+		close(l.done)
+
+		for {
+			select {
+			case c := <-l.conns:
+				c.Close()
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Addr returns the listening address
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
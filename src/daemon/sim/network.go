@@ -0,0 +1,281 @@
+/*
+Package sim runs several nodes in one process, connected by a simulated network.
+
+Each node is a full daemon.Daemon and visor.Visor with its own database. The nodes'
+connection pools use a gnet.Transport that sends data over in-memory connections
+instead of TCP sockets, so that the latency, packet loss and partitions between
+nodes are controlled by the scenario.
+
+Packet loss drops whole writes. gnet writes each message with a single write,
+so a lost packet is a lost message and the stream stays readable.
+The random numbers used for jitter and packet loss come from the seed passed to NewNetwork.
+
+Scenarios lists the scenarios that run nodes on a Network. They are run by cmd/laqpay-sim,
+e.g. with make sim.
+*/
+package sim
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"../../../src/daemon/gnet"
+	"../../../src/util/iputil"
+)
+
+const (
+	// firstEphemeralPort is the first local port assigned to outgoing connections
+	firstEphemeralPort = 49152
+	// listenBacklog is the number of connections that can wait to be accepted by a listener
+	listenBacklog = 128
+)
+
+var (
+	// ErrConnectionRefused nothing is listening on the dialed address
+	ErrConnectionRefused = errors.New("Connection refused")
+	// ErrHostUnreachable the dialed address is on the other side of a partition
+	ErrHostUnreachable = errors.New("Host is unreachable")
+	// ErrAddressInUse something is already listening on the address
+	ErrAddressInUse = errors.New("Address already in use")
+	// ErrListenerClosed the listener was closed
+	ErrListenerClosed = errors.New("Listener closed")
+)
+
+// Link describes the connections between two hosts
+type Link struct {
+	// Latency is how long data written to a connection takes to become readable by the peer
+	Latency time.Duration
+	// Jitter is the maximum random delay added to Latency. Data is always delivered in order
+	Jitter time.Duration
+	// Loss is the probability, between 0 and 1, that a write is dropped
+	Loss float64
+}
+
+// linkKey identifies the Link between two hosts, in either direction
+type linkKey struct {
+	a, b string
+}
+
+func newLinkKey(a, b string) linkKey {
+	if b < a {
+		a, b = b, a
+	}
+	return linkKey{a: a, b: b}
+}
+
+// Network is a simulated network of hosts. It is safe for concurrent use.
+type Network struct {
+	sync.Mutex
+	// Chain holds the blockchain parameters of the nodes created by NewNode
+	Chain Chain
+
+	rand        *rand.Rand
+	defaultLink Link
+	links       map[linkKey]Link
+	// partition group of each host, nil if the network is not partitioned
+	groups        map[string]int
+	listeners     map[string]*listener
+	ephemeralPort int
+	nodes         []*Node
+}
+
+// NewNetwork creates a Network. The seed determines the blockchain keys and the
+// random numbers used for jitter and packet loss
+func NewNetwork(seed int64) (*Network, error) {
+	chain, err := newChain(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Network{
+		Chain:         chain,
+		rand:          rand.New(rand.NewSource(seed)),
+		links:         make(map[linkKey]Link),
+		listeners:     make(map[string]*listener),
+		ephemeralPort: firstEphemeralPort,
+	}, nil
+}
+
+// SetDefaultLink sets the Link used between hosts that do not have a Link set with SetLink
+func (n *Network) SetDefaultLink(l Link) {
+	n.Lock()
+	defer n.Unlock()
+	n.defaultLink = l
+}
+
+// SetLink sets the Link between two hosts. Existing connections are affected too
+func (n *Network) SetLink(a, b string, l Link) {
+	n.Lock()
+	defer n.Unlock()
+	n.links[newLinkKey(a, b)] = l
+}
+
+// Partition splits the network into groups of hosts. Hosts can only reach the hosts
+// in the same group. Hosts that are not in any group are put together in one more group.
+// Data sent across the partition by existing connections is dropped, and dialing across it fails
+func (n *Network) Partition(groups ...[]string) {
+	n.Lock()
+	defer n.Unlock()
+
+	n.groups = make(map[string]int)
+	for i, hosts := range groups {
+		for _, h := range hosts {
+			n.groups[h] = i + 1
+		}
+	}
+}
+
+// Heal removes the partition created by Partition
+func (n *Network) Heal() {
+	n.Lock()
+	defer n.Unlock()
+	n.groups = nil
+}
+
+// Transport returns a gnet.Transport for a host. The host is an IP address, and is
+// used as the local address of the connections dialed by the transport
+func (n *Network) Transport(host string) gnet.Transport {
+	return &transport{
+		n:    n,
+		host: host,
+	}
+}
+
+// reachable returns true if a and b are not separated by a partition. Must be called under lock
+func (n *Network) reachable(a, b string) bool {
+	if n.groups == nil {
+		return true
+	}
+	return n.groups[a] == n.groups[b]
+}
+
+// route returns how long data written from host a takes to reach host b,
+// and false if the data is lost
+func (n *Network) route(a, b string) (time.Duration, bool) {
+	n.Lock()
+	defer n.Unlock()
+
+	if !n.reachable(a, b) {
+		return 0, false
+	}
+
+	l, ok := n.links[newLinkKey(a, b)]
+	if !ok {
+		l = n.defaultLink
+	}
+
+	if l.Loss > 0 && n.rand.Float64() < l.Loss {
+		return 0, false
+	}
+
+	delay := l.Latency
+	if l.Jitter > 0 {
+		delay += time.Duration(n.rand.Int63n(int64(l.Jitter) + 1))
+	}
+
+	return delay, true
+}
+
+// transport is the gnet.Transport of a host on a Network
+type transport struct {
+	n    *Network
+	host string
+}
+
+// Listen listens for connections on address. If the port is 0, a port is assigned
+func (t *transport) Listen(address string) (net.Listener, error) {
+	host, port, err := iputil.SplitAddr(address)
+	if err != nil {
+		return nil, err
+	}
+
+	t.n.Lock()
+	defer t.n.Unlock()
+
+	if port == 0 {
+		port = t.n.nextEphemeralPort()
+	}
+	address = iputil.JoinAddr(host, port)
+
+	if _, ok := t.n.listeners[address]; ok {
+		return nil, ErrAddressInUse
+	}
+
+	l := &listener{
+		n:     t.n,
+		addr:  addr(address),
+		conns: make(chan net.Conn, listenBacklog),
+		done:  make(chan struct{}),
+	}
+	t.n.listeners[address] = l
+
+	return l, nil
+}
+
+// Dial opens a connection to address. The timeout is not used, dialing does not block
+func (t *transport) Dial(address string, timeout time.Duration) (net.Conn, error) {
+	host, _, err := iputil.SplitAddr(address)
+	if err != nil {
+		return nil, err
+	}
+
+	t.n.Lock()
+	defer t.n.Unlock()
+
+	if !t.n.reachable(t.host, host) {
+		return nil, ErrHostUnreachable
+	}
+
+	l := t.n.listeners[address]
+	if l == nil {
+		return nil, ErrConnectionRefused
+	}
+
+	local := addr(iputil.JoinAddr(t.host, t.n.nextEphemeralPort()))
+	c, peer := newConnPair(t.n, local, addr(address))
+
+	// Listeners are removed from the network before they are closed, so this does not race with Close
+	select {
+	case l.conns <- peer:
+		return c, nil
+	default:
+		c.Close()
+		peer.Close()
+		return nil, ErrConnectionRefused
+	}
+}
+
+// nextEphemeralPort returns a port for an outgoing connection or a listener. Must be called under lock
+func (n *Network) nextEphemeralPort() uint16 {
+	p := n.ephemeralPort
+	n.ephemeralPort++
+	if n.ephemeralPort > 65535 {
+		n.ephemeralPort = firstEphemeralPort
+	}
+	return uint16(p)
+}
+
+// addr is the net.Addr of a simulated connection, in host:port format
+type addr string
+
+// Network returns the name of the network
+func (a addr) Network() string {
+	return "sim"
+}
+
+// String returns the address
+func (a addr) String() string {
+	return string(a)
+}
+
+// host returns the host part of the address
+func (a addr) host() string {
+	host, _, err := net.SplitHostPort(string(a))
+	if err != nil {
+		return string(a)
+	}
+	return host
+}
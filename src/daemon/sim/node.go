@@ -0,0 +1,373 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"../../../src/cipher"
+	"../../../src/coin"
+	"../../../src/daemon"
+	"../../../src/params"
+	"../../../src/util/droplet"
+	"../../../src/util/useragent"
+	"../../../src/visor"
+	"../../../src/visor/dbutil"
+	"../../../src/wallet"
+)
+
+const (
+	// genesisTimestamp is the timestamp of the genesis block of a Network
+	genesisTimestamp = 1514764800
+	// pollRate is how often the Wait methods check the node's state
+	pollRate = 10 * time.Millisecond
+)
+
+// ErrTimeout the condition waited for was not met in time
+var ErrTimeout = errors.New("Timed out")
+
+// Chain holds the blockchain parameters shared by the nodes of a Network
+type Chain struct {
+	// Key pair of the block publisher
+	BlockchainPubkey cipher.PubKey
+	BlockchainSeckey cipher.SecKey
+	// Key pair of the address that receives the genesis coins
+	GenesisAddress cipher.Address
+	GenesisSeckey  cipher.SecKey
+	// Genesis block parameters
+	GenesisSignature  cipher.Sig
+	GenesisTimestamp  uint64
+	GenesisCoinVolume uint64
+	GenesisHash       cipher.SHA256
+}
+
+// newChain creates the blockchain parameters, deriving the keys from the seed
+func newChain(seed int64) (Chain, error) {
+	pk, sk, err := cipher.GenerateDeterministicKeyPair([]byte(fmt.Sprintf("sim blockchain %d", seed)))
+	if err != nil {
+		return Chain{}, err
+	}
+
+	genesisPk, genesisSk, err := cipher.GenerateDeterministicKeyPair([]byte(fmt.Sprintf("sim genesis %d", seed)))
+	if err != nil {
+		return Chain{}, err
+	}
+
+	c := Chain{
+		BlockchainPubkey:  pk,
+		BlockchainSeckey:  sk,
+		GenesisAddress:    cipher.AddressFromPubKey(genesisPk),
+		GenesisSeckey:     genesisSk,
+		GenesisTimestamp:  genesisTimestamp,
+		GenesisCoinVolume: params.MainNetDistribution.MaxCoinSupply * droplet.Multiplier,
+	}
+
+	b, err := coin.NewGenesisBlock(c.GenesisAddress, c.GenesisCoinVolume, c.GenesisTimestamp)
+	if err != nil {
+		return Chain{}, err
+	}
+
+	c.GenesisHash = b.HashHeader()
+	c.GenesisSignature = cipher.MustSignHash(c.GenesisHash, sk)
+
+	return c, nil
+}
+
+// NodeConfig configures a node created by Network.NewNode
+type NodeConfig struct {
+	// IP address of the node on the simulated network
+	Host string
+	// Port to listen on
+	Port int
+	// Run the node as a block publisher, with the Network's blockchain secret key
+	Publisher bool
+	// Secret key of the block publisher, in place of the Network's blockchain secret key
+	PublisherSeckey cipher.SecKey
+	// Public keys of the other block publishers, that agree on each block with the Network's block publisher,
+	// see visor.Config.BlockPublisherPubkeys. All nodes of the Network must use the same keys
+	PublisherPubkeys []cipher.PubKey
+	// Addresses of the nodes to connect to on startup. They are the node's trusted peers
+	Peers []string
+	// Accept blocks with no transactions, see visor.Config.AllowEmptyBlocks
+	AllowEmptyBlocks bool
+	// Daemon config. The address, networking, data directory and blockchain
+	// options are overwritten by Network.NewNode
+	Daemon daemon.Config
+}
+
+// NewNodeConfig returns a NodeConfig with the daemon's timers shortened, so that
+// nodes find each other and sync quickly
+func NewNodeConfig(host string) NodeConfig {
+	dc := daemon.NewConfig()
+
+	dc.Daemon.OutgoingRate = 100 * time.Millisecond
+	dc.Daemon.PrivateRate = time.Second
+	dc.Daemon.IntroductionWait = 5 * time.Second
+	dc.Daemon.CullInvalidRate = time.Second
+	dc.Daemon.BlocksRequestRate = time.Second
+	dc.Daemon.BlocksAnnounceRate = time.Second
+	dc.Daemon.UserAgent = useragent.Data{
+		Coin:    "laqpay",
+		Version: "0.0.0",
+		Remark:  "sim",
+	}
+
+	dc.Pex.RequestRate = time.Second
+
+	dc.Pool.DialTimeout = 5 * time.Second
+
+	return NodeConfig{
+		Host:   host,
+		Port:   6000,
+		Daemon: dc,
+	}
+}
+
+// Node is a daemon and visor running on a Network
+type Node struct {
+	Daemon *daemon.Daemon
+	Visor  *visor.Visor
+
+	n            *Network
+	addr         string
+	dir          string
+	db           *dbutil.DB
+	done         chan error
+	shutdownOnce sync.Once
+}
+
+// NewNode creates a node with its own database in a temporary directory, and starts it
+func (n *Network) NewNode(c NodeConfig) (*Node, error) {
+	dir, err := ioutil.TempDir("", "laqpay-sim-")
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := n.newNode(c, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	n.Lock()
+	n.nodes = append(n.nodes, node)
+	n.Unlock()
+
+	go func() {
+		node.done <- node.Daemon.Run()
+	}()
+
+	return node, nil
+}
+
+func (n *Network) newNode(c NodeConfig, dir string) (*Node, error) {
+	vc := visor.NewConfig()
+	vc.Distribution = params.MainNetDistribution
+	vc.IsBlockPublisher = c.Publisher
+	vc.Arbitrating = c.Publisher
+	vc.BlockchainPubkey = n.Chain.BlockchainPubkey
+	if c.Publisher {
+		vc.BlockchainSeckey = n.Chain.BlockchainSeckey
+		if c.PublisherSeckey != (cipher.SecKey{}) {
+			vc.BlockchainSeckey = c.PublisherSeckey
+		}
+	}
+	vc.BlockPublisherPubkeys = c.PublisherPubkeys
+	vc.GenesisAddress = n.Chain.GenesisAddress
+	vc.GenesisSignature = n.Chain.GenesisSignature
+	vc.GenesisTimestamp = n.Chain.GenesisTimestamp
+	vc.GenesisCoinVolume = n.Chain.GenesisCoinVolume
+	vc.AllowEmptyBlocks = c.AllowEmptyBlocks
+
+	dc := c.Daemon
+	dc.Daemon.Address = c.Host
+	dc.Daemon.Port = c.Port
+	dc.Daemon.LocalhostOnly = false
+	dc.Daemon.DisableNetworking = false
+	dc.Daemon.DataDirectory = dir
+	dc.Daemon.BlockchainPubkey = n.Chain.BlockchainPubkey
+	dc.Daemon.GenesisHash = n.Chain.GenesisHash
+	dc.Daemon.DefaultConnections = c.Peers
+	dc.Pool.DefaultConnections = c.Peers
+	dc.Pool.Transport = n.Transport(c.Host)
+	dc.Pex.DataDirectory = dir
+	dc.Pex.DefaultConnections = c.Peers
+	dc.Pex.DownloadPeerList = false
+	dc.Pex.DNSSeeds = nil
+	dc.Pex.CustomPeersFile = ""
+
+	db, err := visor.OpenDB(filepath.Join(dir, "data.db"), false)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := n.newNodeWithDB(vc, dc, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	node.addr = fmt.Sprintf("%s:%d", c.Host, c.Port)
+	node.dir = dir
+
+	return node, nil
+}
+
+func (n *Network) newNodeWithDB(vc visor.Config, dc daemon.Config, db *dbutil.DB) (*Node, error) {
+	wc := wallet.NewConfig()
+	wc.WalletDir = filepath.Join(dc.Daemon.DataDirectory, "wallets")
+	w, err := wallet.NewService(wc)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := visor.New(vc, db, w)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := daemon.New(dc, v)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.Init(); err != nil {
+		return nil, err
+	}
+
+	return &Node{
+		Daemon: d,
+		Visor:  v,
+		n:      n,
+		db:     db,
+		done:   make(chan error, 1),
+	}, nil
+}
+
+// Shutdown stops all of the nodes of the network
+func (n *Network) Shutdown() {
+	n.Lock()
+	nodes := n.nodes
+	n.nodes = nil
+	n.Unlock()
+
+	for _, node := range nodes {
+		node.Shutdown()
+	}
+}
+
+// Addr returns the address that the node listens on
+func (node *Node) Addr() string {
+	return node.addr
+}
+
+// Shutdown stops the node and deletes its data directory.
+// Returns the error returned by daemon.Daemon.Run, if any
+func (node *Node) Shutdown() error {
+	var err error
+	node.shutdownOnce.Do(func() {
+		node.Daemon.Shutdown()
+		err = <-node.done
+
+		if dbErr := node.db.Close(); dbErr != nil && err == nil {
+			err = dbErr
+		}
+
+		os.RemoveAll(node.dir)
+
+		node.n.Lock()
+		for i, x := range node.n.nodes {
+			if x == node {
+				node.n.nodes = append(node.n.nodes[:i], node.n.nodes[i+1:]...)
+				break
+			}
+		}
+		node.n.Unlock()
+	})
+	return err
+}
+
+// HeadSeq returns the sequence number of the node's head block
+func (node *Node) HeadSeq() (uint64, error) {
+	seq, _, err := node.Visor.HeadBkSeq()
+	return seq, err
+}
+
+// Connections returns the number of connections that have completed the introduction
+func (node *Node) Connections() (int, error) {
+	conns, err := node.Daemon.GetConnections(func(c daemon.Connection) bool {
+		return c.State == daemon.ConnectionStateIntroduced
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(conns), nil
+}
+
+// WaitForHeight waits until the node's head block sequence is at least seq
+func (node *Node) WaitForHeight(seq uint64, timeout time.Duration) error {
+	return wait(timeout, func() (bool, error) {
+		head, err := node.HeadSeq()
+		if err != nil {
+			return false, err
+		}
+		return head >= seq, nil
+	})
+}
+
+// WaitForConnections waits until the node has at least n introduced connections
+func (node *Node) WaitForConnections(n int, timeout time.Duration) error {
+	return wait(timeout, func() (bool, error) {
+		conns, err := node.Connections()
+		if err != nil {
+			return false, err
+		}
+		return conns >= n, nil
+	})
+}
+
+// WaitForConnectionTo waits until the node has an introduced outgoing connection to addr
+func (node *Node) WaitForConnectionTo(addr string, timeout time.Duration) error {
+	return wait(timeout, func() (bool, error) {
+		conns, err := node.Daemon.GetConnections(func(c daemon.Connection) bool {
+			return c.State == daemon.ConnectionStateIntroduced && c.Outgoing && c.Addr == addr
+		})
+		if err != nil {
+			return false, err
+		}
+		return len(conns) != 0, nil
+	})
+}
+
+// WaitForUnconfirmedTxn waits until the node has the transaction in its unconfirmed pool
+func (node *Node) WaitForUnconfirmedTxn(txid cipher.SHA256, timeout time.Duration) error {
+	return wait(timeout, func() (bool, error) {
+		txn, err := node.Visor.GetUnconfirmedTxn(txid)
+		if err != nil {
+			return false, err
+		}
+		return txn != nil, nil
+	})
+}
+
+// wait calls f until it returns true or an error, or the timeout elapses
+func wait(timeout time.Duration, f func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := f()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(pollRate)
+	}
+}
@@ -0,0 +1,628 @@
+package sim
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"../../../src/cipher"
+	"../../../src/coin"
+	"../../../src/daemon"
+	"../../../src/visor"
+)
+
+// scenarioTimeout is how long a scenario waits for each condition
+const scenarioTimeout = 30 * time.Second
+
+// Scenario runs nodes on a Network and checks how they behave
+type Scenario struct {
+	// Name identifies the scenario
+	Name string
+	// Description tells what the scenario checks
+	Description string
+	// Run runs the scenario. It returns an error if a node does not behave as expected.
+	// The nodes are shut down by the caller, with Network.Shutdown
+	Run func(n *Network) error
+}
+
+// Scenarios are the scenarios run by cmd/laqpay-sim
+var Scenarios = []Scenario{
+	{
+		Name:        "sync",
+		Description: "Blocks created by the block publisher reach a node that is only connected to it through another node",
+		Run:         runSync,
+	},
+	{
+		Name:        "partition",
+		Description: "A node cut off from the block publisher by a partition catches up once the partition heals",
+		Run:         runPartition,
+	},
+	{
+		Name:        "pex",
+		Description: "A node learns the address of the block publisher from its only peer and connects to it",
+		Run:         runPex,
+	},
+	{
+		Name:        "mempool-relay",
+		Description: "A transaction injected at a node reaches the block publisher through another node and is confirmed",
+		Run:         runMempoolRelay,
+	},
+	{
+		Name:        "publisher-failover",
+		Description: "A standby block publisher takes over block creation when the active block publisher stops",
+		Run:         runPublisherFailover,
+	},
+	{
+		Name:        "publisher-conflict",
+		Description: "Of two active block publishers that created a block at the same seq while partitioned, the one with the higher block hash stands by",
+		Run:         runPublisherConflict,
+	},
+	{
+		Name:        "consensus-split",
+		Description: "Two of three consensus block publishers that proposed different blocks while partitioned agree on one of them in a later round, while the third is offline",
+		Run:         runConsensusSplit,
+	},
+}
+
+// BlockSwitch is a daemon.BlockPolicy that creates blocks while it is on, from the unconfirmed
+// transactions or empty, so that a scenario decides when the block publisher creates blocks
+type BlockSwitch struct {
+	sync.Mutex
+	on bool
+}
+
+// Decide implements daemon.BlockPolicy
+func (s *BlockSwitch) Decide(state daemon.BlockPolicyState) daemon.BlockAction {
+	s.Lock()
+	defer s.Unlock()
+
+	switch {
+	case !s.on:
+		return daemon.BlockActionNone
+	case state.PendingTxns != 0:
+		return daemon.BlockActionCreate
+	default:
+		return daemon.BlockActionEmpty
+	}
+}
+
+// Set turns block creation on or off
+func (s *BlockSwitch) Set(on bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.on = on
+}
+
+// NewPublisherConfig returns a NodeConfig for a block publisher that creates an empty block
+// every second while the BlockSwitch is on
+func NewPublisherConfig(host string, s *BlockSwitch) NodeConfig {
+	c := NewNodeConfig(host)
+	c.Publisher = true
+	c.AllowEmptyBlocks = true
+	c.Daemon.Daemon.BlockCreationInterval = 1
+	c.Daemon.Daemon.BlockPolicy = s
+	return c
+}
+
+// createBlocks turns block creation on until the node's head block sequence is at least seq
+func createBlocks(node *Node, s *BlockSwitch, seq uint64) error {
+	s.Set(true)
+	defer s.Set(false)
+
+	if err := node.WaitForHeight(seq, scenarioTimeout); err != nil {
+		return fmt.Errorf("block publisher %s did not reach height %d: %v", node.Addr(), seq, err)
+	}
+
+	return nil
+}
+
+// waitForHeights waits until the head block sequence of each node is at least seq
+func waitForHeights(seq uint64, nodes ...*Node) error {
+	for _, node := range nodes {
+		if err := node.WaitForHeight(seq, scenarioTimeout); err != nil {
+			head, _ := node.HeadSeq() // nolint: errcheck
+			return fmt.Errorf("node %s did not reach height %d, its height is %d: %v", node.Addr(), seq, head, err)
+		}
+	}
+
+	return nil
+}
+
+// runSync connects a node to the block publisher through another node
+func runSync(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+		Jitter:  10 * time.Millisecond,
+	})
+
+	var s BlockSwitch
+	publisher, err := n.NewNode(NewPublisherConfig("10.0.0.1", &s))
+	if err != nil {
+		return err
+	}
+
+	c := NewNodeConfig("10.0.0.2")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{publisher.Addr()}
+	relay, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	c = NewNodeConfig("10.0.0.3")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{relay.Addr()}
+	node, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := node.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", node.Addr(), err)
+	}
+
+	if err := createBlocks(publisher, &s, 3); err != nil {
+		return err
+	}
+
+	return waitForHeights(3, relay, node)
+}
+
+// runPartition partitions a node from the block publisher while blocks are created, then heals the partition
+func runPartition(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+	})
+
+	var s BlockSwitch
+	publisher, err := n.NewNode(NewPublisherConfig("10.0.0.1", &s))
+	if err != nil {
+		return err
+	}
+
+	c := NewNodeConfig("10.0.0.2")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{publisher.Addr()}
+	node, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := node.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", node.Addr(), err)
+	}
+
+	if err := createBlocks(publisher, &s, 1); err != nil {
+		return err
+	}
+	if err := waitForHeights(1, node); err != nil {
+		return err
+	}
+
+	n.Partition([]string{"10.0.0.1"}, []string{"10.0.0.2"})
+
+	if err := createBlocks(publisher, &s, 3); err != nil {
+		return err
+	}
+
+	if head, err := node.HeadSeq(); err != nil {
+		return err
+	} else if head != 1 {
+		return fmt.Errorf("node %s received blocks across the partition, its height is %d", node.Addr(), head)
+	}
+
+	n.Heal()
+
+	return waitForHeights(3, node)
+}
+
+// runPex connects a node to the block publisher's only peer, from which it learns the block publisher's address
+func runPex(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+	})
+
+	var s BlockSwitch
+	publisher, err := n.NewNode(NewPublisherConfig("10.0.0.1", &s))
+	if err != nil {
+		return err
+	}
+
+	c := NewNodeConfig("10.0.0.2")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{publisher.Addr()}
+	relay, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := relay.WaitForConnectionTo(publisher.Addr(), scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect to %s: %v", relay.Addr(), publisher.Addr(), err)
+	}
+
+	c = NewNodeConfig("10.0.0.3")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{relay.Addr()}
+	node, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := node.WaitForConnectionTo(publisher.Addr(), scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect to %s, which it could only learn about through pex: %v", node.Addr(), publisher.Addr(), err)
+	}
+
+	return nil
+}
+
+// runMempoolRelay injects a transaction at a node that is only connected to the block publisher through another node
+func runMempoolRelay(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+		Jitter:  10 * time.Millisecond,
+	})
+
+	var s BlockSwitch
+	publisher, err := n.NewNode(NewPublisherConfig("10.0.0.1", &s))
+	if err != nil {
+		return err
+	}
+
+	c := NewNodeConfig("10.0.0.2")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{publisher.Addr()}
+	relay, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	c = NewNodeConfig("10.0.0.3")
+	c.AllowEmptyBlocks = true
+	c.Peers = []string{relay.Addr()}
+	node, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := node.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", node.Addr(), err)
+	}
+
+	// The genesis coins accrue coin hours once a block is created after the genesis timestamp,
+	// so that the transaction can pay a fee
+	if err := createBlocks(publisher, &s, 1); err != nil {
+		return err
+	}
+	if err := waitForHeights(1, relay, node); err != nil {
+		return err
+	}
+
+	txn, err := newGenesisSpend(node, n.Chain)
+	if err != nil {
+		return err
+	}
+
+	if err := node.Daemon.InjectBroadcastTransaction(txn); err != nil {
+		return fmt.Errorf("node %s did not accept the transaction: %v", node.Addr(), err)
+	}
+
+	txid := txn.Hash()
+	for _, x := range []*Node{relay, publisher} {
+		if err := x.WaitForUnconfirmedTxn(txid, scenarioTimeout); err != nil {
+			return fmt.Errorf("transaction %s did not reach node %s: %v", txid.Hex(), x.Addr(), err)
+		}
+	}
+
+	if err := createBlocks(publisher, &s, 2); err != nil {
+		return err
+	}
+	if err := waitForHeights(2, node); err != nil {
+		return err
+	}
+
+	b, err := node.Visor.GetSignedBlockBySeq(2)
+	if err != nil {
+		return err
+	}
+	if len(b.Body.Transactions) != 1 || b.Body.Transactions[0].Hash() != txid {
+		return fmt.Errorf("block 2 does not contain transaction %s", txid.Hex())
+	}
+
+	return nil
+}
+
+// newGenesisSpend creates a transaction that sends the genesis coins to a new address,
+// burning all of their coin hours
+func newGenesisSpend(node *Node, chain Chain) (coin.Transaction, error) {
+	auxs, err := node.Visor.GetUnspentsOfAddrs([]cipher.Address{chain.GenesisAddress})
+	if err != nil {
+		return coin.Transaction{}, err
+	}
+
+	uxs := auxs[chain.GenesisAddress]
+	if len(uxs) != 1 {
+		return coin.Transaction{}, fmt.Errorf("genesis address has %d unspent outputs, expected 1", len(uxs))
+	}
+
+	pk, _ := cipher.GenerateKeyPair()
+
+	var txn coin.Transaction
+	if err := txn.PushInput(uxs[0].Hash()); err != nil {
+		return coin.Transaction{}, err
+	}
+	if err := txn.PushOutput(cipher.AddressFromPubKey(pk), uxs[0].Body.Coins, 0); err != nil {
+		return coin.Transaction{}, err
+	}
+	txn.SignInputs([]cipher.SecKey{chain.GenesisSeckey})
+	if err := txn.UpdateHeader(); err != nil {
+		return coin.Transaction{}, err
+	}
+
+	return txn, nil
+}
+
+// runPublisherFailover runs a standby of the block publisher, then stops the active block publisher
+func runPublisherFailover(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+	})
+
+	var sa, sb BlockSwitch
+	active, err := n.NewNode(NewPublisherConfig("10.0.0.1", &sa))
+	if err != nil {
+		return err
+	}
+
+	c := NewPublisherConfig("10.0.0.2", &sb)
+	c.Daemon.Daemon.PublisherStandby = true
+	c.Peers = []string{active.Addr()}
+	standby, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := standby.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", standby.Addr(), err)
+	}
+
+	sa.Set(true)
+	defer sa.Set(false)
+	sb.Set(true)
+	defer sb.Set(false)
+
+	if err := waitForHeights(2, active, standby); err != nil {
+		return err
+	}
+
+	if role := standby.Daemon.GetPublisherRole(); role != daemon.PublisherRoleStandby {
+		return fmt.Errorf("standby block publisher %s has role %s while the active block publisher runs", standby.Addr(), role)
+	}
+
+	head, err := active.HeadSeq()
+	if err != nil {
+		return err
+	}
+
+	if err := active.Shutdown(); err != nil {
+		return fmt.Errorf("block publisher %s shutdown failed: %v", active.Addr(), err)
+	}
+
+	if err := waitForHeights(head+2, standby); err != nil {
+		return fmt.Errorf("standby block publisher did not take over: %v", err)
+	}
+
+	if role := standby.Daemon.GetPublisherRole(); role != daemon.PublisherRoleActive {
+		return fmt.Errorf("block publisher %s created blocks with role %s", standby.Addr(), role)
+	}
+
+	return nil
+}
+
+// runPublisherConflict partitions two block publishers that both hold the lease, so that both create
+// a block at seq 1, then heals the partition and lets both create blocks
+func runPublisherConflict(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+	})
+
+	var sa, sb BlockSwitch
+	a, err := n.NewNode(NewPublisherConfig("10.0.0.1", &sa))
+	if err != nil {
+		return err
+	}
+
+	c := NewPublisherConfig("10.0.0.2", &sb)
+	c.Peers = []string{a.Addr()}
+	b, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := b.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", b.Addr(), err)
+	}
+
+	n.Partition([]string{"10.0.0.1"}, []string{"10.0.0.2"})
+
+	if err := createBlocks(a, &sa, 1); err != nil {
+		return err
+	}
+	ba, err := a.Visor.GetSignedBlockBySeq(1)
+	if err != nil {
+		return err
+	}
+
+	// Empty blocks created in the same second by the same key are the same block
+	time.Sleep(time.Until(time.Unix(int64(ba.Time())+1, 0)))
+
+	if err := createBlocks(b, &sb, 1); err != nil {
+		return err
+	}
+	bb, err := b.Visor.GetSignedBlockBySeq(1)
+	if err != nil {
+		return err
+	}
+
+	winner, loser := a, b
+	hashA, hashB := ba.HashHeader(), bb.HashHeader()
+	if hashA == hashB {
+		return fmt.Errorf("the block publishers created the same block at seq 1 across the partition")
+	}
+	if bytes.Compare(hashB[:], hashA[:]) < 0 {
+		winner, loser = b, a
+	}
+
+	n.Heal()
+
+	sa.Set(true)
+	defer sa.Set(false)
+	sb.Set(true)
+	defer sb.Set(false)
+
+	if err := wait(scenarioTimeout, func() (bool, error) {
+		return loser.Daemon.GetPublisherRole() == daemon.PublisherRoleStandby, nil
+	}); err != nil {
+		return fmt.Errorf("block publisher %s with the higher block hash at seq 1 did not stand by: %v", loser.Addr(), err)
+	}
+
+	// The conflict is recorded, so that the loser does not take the lease on its forked chain after a restart
+	if _, err := os.Stat(filepath.Join(loser.dir, daemon.PublisherConflictFilename)); err != nil {
+		return fmt.Errorf("block publisher %s did not record the conflict: %v", loser.Addr(), err)
+	}
+
+	if role := winner.Daemon.GetPublisherRole(); role != daemon.PublisherRoleActive {
+		return fmt.Errorf("block publisher %s with the lower block hash at seq 1 has role %s", winner.Addr(), role)
+	}
+
+	// The winner keeps creating blocks, and the loser does not take the lease back
+	head, err := winner.HeadSeq()
+	if err != nil {
+		return err
+	}
+	if err := waitForHeights(head+2, winner); err != nil {
+		return err
+	}
+
+	if role := loser.Daemon.GetPublisherRole(); role != daemon.PublisherRoleStandby {
+		return fmt.Errorf("block publisher %s took the lease back after standing by, its role is %s", loser.Addr(), role)
+	}
+
+	return nil
+}
+
+// runConsensusSplit runs two of three block publishers that agree on each block, partitioned so that each
+// proposes and signs its own block for seq 1, then heals the partition. The third block publisher is never started,
+// so neither block has the signatures of more than half of the block publishers until they sign again in a later round
+func runConsensusSplit(n *Network) error {
+	n.SetDefaultLink(Link{
+		Latency: 20 * time.Millisecond,
+	})
+
+	var pubkeys []cipher.PubKey
+	var seckeys []cipher.SecKey
+	for i := 0; i < 2; i++ {
+		pk, sk, err := cipher.GenerateDeterministicKeyPair([]byte(fmt.Sprintf("sim publisher %d", i)))
+		if err != nil {
+			return err
+		}
+		pubkeys = append(pubkeys, pk)
+		seckeys = append(seckeys, sk)
+	}
+
+	newConfig := func(host string, s *BlockSwitch) NodeConfig {
+		c := NewPublisherConfig(host, s)
+		c.PublisherPubkeys = pubkeys
+		c.Daemon.Daemon.ConsensusWait = 2 * time.Second
+		return c
+	}
+
+	// a has the Network's blockchain key, b has the first of the other keys, and the publisher
+	// with the second key is offline
+	var sa, sb BlockSwitch
+	a, err := n.NewNode(newConfig("10.0.0.1", &sa))
+	if err != nil {
+		return err
+	}
+
+	c := newConfig("10.0.0.2", &sb)
+	c.PublisherSeckey = seckeys[0]
+	c.Peers = []string{a.Addr()}
+	b, err := n.NewNode(c)
+	if err != nil {
+		return err
+	}
+
+	if err := b.WaitForConnections(1, scenarioTimeout); err != nil {
+		return fmt.Errorf("node %s did not connect: %v", b.Addr(), err)
+	}
+
+	n.Partition([]string{"10.0.0.1"}, []string{"10.0.0.2"})
+
+	// Each block publisher saves its vote before it sends its proposed block
+	propose := func(node *Node, s *BlockSwitch) (*visor.ConsensusVote, error) {
+		s.Set(true)
+		var v *visor.ConsensusVote
+		if err := wait(scenarioTimeout, func() (bool, error) {
+			var err error
+			v, err = node.Visor.GetConsensusVote()
+			return v != nil && v.Seq == 1, err
+		}); err != nil {
+			return nil, fmt.Errorf("block publisher %s did not propose a block at seq 1: %v", node.Addr(), err)
+		}
+		return v, nil
+	}
+
+	defer sa.Set(false)
+	defer sb.Set(false)
+
+	va, err := propose(a, &sa)
+	if err != nil {
+		return err
+	}
+
+	// Empty blocks created in the same second are the same block, whichever key signs them
+	time.Sleep(time.Second)
+
+	vb, err := propose(b, &sb)
+	if err != nil {
+		return err
+	}
+
+	if va.Hash == vb.Hash {
+		return fmt.Errorf("the block publishers proposed the same block at seq 1 across the partition")
+	}
+
+	n.Heal()
+
+	if err := waitForHeights(1, a, b); err != nil {
+		return err
+	}
+
+	ba, err := a.Visor.GetSignedBlockBySeq(1)
+	if err != nil {
+		return err
+	}
+	bb, err := b.Visor.GetSignedBlockBySeq(1)
+	if err != nil {
+		return err
+	}
+
+	if ba.HashHeader() != bb.HashHeader() {
+		return fmt.Errorf("the block publishers executed different blocks at seq 1")
+	}
+
+	for _, node := range []*Node{a, b} {
+		v, err := node.Visor.GetConsensusVote()
+		if err != nil {
+			return err
+		}
+		if v.Seq == 1 && v.Round == 0 {
+			return fmt.Errorf("block publisher %s agreed on the block at seq 1 without signing again in a later round", node.Addr())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The sync watchdog notices when the blockchain stops syncing.
+// Blocks are requested from the peers that report a higher height than ours, see requestBlocks.
+// A peer that does not answer a block request within SyncStallTimeout is disconnected,
+// since it advertises blocks that it does not serve. Its stats record the unanswered requests,
+// so it is less likely to be picked for outgoing connections again.
+// If the head block does not advance for SyncStallTimeout while peers report a higher height,
+// the sync is stalled. Blocks are then requested again from all peers right away,
+// instead of waiting for the next BlocksRequestRate tick. Blocks are also requested right away
+// after a peer is disconnected, and the outgoing connections ticker replaces the peer.
+
+// syncWatchdogRate is how often the sync watchdog checks the progress of the sync
+const syncWatchdogRate = 5 * time.Second
+
+// SyncState is the state of the blockchain sync
+type SyncState string
+
+const (
+	// SyncStateSynced the head block is at the highest height reported by peers
+	SyncStateSynced SyncState = "synced"
+	// SyncStateSyncing peers report a higher height and the head block is advancing
+	SyncStateSyncing SyncState = "syncing"
+	// SyncStateStalled peers report a higher height but the head block has not advanced for SyncStallTimeout
+	SyncStateStalled SyncState = "stalled"
+)
+
+// syncWatchdog tracks the progress of the sync
+type syncWatchdog struct {
+	sync.Mutex
+	headSeq uint64
+	// progressAt is when the head block last advanced, or was at the highest height reported by peers
+	progressAt time.Time
+	state      SyncState
+}
+
+func newSyncWatchdog() *syncWatchdog {
+	return &syncWatchdog{
+		progressAt: time.Now(),
+		state:      SyncStateSynced,
+	}
+}
+
+// update updates the sync state from the head block and the highest height reported by peers.
+// A stallTimeout of 0 never reports a stall. Returns the previous and the new state
+func (w *syncWatchdog) update(headSeq, highest uint64, now time.Time, stallTimeout time.Duration) (SyncState, SyncState) {
+	w.Lock()
+	defer w.Unlock()
+
+	prev := w.state
+
+	if headSeq != w.headSeq || highest <= headSeq {
+		w.headSeq = headSeq
+		w.progressAt = now
+	}
+
+	switch {
+	case highest <= headSeq:
+		w.state = SyncStateSynced
+	case stallTimeout != 0 && now.Sub(w.progressAt) >= stallTimeout:
+		w.state = SyncStateStalled
+	default:
+		w.state = SyncStateSyncing
+	}
+
+	return prev, w.state
+}
+
+func (w *syncWatchdog) getState() SyncState {
+	w.Lock()
+	defer w.Unlock()
+	return w.state
+}
+
+// checkSync disconnects the peers that have not answered a block request for SyncStallTimeout,
+// and requests blocks again if the sync has stalled
+func (dm *Daemon) checkSync() {
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		logger.WithError(err).Error("checkSync: visor.HeadBkSeq failed")
+		return
+	}
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	timeout := dm.config.SyncStallTimeout
+	rotated := false
+
+	if timeout != 0 {
+		for _, c := range dm.connections.unservedBlockRequests(headSeq, now.Add(-timeout)) {
+			logger.WithFields(logrus.Fields{
+				"addr":    c.Addr,
+				"gnetID":  c.gnetID,
+				"height":  c.Height,
+				"headSeq": headSeq,
+			}).Info("Peer did not serve the blocks that it advertises, disconnecting")
+
+			if err := dm.Disconnect(c.Addr, ErrDisconnectNoBlocksServed); err != nil {
+				logger.WithError(err).WithField("addr", c.Addr).Warning("Disconnect failed")
+				continue
+			}
+			rotated = true
+		}
+	}
+
+	highest := EstimateBlockchainHeight(headSeq, newPeerBlockchainHeights(dm.connections.all()))
+	prev, state := dm.syncWatchdog.update(headSeq, highest, now, timeout)
+
+	fields := logrus.Fields{
+		"headSeq": headSeq,
+		"highest": highest,
+	}
+
+	switch {
+	case state == SyncStateStalled && prev != SyncStateStalled:
+		logger.WithFields(fields).Warningf("Blockchain sync stalled, the head block has not advanced for %s", timeout)
+	case state != SyncStateStalled && prev == SyncStateStalled:
+		logger.WithFields(fields).Info("Blockchain sync resumed")
+	}
+
+	if (state == SyncStateStalled && prev != SyncStateStalled) || rotated {
+		if err := dm.requestBlocks(); err != nil {
+			logger.WithError(err).Debug("checkSync: requestBlocks failed")
+		}
+	}
+}
+
+// GetSyncState returns the state of the blockchain sync
+func (dm *Daemon) GetSyncState() SyncState {
+	return dm.syncWatchdog.getState()
+}
@@ -5,6 +5,7 @@ package fiber
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -34,6 +35,9 @@ type NodeConfig struct {
 	// BlockchainSeckey is a hex-encoded secret key required for block publishing.
 	// It must correspond to BlockchainPubkeyStr
 	BlockchainSeckeyStr string `mapstructure:"blockchain_seckey_str"`
+	// BlockPublisherPubkeys are hex-encoded public keys of other block publishers.
+	// Blocks signed by them are accepted, and the block publishers agree on each block
+	BlockPublisherPubkeys []string `mapstructure:"block_publisher_pubkeys"`
 	// GenesisTimestamp is the timestamp of the genesis block
 	GenesisTimestamp uint64 `mapstructure:"genesis_timestamp"`
 	// GenesisCoinVolume is the total number of coins in the genesis block
@@ -42,6 +46,9 @@ type NodeConfig struct {
 	DefaultConnections []string `mapstructure:"default_connections"`
 	// PeerlistURL is a URL pointing to a newline-separated list of ip:ports that are used for bootstrapping (but they are not "trusted")
 	PeerListURL string `mapstructure:"peer_list_url"`
+	// DNSSeeds are hostnames whose A and AAAA records are used for bootstrapping (but they are not "trusted").
+	// A seed may include a port, otherwise Port is used
+	DNSSeeds []string `mapstructure:"dns_seeds"`
 
 	// UnconfirmedBurnFactor is the burn factor to apply when verifying unconfirmed transactions
 	UnconfirmedBurnFactor uint32 `mapstructure:"unconfirmed_burn_factor"`
@@ -57,6 +64,24 @@ type NodeConfig struct {
 	CreateBlockMaxDropletPrecision uint8 `mapstructure:"create_block_max_decimals"`
 	// MaxBlockTransactionsSize is the maximum total size of transactions in a block when publishing a block
 	MaxBlockTransactionsSize uint32 `mapstructure:"max_block_transactions_size"`
+	// MaxBlockTransactionsPerAddress is the maximum number of transactions spending from the same address
+	// in a block when publishing a block. 0 is unlimited
+	MaxBlockTransactionsPerAddress int `mapstructure:"max_block_transactions_per_address"`
+	// AllowEmptyBlocks makes the blockchain accept blocks with no transactions
+	AllowEmptyBlocks bool `mapstructure:"allow_empty_blocks"`
+
+	// BlockCreationInterval is how often the block publisher decides whether to create a block, in seconds
+	BlockCreationInterval uint64 `mapstructure:"block_creation_interval"`
+	// BlockMinTransactions is the minimum number of valid unconfirmed transactions to create a block
+	BlockMinTransactions int `mapstructure:"block_min_transactions"`
+	// BlockMinFee is the minimum total fee of the valid unconfirmed transactions to create a block, in coin hours
+	BlockMinFee uint64 `mapstructure:"block_min_fee"`
+	// MaxBlockAge is how old the head block may get before a block is created even if BlockMinTransactions
+	// or BlockMinFee are not reached, in seconds. 0 waits for them indefinitely
+	MaxBlockAge uint64 `mapstructure:"max_block_age"`
+	// EmptyBlockInterval is how old the head block may get before an empty block is created, if there are
+	// no valid unconfirmed transactions, in seconds. 0 disables empty blocks. Requires AllowEmptyBlocks
+	EmptyBlockInterval uint64 `mapstructure:"empty_block_interval"`
 
 	// DisplayName is the display name of the coin in the wallet e.g. Laqpay
 	DisplayName string `mapstructure:"display_name"`
@@ -91,6 +116,9 @@ type ParamsConfig struct {
 	// UnlockTimeInterval is the distribution address unlock time interval, measured in seconds.
 	// Once the InitialUnlockedCount is exhausted, UnlockAddressRate addresses will be unlocked per UnlockTimeInterval
 	UnlockTimeInterval uint64 `mapstructure:"unlock_time_interval"`
+	// UnlockStartTime is the unix time of the first time-based unlock of UnlockAddressRate addresses.
+	// 0 disables time-based unlocking
+	UnlockStartTime uint64 `mapstructure:"unlock_start_time"`
 	// UserMaxDropletPrecision represents the decimal precision of droplets
 	UserMaxDropletPrecision uint64 `mapstructure:"user_max_decimals"`
 	// UserMaxTransactionSize is max size of a user-created transaction (typically equal to the max size of a block)
@@ -100,11 +128,36 @@ type ParamsConfig struct {
 	DistributionAddresses []string `mapstructure:"distribution_addresses"`
 	// UserBurnFactor inverse fraction of coinhours that must be burned, this value is used when creating transactions
 	UserBurnFactor uint64 `mapstructure:"user_burn_factor"`
+	// Checkpoints are the block hashes that the blockchain must contain, sorted by ascending seq.
+	// The signatures of transactions in blocks at or below the latest checkpoint are not verified when syncing.
+	// They are written to params.MainNetCheckpoints by newcoin -regenerate
+	Checkpoints []CheckpointConfig `mapstructure:"checkpoints"`
+	// PublisherKeyChanges hand off block publishing to new public keys, sorted by ascending seq
+	PublisherKeyChanges []PublisherKeyChangeConfig `mapstructure:"publisher_key_changes"`
+}
+
+// CheckpointConfig is a block checkpoint
+type CheckpointConfig struct {
+	// Seq is the sequence of the block
+	Seq uint64 `mapstructure:"seq"`
+	// Hash is the hex-encoded header hash of the block
+	Hash string `mapstructure:"hash"`
+}
+
+// PublisherKeyChangeConfig is a block publisher key change
+type PublisherKeyChangeConfig struct {
+	// Seq is the sequence of the first block signed by Pubkey
+	Seq uint64 `mapstructure:"seq"`
+	// Pubkey is the hex-encoded public key of the new block publisher
+	Pubkey string `mapstructure:"pubkey"`
+	// Sig is the hex-encoded signature of the change by the previous block publisher
+	Sig string `mapstructure:"sig"`
 }
 
 // NewConfig loads blockchain config parameters from a config file
 // default file is: fiber.toml in the project root
 // JSON, toml or yaml file can be used (toml preferred).
+// Each call reads the named file only, so that a fiber.json next to a fiber.toml is not picked up instead.
 func NewConfig(configName, appDir string) (Config, error) {
 	// set viper parameters
 	// check that file is of supported type
@@ -112,63 +165,68 @@ func NewConfig(configName, appDir string) (Config, error) {
 	fileType := confNameSplit[len(confNameSplit)-1]
 	switch fileType {
 	case "toml", "json", "yaml", "yml":
-		viper.SetConfigType(confNameSplit[len(confNameSplit)-1])
 	default:
 		return Config{}, fmt.Errorf("invalid blockchain config file type: %s", fileType)
 	}
 
-	configName = configName[:len(configName)-(len(fileType)+1)]
-	viper.SetConfigName(configName)
-
-	viper.AddConfigPath(appDir)
-	viper.AddConfigPath(".")
+	v := viper.New()
+	v.SetConfigType(fileType)
+	v.SetConfigFile(filepath.Join(appDir, configName))
 
 	// set defaults
-	setDefaults()
+	setDefaults(v)
 
 	params := Config{}
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := v.ReadInConfig(); err != nil {
 		return params, err
 	}
 
-	if err := viper.Unmarshal(&params); err != nil {
+	if err := v.Unmarshal(&params); err != nil {
 		return params, err
 	}
 
 	return params, nil
 }
 
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
 	// node defaults
-	viper.SetDefault("node.genesis_coin_volume", 100e12)
-	viper.SetDefault("node.port", 6000)
-	viper.SetDefault("node.web_interface_port", 6420)
-	viper.SetDefault("node.unconfirmed_burn_factor", 10)
-	viper.SetDefault("node.unconfirmed_max_transaction_size", 32*1024)
-	viper.SetDefault("node.unconfirmed_max_decimals", 3)
-	viper.SetDefault("node.create_block_burn_factor", 10)
-	viper.SetDefault("node.create_block_max_transaction_size", 32*1024)
-	viper.SetDefault("node.create_block_max_decimals", 3)
-	viper.SetDefault("node.max_block_transactions_size", 32*1024)
-	viper.SetDefault("node.display_name", "LAQ")
-	viper.SetDefault("node.ticker", "LAQ")
-	viper.SetDefault("node.coin_hours_display_name", "LAQH")
-	viper.SetDefault("node.coin_hours_display_name_singular", "LAQH")
-	viper.SetDefault("node.coin_hours_ticker", "LAQH")
-	viper.SetDefault("node.explorer_url", "https://explorer.laqpay.com")
-	viper.SetDefault("node.bip44_coin", bip44.CoinTypeLaqpay)
+	v.SetDefault("node.genesis_coin_volume", 100e12)
+	v.SetDefault("node.port", 6000)
+	v.SetDefault("node.web_interface_port", 6420)
+	v.SetDefault("node.unconfirmed_burn_factor", 10)
+	v.SetDefault("node.unconfirmed_max_transaction_size", 32*1024)
+	v.SetDefault("node.unconfirmed_max_decimals", 3)
+	v.SetDefault("node.create_block_burn_factor", 10)
+	v.SetDefault("node.create_block_max_transaction_size", 32*1024)
+	v.SetDefault("node.create_block_max_decimals", 3)
+	v.SetDefault("node.max_block_transactions_size", 32*1024)
+	v.SetDefault("node.max_block_transactions_per_address", 0)
+	v.SetDefault("node.allow_empty_blocks", false)
+	v.SetDefault("node.block_creation_interval", 10)
+	v.SetDefault("node.block_min_transactions", 1)
+	v.SetDefault("node.block_min_fee", 0)
+	v.SetDefault("node.max_block_age", 0)
+	v.SetDefault("node.empty_block_interval", 0)
+	v.SetDefault("node.display_name", "LAQ")
+	v.SetDefault("node.ticker", "LAQ")
+	v.SetDefault("node.coin_hours_display_name", "LAQH")
+	v.SetDefault("node.coin_hours_display_name_singular", "LAQH")
+	v.SetDefault("node.coin_hours_ticker", "LAQH")
+	v.SetDefault("node.explorer_url", "https://explorer.laqpay.com")
+	v.SetDefault("node.bip44_coin", bip44.CoinTypeLaqpay)
 
 	// build defaults
-	viper.SetDefault("build.commit", "")
-	viper.SetDefault("build.branch", "")
+	v.SetDefault("build.commit", "")
+	v.SetDefault("build.branch", "")
 
 	// params defaults
-	viper.SetDefault("params.max_coin_supply", 1e8)
-	viper.SetDefault("params.initial_unlocked_count", 25)
-	viper.SetDefault("params.unlock_address_rate", 5)
-	viper.SetDefault("params.unlock_time_interval", 60*60*24*365)
-	viper.SetDefault("params.user_max_decimals", 3)
-	viper.SetDefault("params.user_burn_factor", 10)
-	viper.SetDefault("params.user_max_transaction_size", 32*1024)
+	v.SetDefault("params.max_coin_supply", 1e8)
+	v.SetDefault("params.initial_unlocked_count", 25)
+	v.SetDefault("params.unlock_address_rate", 5)
+	v.SetDefault("params.unlock_time_interval", 60*60*24*365)
+	v.SetDefault("params.unlock_start_time", 0)
+	v.SetDefault("params.user_max_decimals", 3)
+	v.SetDefault("params.user_burn_factor", 10)
+	v.SetDefault("params.user_max_transaction_size", 32*1024)
 }
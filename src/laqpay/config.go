@@ -34,6 +34,7 @@ import (
 	"../../src/util/certutil"
 	"../../src/util/droplet"
 	"../../src/util/file"
+	"../../src/util/iputil"
 	"../../src/util/logging"
 	"../../src/util/useragent"
 	"../../src/visor"
@@ -56,18 +57,34 @@ type NodeConfig struct {
 	// Name of the coin
 	CoinName string
 
+	// ConfigFile is a TOML or YAML file with option values, see ApplyConfigSources
+	ConfigFile string
+	// configErrors are the invalid option values found by ApplyConfigSources
+	configErrors ConfigErrors
+	// configSources are where the options were set, as found by ApplyConfigSources
+	configSources map[string]string
+	// effectiveConfig are the final option values and where they were set, see buildEffectiveConfig
+	effectiveConfig []readable.ConfigOption
+
 	// Disable peer exchange
 	DisablePEX bool
 	// Download peer list
 	DownloadPeerList bool
 	// Download the peers list from this URL
 	PeerListURL string
+	// Dial outgoing connections through this SOCKS5 proxy, e.g. socks5://127.0.0.1:9050
+	Proxy string
+	// Disable peer discovery from the DNS seeds
+	DisableDNSSeeds bool
 	// Don't make any outgoing connections
 	DisableOutgoingConnections bool
 	// Don't allowing incoming connections
 	DisableIncomingConnections bool
 	// Disables networking altogether
 	DisableNetworking bool
+	// Network is the network to run on, NetworkMainnet or NetworkRegtest
+	Network string
+	regtest *regtestChain
 	// Enable GUI
 	EnableGUI bool
 	// Disable CSRF check in the wallet API
@@ -107,6 +124,25 @@ type NodeConfig struct {
 	MaxOutgoingMessageLength int
 	// MaxIncomingMessageLength maximum size of incoming messages
 	MaxIncomingMessageLength int
+	// MaxUploadRate maximum upload rate of all connections combined, in bytes per second. 0 is unlimited
+	MaxUploadRate int
+	// MaxDownloadRate maximum download rate of all connections combined, in bytes per second. 0 is unlimited
+	MaxDownloadRate int
+	// MaxConnectionUploadRate maximum upload rate of each connection, in bytes per second. 0 is unlimited
+	MaxConnectionUploadRate int
+	// MaxConnectionDownloadRate maximum download rate of each connection, in bytes per second. 0 is unlimited
+	MaxConnectionDownloadRate int
+	// CompressionThreshold messages longer than this are sent compressed to peers that accept compression. 0 disables compression
+	CompressionThreshold int
+	// Dandelion relays transactions created by this node through a random path of peers before they are broadcast
+	Dandelion bool
+	// DandelionEmbargo is how long to wait for a transaction relayed by Dandelion to be broadcast by another node
+	DandelionEmbargo time.Duration
+	// ConsensusWait is how long to wait for the block publishers to agree on the next block before retrying
+	ConsensusWait time.Duration
+	// SyncStallTimeout is how long a peer may leave a block request unanswered, and how long the head block
+	// may not advance while peers report a higher height, before the sync watchdog acts. 0 disables the watchdog
+	SyncStallTimeout time.Duration
 	// PeerlistSize represents the maximum number of peers that the pex would maintain
 	PeerlistSize int
 	// Wallet Address Version
@@ -157,6 +193,8 @@ type NodeConfig struct {
 	VerifyDB bool
 	// Reset the database if integrity checks fail, and continue running
 	ResetCorruptDB bool
+	// Skip the verification of transaction signatures in blocks at or below the latest checkpoint
+	AssumeValid bool
 
 	// Transaction verification parameters for unconfirmed transactions
 	UnconfirmedVerifyTxn params.VerifyTxn
@@ -164,6 +202,10 @@ type NodeConfig struct {
 	CreateBlockVerifyTxn params.VerifyTxn
 	// Maximum total size of transactions in a block
 	MaxBlockTransactionsSize uint32
+	// Maximum number of transactions spending from the same address in a block, 0 is unlimited
+	MaxBlockTransactionsPerAddress int
+	// Accept blocks with no transactions
+	AllowEmptyBlocks bool
 
 	unconfirmedBurnFactor          uint64
 	maxUnconfirmedTransactionSize  uint64
@@ -190,6 +232,35 @@ type NodeConfig struct {
 	CustomPeersFile string
 
 	RunBlockPublisher bool
+	// PublisherStandby runs the block publisher as a hot standby, which creates blocks only after
+	// the lease of the active block publisher expires
+	PublisherStandby bool
+	// PublisherLeaseIntervals is how many block creation intervals valid unconfirmed transactions may wait
+	// without a new block before the lease of the active block publisher expires
+	PublisherLeaseIntervals uint64
+	// BlockSignerCommand is a command that signs the blocks created by the block publisher,
+	// in place of the blockchain secret key. See visor.CommandSigner
+	BlockSignerCommand string
+	// BlockSignerPubkeyStr is the public key that BlockSignerCommand signs with. Defaults to BlockchainPubkeyStr
+	BlockSignerPubkeyStr string
+	// BlockSignerTimeout is how long BlockSignerCommand may take to sign a block
+	BlockSignerTimeout time.Duration
+	// BlockCreationInterval is how often the block publisher decides whether to create a block, in seconds
+	BlockCreationInterval uint64
+	// BlockMinTransactions is the minimum number of valid unconfirmed transactions to create a block
+	BlockMinTransactions int
+	// BlockMinFee is the minimum total fee of the valid unconfirmed transactions to create a block, in coin hours
+	BlockMinFee uint64
+	// MaxBlockAge is how old the head block may get before a block is created even if
+	// BlockMinTransactions or BlockMinFee are not reached, in seconds. 0 waits for them indefinitely
+	MaxBlockAge uint64
+	// EmptyBlockInterval is how old the head block may get before an empty block is created,
+	// if there are no valid unconfirmed transactions, in seconds. 0 disables empty blocks
+	EmptyBlockInterval uint64
+	// BlockchainKeystore is a keystore file with the encrypted blockchain secret key, see wallet.Keystore
+	BlockchainKeystore string
+	// BlockchainKeystorePasswordFd is a file descriptor to read the keystore password from, if >= 0
+	BlockchainKeystorePasswordFd int
 
 	/* Developer options */
 
@@ -211,9 +282,15 @@ type NodeConfig struct {
 	GenesisAddressStr   string
 	BlockchainPubkeyStr string
 	BlockchainSeckeyStr string
-	GenesisTimestamp    uint64
-	GenesisCoinVolume   uint64
-	DefaultConnections  []string
+	// BlockPublisherPubkeysStr is a comma-separated list of the public keys of other block publishers
+	BlockPublisherPubkeysStr string
+	GenesisTimestamp         uint64
+	GenesisCoinVolume        uint64
+	DefaultConnections       []string
+	DNSSeeds                 []string
+
+	// dnsSeedPort is the port of peers returned by a DNS seed
+	dnsSeedPort uint16
 
 	genesisSignature cipher.Sig
 	genesisAddress   cipher.Address
@@ -222,20 +299,27 @@ type NodeConfig struct {
 	blockchainPubkey cipher.PubKey
 	blockchainSeckey cipher.SecKey
 
+	blockPublisherPubkeys []cipher.PubKey
+
+	blockSigner visor.BlockSigner
+
 	Fiber readable.FiberConfig
 }
 
 // NewNodeConfig returns a new node config instance
 func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 	nodeConfig := NodeConfig{
-		CoinName:            node.CoinName,
-		GenesisSignatureStr: node.GenesisSignatureStr,
-		GenesisAddressStr:   node.GenesisAddressStr,
-		GenesisCoinVolume:   node.GenesisCoinVolume,
-		GenesisTimestamp:    node.GenesisTimestamp,
-		BlockchainPubkeyStr: node.BlockchainPubkeyStr,
-		BlockchainSeckeyStr: node.BlockchainSeckeyStr,
-		DefaultConnections:  node.DefaultConnections,
+		CoinName:                 node.CoinName,
+		GenesisSignatureStr:      node.GenesisSignatureStr,
+		GenesisAddressStr:        node.GenesisAddressStr,
+		GenesisCoinVolume:        node.GenesisCoinVolume,
+		GenesisTimestamp:         node.GenesisTimestamp,
+		BlockchainPubkeyStr:      node.BlockchainPubkeyStr,
+		BlockchainSeckeyStr:      node.BlockchainSeckeyStr,
+		BlockPublisherPubkeysStr: strings.Join(node.BlockPublisherPubkeys, ","),
+		DefaultConnections:       node.DefaultConnections,
+		DNSSeeds:                 node.DNSSeeds,
+		dnsSeedPort:              uint16(node.Port),
 		// Disable peer exchange
 		DisablePEX: false,
 		// Don't make any outgoing connections
@@ -244,6 +328,7 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 		DisableIncomingConnections: false,
 		// Disables networking altogether
 		DisableNetworking: false,
+		Network:           NetworkMainnet,
 		// Enable GUI
 		EnableGUI: false,
 		// Disable CSRF check in the wallet API
@@ -271,6 +356,11 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 		OutgoingConnectionsRate:  time.Second * 5,
 		MaxOutgoingMessageLength: 256 * 1024,
 		MaxIncomingMessageLength: 1024 * 1024,
+		CompressionThreshold:     1024,
+		Dandelion:                false,
+		DandelionEmbargo:         time.Second * 30,
+		ConsensusWait:            time.Second * 5,
+		SyncStallTimeout:         time.Minute * 5,
 		PeerlistSize:             65535,
 		// Wallet Address Version
 		// AddressVersion: "test",
@@ -301,6 +391,7 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 
 		VerifyDB:       true,
 		ResetCorruptDB: true,
+		AssumeValid:    true,
 
 		// Blockchain/transaction validation
 		UnconfirmedVerifyTxn: params.VerifyTxn{
@@ -313,7 +404,9 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 			MaxTransactionSize:  node.CreateBlockMaxTransactionSize,
 			MaxDropletPrecision: node.CreateBlockMaxDropletPrecision,
 		},
-		MaxBlockTransactionsSize: node.MaxBlockTransactionsSize,
+		MaxBlockTransactionsSize:       node.MaxBlockTransactionsSize,
+		MaxBlockTransactionsPerAddress: node.MaxBlockTransactionsPerAddress,
+		AllowEmptyBlocks:               node.AllowEmptyBlocks,
 
 		// Wallets
 		WalletDirectory:  "",
@@ -332,7 +425,17 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 		HTTPWriteTimeout: time.Second * 60,
 		HTTPIdleTimeout:  time.Second * 120,
 
-		RunBlockPublisher: false,
+		RunBlockPublisher:       false,
+		PublisherStandby:        false,
+		PublisherLeaseIntervals: 3,
+		BlockSignerTimeout:      time.Second * 10,
+		BlockCreationInterval:   node.BlockCreationInterval,
+		BlockMinTransactions:    node.BlockMinTransactions,
+		BlockMinFee:             node.BlockMinFee,
+		MaxBlockAge:             node.MaxBlockAge,
+		EmptyBlockInterval:      node.EmptyBlockInterval,
+
+		BlockchainKeystorePasswordFd: -1,
 
 		// Enable cpu profiling
 		ProfileCPU: false,
@@ -365,33 +468,87 @@ func (c *Config) postProcess() error {
 		os.Exit(0)
 	}
 
+	// The option values before they are changed below, to report which ones were derived
+	prevValues := flagValues()
+
+	// Invalid option values are reported by validate, with the other errors
+	configError := func(err error) {
+		c.Node.configErrors = append(c.Node.configErrors, err)
+	}
+
+	switch c.Node.Network {
+	case NetworkMainnet:
+	case NetworkRegtest:
+		if err := c.applyRegtest(); err != nil {
+			return err
+		}
+	default:
+		configError(fmt.Errorf("Invalid -network %q, must be %s or %s", c.Node.Network, NetworkMainnet, NetworkRegtest))
+	}
+
 	var err error
 	if c.Node.GenesisSignatureStr != "" {
 		c.Node.genesisSignature, err = cipher.SigFromHex(c.Node.GenesisSignatureStr)
-		panicIfError(err, "Invalid Signature")
+		if err != nil {
+			configError(fmt.Errorf("Invalid -genesis-signature: %v", err))
+		}
 	}
 
+	genesisAddressOK := true
 	if c.Node.GenesisAddressStr != "" {
 		c.Node.genesisAddress, err = cipher.DecodeBase58Address(c.Node.GenesisAddressStr)
-		panicIfError(err, "Invalid Address")
+		if err != nil {
+			configError(fmt.Errorf("Invalid -genesis-address: %v", err))
+			genesisAddressOK = false
+		}
 	}
 
 	// Compute genesis block hash
-	gb, err := coin.NewGenesisBlock(c.Node.genesisAddress, c.Node.GenesisCoinVolume, c.Node.GenesisTimestamp)
-	if err != nil {
-		panicIfError(err, "Create genesis hash failed")
+	if genesisAddressOK {
+		gb, err := coin.NewGenesisBlock(c.Node.genesisAddress, c.Node.GenesisCoinVolume, c.Node.GenesisTimestamp)
+		if err != nil {
+			configError(fmt.Errorf("Create genesis block failed: %v", err))
+		} else {
+			c.Node.genesisHash = gb.HashHeader()
+		}
 	}
-	c.Node.genesisHash = gb.HashHeader()
 
 	if c.Node.BlockchainPubkeyStr != "" {
 		c.Node.blockchainPubkey, err = cipher.PubKeyFromHex(c.Node.BlockchainPubkeyStr)
-		panicIfError(err, "Invalid Pubkey")
+		if err != nil {
+			configError(fmt.Errorf("Invalid -blockchain-public-key: %v", err))
+		}
+	}
+	c.Node.blockPublisherPubkeys = nil
+	for _, s := range strings.Split(c.Node.BlockPublisherPubkeysStr, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		pk, err := cipher.PubKeyFromHex(s)
+		if err != nil {
+			configError(fmt.Errorf("Invalid -block-publisher-public-keys %s: %v", s, err))
+			continue
+		}
+		c.Node.blockPublisherPubkeys = append(c.Node.blockPublisherPubkeys, pk)
 	}
+
 	if c.Node.BlockchainSeckeyStr != "" {
 		c.Node.blockchainSeckey, err = cipher.SecKeyFromHex(c.Node.BlockchainSeckeyStr)
-		panicIfError(err, "Invalid Seckey")
+		if err != nil {
+			// The value is not included in the error, it is a secret
+			configError(errors.New("Invalid -blockchain-secret-key"))
+		}
 		c.Node.BlockchainSeckeyStr = ""
 	}
+
+	blockSignerPubkey := c.Node.blockchainPubkey
+	if c.Node.BlockSignerPubkeyStr != "" {
+		blockSignerPubkey, err = cipher.PubKeyFromHex(c.Node.BlockSignerPubkeyStr)
+		if err != nil {
+			configError(fmt.Errorf("Invalid -block-signer-public-key: %v", err))
+		}
+	}
 	if c.Node.BlockchainSeckeyStr != "" {
 		c.Node.blockchainSeckey = cipher.SecKey{}
 	}
@@ -443,14 +600,20 @@ func (c *Config) postProcess() error {
 	}
 
 	if _, err := userAgentData.Build(); err != nil {
-		return err
+		configError(err)
 	}
 
 	c.Node.userAgent = userAgentData
 
 	apiSets, err := buildAPISets(c.Node)
 	if err != nil {
-		return err
+		configError(err)
+		apiSets = make(map[string]struct{})
+	}
+
+	// The regtest API set can't be enabled on other networks
+	if c.Node.regtest != nil {
+		apiSets[api.EndpointsRegtest] = struct{}{}
 	}
 
 	// Don't open browser to load wallets if wallet apis are disabled.
@@ -468,44 +631,31 @@ func (c *Config) postProcess() error {
 		c.Node.DefaultConnections = nil
 	}
 
-	if c.Node.HostWhitelist != "" {
-		if c.Node.DisableHeaderCheck {
-			return errors.New("host whitelist should be empty when header check is disabled")
-		}
-		c.Node.hostWhitelist = strings.Split(c.Node.HostWhitelist, ",")
-	}
-
-	httpAuthEnabled := c.Node.WebInterfaceUsername != "" || c.Node.WebInterfacePassword != ""
-	if httpAuthEnabled && !c.Node.WebInterfaceHTTPS && !c.Node.WebInterfacePlaintextAuth {
-		return errors.New("Web interface auth enabled but HTTPS is not enabled. Use -web-interface-plaintext-auth=true if this is desired")
+	if c.Node.DisableDNSSeeds {
+		c.Node.DNSSeeds = nil
 	}
 
-	if c.Node.MaxConnections < c.Node.MaxOutgoingConnections+c.Node.MaxDefaultPeerOutgoingConnections {
-		return errors.New("-max-connections must be >= -max-outgoing-connections + -max-default-peer-outgoing-connections")
+	if err := c.Node.validate(); err != nil {
+		return err
 	}
 
-	if c.Node.MaxOutgoingConnections > c.Node.MaxConnections {
-		return errors.New("-max-outgoing-connections cannot be higher than -max-connections")
+	if c.Node.HostWhitelist != "" {
+		c.Node.hostWhitelist = strings.Split(c.Node.HostWhitelist, ",")
 	}
 
-	if c.Node.maxBlockSize > math.MaxUint32 {
-		return errors.New("-max-block-size exceeds MaxUint32")
-	}
-	if c.Node.maxUnconfirmedTransactionSize > math.MaxUint32 {
-		return errors.New("-max-txn-size-unconfirmed exceeds MaxUint32")
-	}
-	if c.Node.unconfirmedBurnFactor > math.MaxUint32 {
-		return errors.New("-burn-factor-unconfirmed exceeds MaxUint32")
-	}
-	if c.Node.createBlockBurnFactor > math.MaxUint32 {
-		return errors.New("-burn-factor-create-block exceeds MaxUint32")
+	if c.Node.BlockSignerCommand != "" {
+		c.Node.blockSigner, err = visor.NewCommandSigner(blockSignerPubkey, c.Node.BlockSignerCommand, c.Node.BlockSignerTimeout)
+		if err != nil {
+			return err
+		}
 	}
 
-	if c.Node.unconfirmedMaxDropletPrecision > math.MaxUint8 {
-		return errors.New("-max-decimals-unconfirmed exceeds MaxUint8")
-	}
-	if c.Node.createBlockMaxDropletPrecision > math.MaxUint8 {
-		return errors.New("-max-decimals-create-block exceeds MaxUint8")
+	if c.Node.BlockchainKeystore != "" {
+		c.Node.BlockchainKeystore = replaceHome(c.Node.BlockchainKeystore, home)
+		c.Node.blockchainSeckey, err = unlockBlockchainKeystore(c.Node.BlockchainKeystore, c.Node.BlockchainKeystorePasswordFd)
+		if err != nil {
+			return err
+		}
 	}
 
 	c.Node.UnconfirmedVerifyTxn.BurnFactor = uint32(c.Node.unconfirmedBurnFactor)
@@ -516,58 +666,108 @@ func (c *Config) postProcess() error {
 	c.Node.CreateBlockVerifyTxn.MaxDropletPrecision = uint8(c.Node.createBlockMaxDropletPrecision)
 	c.Node.MaxBlockTransactionsSize = uint32(c.Node.maxBlockSize)
 
-	if c.Node.UnconfirmedVerifyTxn.MaxTransactionSize < params.MinTransactionSize {
-		return fmt.Errorf("-max-txn-size-unconfirmed must be >= params.MinTransactionSize (%d)", params.MinTransactionSize)
-	}
-	if c.Node.UnconfirmedVerifyTxn.MaxTransactionSize < params.UserVerifyTxn.MaxTransactionSize {
-		return fmt.Errorf("-max-txn-size-unconfirmed must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize)
-	}
-	if c.Node.CreateBlockVerifyTxn.MaxTransactionSize < params.MinTransactionSize {
-		return fmt.Errorf("-max-txn-size-create-block must be >= params.MinTransactionSize (%d)", params.MinTransactionSize)
-	}
-	if c.Node.CreateBlockVerifyTxn.MaxTransactionSize < params.UserVerifyTxn.MaxTransactionSize {
-		return fmt.Errorf("-max-txn-size-create-block must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize)
-	}
+	c.Node.buildEffectiveConfig(prevValues)
 
-	if c.Node.MaxBlockTransactionsSize < params.MinTransactionSize {
-		return fmt.Errorf("-max-block-size must be >= params.MinTransactionSize (%d)", params.MinTransactionSize)
-	}
-	if c.Node.MaxBlockTransactionsSize < params.UserVerifyTxn.MaxTransactionSize {
-		return fmt.Errorf("-max-block-size must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize)
-	}
-	if c.Node.MaxBlockTransactionsSize < c.Node.UnconfirmedVerifyTxn.MaxTransactionSize {
-		return errors.New("-max-block-size must be >= -max-txn-size-unconfirmed")
-	}
-	if c.Node.MaxBlockTransactionsSize < c.Node.CreateBlockVerifyTxn.MaxTransactionSize {
-		return errors.New("-max-block-size must be >= -max-txn-size-create-block")
-	}
+	return nil
+}
 
-	if c.Node.UnconfirmedVerifyTxn.BurnFactor < params.MinBurnFactor {
-		return fmt.Errorf("-burn-factor-unconfirmed must be >= params.MinBurnFactor (%d)", params.MinBurnFactor)
-	}
-	if c.Node.UnconfirmedVerifyTxn.BurnFactor < params.UserVerifyTxn.BurnFactor {
-		return fmt.Errorf("-burn-factor-unconfirmed must be >= params.UserVerifyTxn.BurnFactor (%d)", params.UserVerifyTxn.BurnFactor)
-	}
+// ConfigErrors are the errors of an invalid config, reported together
+type ConfigErrors []error
 
-	if c.Node.CreateBlockVerifyTxn.BurnFactor < params.MinBurnFactor {
-		return fmt.Errorf("-burn-factor-create-block must be >= params.MinBurnFactor (%d)", params.MinBurnFactor)
+func (e ConfigErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
 	}
-	if c.Node.CreateBlockVerifyTxn.BurnFactor < params.UserVerifyTxn.BurnFactor {
-		return fmt.Errorf("-burn-factor-create-block must be >= params.UserVerifyTxn.BurnFactor (%d)", params.UserVerifyTxn.BurnFactor)
+
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
 	}
 
-	if c.Node.UnconfirmedVerifyTxn.MaxDropletPrecision > droplet.Exponent {
-		return fmt.Errorf("-max-decimals-unconfirmed must be <= droplet.Exponent (%d)", droplet.Exponent)
+	return fmt.Sprintf("%d config errors:\n\t%s", len(e), strings.Join(msgs, "\n\t"))
+}
+
+// validate checks the option values, including the invalid values found by ApplyConfigSources.
+// All errors are returned at once as ConfigErrors
+func (c *NodeConfig) validate() error {
+	errs := append(ConfigErrors{}, c.configErrors...)
+	check := func(ok bool, err error) {
+		if !ok {
+			errs = append(errs, err)
+		}
 	}
-	if c.Node.UnconfirmedVerifyTxn.MaxDropletPrecision < params.UserVerifyTxn.MaxDropletPrecision {
-		return fmt.Errorf("-max-decimals-unconfirmed must be >= params.UserVerifyTxn.MaxDropletPrecision (%d)", params.UserVerifyTxn.MaxDropletPrecision)
+
+	check(c.HostWhitelist == "" || !c.DisableHeaderCheck, errors.New("host whitelist should be empty when header check is disabled"))
+
+	httpAuthEnabled := c.WebInterfaceUsername != "" || c.WebInterfacePassword != ""
+	check(!httpAuthEnabled || c.WebInterfaceHTTPS || c.WebInterfacePlaintextAuth,
+		errors.New("Web interface auth enabled but HTTPS is not enabled. Use -web-interface-plaintext-auth=true if this is desired"))
+
+	check(c.MaxConnections >= c.MaxOutgoingConnections+c.MaxDefaultPeerOutgoingConnections,
+		errors.New("-max-connections must be >= -max-outgoing-connections + -max-default-peer-outgoing-connections"))
+	check(c.MaxOutgoingConnections <= c.MaxConnections, errors.New("-max-outgoing-connections cannot be higher than -max-connections"))
+	check(c.MaxUploadRate >= 0, errors.New("-max-upload-rate must be >= 0"))
+	check(c.MaxDownloadRate >= 0, errors.New("-max-download-rate must be >= 0"))
+	check(c.MaxConnectionUploadRate >= 0, errors.New("-max-connection-upload-rate must be >= 0"))
+	check(c.MaxConnectionDownloadRate >= 0, errors.New("-max-connection-download-rate must be >= 0"))
+	check(c.CompressionThreshold >= 0, errors.New("-compression-threshold must be >= 0"))
+	check(c.DandelionEmbargo > 0, errors.New("-dandelion-embargo must be > 0"))
+	check(c.ConsensusWait > 0, errors.New("-consensus-wait must be > 0"))
+	check(c.SyncStallTimeout >= 0, errors.New("-sync-stall-timeout must be >= 0"))
+
+	check(!c.PublisherStandby || c.RunBlockPublisher, errors.New("-block-publisher-standby requires -block-publisher"))
+	check(!c.PublisherStandby || len(c.blockPublisherPubkeys) == 0, errors.New("-block-publisher-standby can't be used with -block-publisher-public-keys"))
+	check(c.PublisherLeaseIntervals >= 2, errors.New("-block-publisher-lease-intervals must be >= 2"))
+
+	if c.BlockSignerCommand != "" {
+		check(c.RunBlockPublisher, errors.New("-block-signer-command requires -block-publisher"))
+		check(c.blockchainSeckey == (cipher.SecKey{}), errors.New("-block-signer-command can't be used with -blockchain-secret-key"))
+		check(len(c.blockPublisherPubkeys) == 0, errors.New("-block-signer-command can't be used with -block-publisher-public-keys"))
 	}
 
-	if c.Node.CreateBlockVerifyTxn.MaxDropletPrecision > droplet.Exponent {
-		return fmt.Errorf("-max-decimals-create-block must be <= droplet.Exponent (%d)", droplet.Exponent)
+	check(c.BlockCreationInterval >= 1, errors.New("-block-creation-interval must be >= 1"))
+	check(c.BlockMinTransactions >= 1, errors.New("-block-min-txns must be >= 1"))
+	check(c.EmptyBlockInterval == 0 || c.AllowEmptyBlocks, errors.New("-empty-block-interval requires -allow-empty-blocks"))
+	check(c.MaxBlockTransactionsPerAddress >= 0, errors.New("-max-block-txns-per-address must be >= 0"))
+
+	if c.BlockchainKeystore != "" {
+		check(c.RunBlockPublisher, errors.New("-blockchain-keystore requires -block-publisher"))
+		check(c.blockchainSeckey == (cipher.SecKey{}), errors.New("-blockchain-keystore can't be used with -blockchain-secret-key"))
+		check(c.BlockSignerCommand == "", errors.New("-blockchain-keystore can't be used with -block-signer-command"))
 	}
-	if c.Node.CreateBlockVerifyTxn.MaxDropletPrecision < params.UserVerifyTxn.MaxDropletPrecision {
-		return fmt.Errorf("-max-decimals-create-block must be >= params.UserVerifyTxn.MaxDropletPrecision (%d)", params.UserVerifyTxn.MaxDropletPrecision)
+
+	check(c.maxBlockSize <= math.MaxUint32, errors.New("-max-block-size exceeds MaxUint32"))
+	check(c.maxUnconfirmedTransactionSize <= math.MaxUint32, errors.New("-max-txn-size-unconfirmed exceeds MaxUint32"))
+	check(c.createBlockMaxTransactionSize <= math.MaxUint32, errors.New("-max-txn-size-create-block exceeds MaxUint32"))
+	check(c.unconfirmedBurnFactor <= math.MaxUint32, errors.New("-burn-factor-unconfirmed exceeds MaxUint32"))
+	check(c.createBlockBurnFactor <= math.MaxUint32, errors.New("-burn-factor-create-block exceeds MaxUint32"))
+	check(c.unconfirmedMaxDropletPrecision <= math.MaxUint8, errors.New("-max-decimals-unconfirmed exceeds MaxUint8"))
+	check(c.createBlockMaxDropletPrecision <= math.MaxUint8, errors.New("-max-decimals-create-block exceeds MaxUint8"))
+
+	minTxnSize := uint64(params.MinTransactionSize)
+	userTxnSize := uint64(params.UserVerifyTxn.MaxTransactionSize)
+	check(c.maxUnconfirmedTransactionSize >= minTxnSize, fmt.Errorf("-max-txn-size-unconfirmed must be >= params.MinTransactionSize (%d)", params.MinTransactionSize))
+	check(c.maxUnconfirmedTransactionSize >= userTxnSize, fmt.Errorf("-max-txn-size-unconfirmed must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize))
+	check(c.createBlockMaxTransactionSize >= minTxnSize, fmt.Errorf("-max-txn-size-create-block must be >= params.MinTransactionSize (%d)", params.MinTransactionSize))
+	check(c.createBlockMaxTransactionSize >= userTxnSize, fmt.Errorf("-max-txn-size-create-block must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize))
+
+	check(c.maxBlockSize >= minTxnSize, fmt.Errorf("-max-block-size must be >= params.MinTransactionSize (%d)", params.MinTransactionSize))
+	check(c.maxBlockSize >= userTxnSize, fmt.Errorf("-max-block-size must be >= params.UserVerifyTxn.MaxTransactionSize (%d)", params.UserVerifyTxn.MaxTransactionSize))
+	check(c.maxBlockSize >= c.maxUnconfirmedTransactionSize, errors.New("-max-block-size must be >= -max-txn-size-unconfirmed"))
+	check(c.maxBlockSize >= c.createBlockMaxTransactionSize, errors.New("-max-block-size must be >= -max-txn-size-create-block"))
+
+	check(c.unconfirmedBurnFactor >= uint64(params.MinBurnFactor), fmt.Errorf("-burn-factor-unconfirmed must be >= params.MinBurnFactor (%d)", params.MinBurnFactor))
+	check(c.unconfirmedBurnFactor >= uint64(params.UserVerifyTxn.BurnFactor), fmt.Errorf("-burn-factor-unconfirmed must be >= params.UserVerifyTxn.BurnFactor (%d)", params.UserVerifyTxn.BurnFactor))
+	check(c.createBlockBurnFactor >= uint64(params.MinBurnFactor), fmt.Errorf("-burn-factor-create-block must be >= params.MinBurnFactor (%d)", params.MinBurnFactor))
+	check(c.createBlockBurnFactor >= uint64(params.UserVerifyTxn.BurnFactor), fmt.Errorf("-burn-factor-create-block must be >= params.UserVerifyTxn.BurnFactor (%d)", params.UserVerifyTxn.BurnFactor))
+
+	check(c.unconfirmedMaxDropletPrecision <= droplet.Exponent, fmt.Errorf("-max-decimals-unconfirmed must be <= droplet.Exponent (%d)", droplet.Exponent))
+	check(c.unconfirmedMaxDropletPrecision >= uint64(params.UserVerifyTxn.MaxDropletPrecision), fmt.Errorf("-max-decimals-unconfirmed must be >= params.UserVerifyTxn.MaxDropletPrecision (%d)", params.UserVerifyTxn.MaxDropletPrecision))
+	check(c.createBlockMaxDropletPrecision <= droplet.Exponent, fmt.Errorf("-max-decimals-create-block must be <= droplet.Exponent (%d)", droplet.Exponent))
+	check(c.createBlockMaxDropletPrecision >= uint64(params.UserVerifyTxn.MaxDropletPrecision), fmt.Errorf("-max-decimals-create-block must be >= params.UserVerifyTxn.MaxDropletPrecision (%d)", params.UserVerifyTxn.MaxDropletPrecision))
+
+	if len(errs) != 0 {
+		return errs
 	}
 
 	return nil
@@ -598,7 +798,7 @@ func buildAPISets(c NodeConfig) (map[string]struct{}, error) {
 		api.EndpointsPrometheus,
 		api.EndpointsNetCtrl,
 		api.EndpointsStorage,
-		// Do not include insecure or deprecated API sets, they must always
+		// Do not include insecure, deprecated or block publisher API sets, they must always
 		// be explicitly enabled through -enable-api-sets
 	}
 
@@ -632,7 +832,8 @@ func validateAPISets(opt string, apiSets []string) error {
 			api.EndpointsInsecureWalletSeed,
 			api.EndpointsPrometheus,
 			api.EndpointsNetCtrl,
-			api.EndpointsStorage:
+			api.EndpointsStorage,
+			api.EndpointsBlockPublisher:
 		case "":
 			continue
 		default:
@@ -644,27 +845,36 @@ func validateAPISets(opt string, apiSets []string) error {
 
 // RegisterFlags binds CLI flags to config values
 func (c *NodeConfig) RegisterFlags() {
-	flag.BoolVar(&help, "help", false, "Show help")
-	flag.BoolVar(&c.DisablePEX, "disable-pex", c.DisablePEX, "disable PEX peer discovery")
-	flag.BoolVar(&c.DownloadPeerList, "download-peerlist", c.DownloadPeerList, "download a peers.txt from -peerlist-url")
-	flag.StringVar(&c.PeerListURL, "peerlist-url", c.PeerListURL, "with -download-peerlist=true, download a peers.txt file from this url")
-	flag.BoolVar(&c.DisableOutgoingConnections, "disable-outgoing", c.DisableOutgoingConnections, "Don't make outgoing connections")
-	flag.BoolVar(&c.DisableIncomingConnections, "disable-incoming", c.DisableIncomingConnections, "Don't allow incoming connections")
-	flag.BoolVar(&c.DisableNetworking, "disable-networking", c.DisableNetworking, "Disable all network activity")
-	flag.BoolVar(&c.EnableGUI, "enable-gui", c.EnableGUI, "Enable GUI")
-	flag.BoolVar(&c.DisableCSRF, "disable-csrf", c.DisableCSRF, "disable CSRF check")
-	flag.BoolVar(&c.DisableHeaderCheck, "disable-header-check", c.DisableHeaderCheck, "disables the host, origin and referer header checks.")
-	flag.BoolVar(&c.DisableCSP, "disable-csp", c.DisableCSP, "disable content-security-policy in http response")
-	flag.StringVar(&c.Address, "address", c.Address, "IP Address to run application on. Leave empty to default to a public interface")
-	flag.IntVar(&c.Port, "port", c.Port, "Port to run application on")
-
-	flag.BoolVar(&c.WebInterface, "web-interface", c.WebInterface, "enable the web interface")
-	flag.IntVar(&c.WebInterfacePort, "web-interface-port", c.WebInterfacePort, "port to serve web interface on")
-	flag.StringVar(&c.WebInterfaceAddr, "web-interface-addr", c.WebInterfaceAddr, "addr to serve web interface on")
-	flag.StringVar(&c.WebInterfaceCert, "web-interface-cert", c.WebInterfaceCert, "laqpayd.cert file for web interface HTTPS. If not provided, will autogenerate or use laqpayd.cert in --data-dir")
-	flag.StringVar(&c.WebInterfaceKey, "web-interface-key", c.WebInterfaceKey, "laqpayd.key file for web interface HTTPS. If not provided, will autogenerate or use laqpayd.key in --data-dir")
-	flag.BoolVar(&c.WebInterfaceHTTPS, "web-interface-https", c.WebInterfaceHTTPS, "enable HTTPS for web interface")
-	flag.StringVar(&c.HostWhitelist, "host-whitelist", c.HostWhitelist, "Hostnames to whitelist in the Host header check. Only applies when the web interface is bound to localhost.")
+	c.registerFlags(flag.CommandLine)
+}
+
+// registerFlags binds the flags of fs to config values, with the current values as defaults
+func (c *NodeConfig) registerFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&help, "help", false, "Show help")
+	fs.StringVar(&c.ConfigFile, "config", c.ConfigFile, fmt.Sprintf("TOML or YAML file with option values, keyed by option name, e.g. data-dir = \"/var/lib/laqpay\". Options can also be set by environment variables, e.g. %s. Command line options override environment variables, which override the file", c.envName("data-dir")))
+	fs.BoolVar(&c.DisablePEX, "disable-pex", c.DisablePEX, "disable PEX peer discovery")
+	fs.BoolVar(&c.DownloadPeerList, "download-peerlist", c.DownloadPeerList, "download a peers.txt from -peerlist-url")
+	fs.StringVar(&c.PeerListURL, "peerlist-url", c.PeerListURL, "with -download-peerlist=true, download a peers.txt file from this url")
+	fs.StringVar(&c.Proxy, "proxy", c.Proxy, "dial outgoing connections and download the peers list through this SOCKS5 proxy, e.g. socks5://127.0.0.1:9050 for Tor")
+	fs.BoolVar(&c.DisableDNSSeeds, "disable-dns-seeds", c.DisableDNSSeeds, "disable peer discovery from the DNS seeds")
+	fs.BoolVar(&c.DisableOutgoingConnections, "disable-outgoing", c.DisableOutgoingConnections, "Don't make outgoing connections")
+	fs.BoolVar(&c.DisableIncomingConnections, "disable-incoming", c.DisableIncomingConnections, "Don't allow incoming connections")
+	fs.BoolVar(&c.DisableNetworking, "disable-networking", c.DisableNetworking, "Disable all network activity")
+	fs.StringVar(&c.Network, "network", c.Network, "Network to run on, mainnet or regtest. regtest runs a local chain in the regtest subdirectory of -data-dir, with blocks mined on demand by /api/v2/regtest/mine")
+	fs.BoolVar(&c.EnableGUI, "enable-gui", c.EnableGUI, "Enable GUI")
+	fs.BoolVar(&c.DisableCSRF, "disable-csrf", c.DisableCSRF, "disable CSRF check")
+	fs.BoolVar(&c.DisableHeaderCheck, "disable-header-check", c.DisableHeaderCheck, "disables the host, origin and referer header checks.")
+	fs.BoolVar(&c.DisableCSP, "disable-csp", c.DisableCSP, "disable content-security-policy in http response")
+	fs.StringVar(&c.Address, "address", c.Address, "IP Address to run application on. Leave empty to default to a public interface")
+	fs.IntVar(&c.Port, "port", c.Port, "Port to run application on")
+
+	fs.BoolVar(&c.WebInterface, "web-interface", c.WebInterface, "enable the web interface")
+	fs.IntVar(&c.WebInterfacePort, "web-interface-port", c.WebInterfacePort, "port to serve web interface on")
+	fs.StringVar(&c.WebInterfaceAddr, "web-interface-addr", c.WebInterfaceAddr, "addr to serve web interface on")
+	fs.StringVar(&c.WebInterfaceCert, "web-interface-cert", c.WebInterfaceCert, "laqpayd.cert file for web interface HTTPS. If not provided, will autogenerate or use laqpayd.cert in --data-dir")
+	fs.StringVar(&c.WebInterfaceKey, "web-interface-key", c.WebInterfaceKey, "laqpayd.key file for web interface HTTPS. If not provided, will autogenerate or use laqpayd.key in --data-dir")
+	fs.BoolVar(&c.WebInterfaceHTTPS, "web-interface-https", c.WebInterfaceHTTPS, "enable HTTPS for web interface")
+	fs.StringVar(&c.HostWhitelist, "host-whitelist", c.HostWhitelist, "Hostnames to whitelist in the Host header check. Only applies when the web interface is bound to localhost.")
 
 	allAPISets := []string{
 		api.EndpointsRead,
@@ -675,65 +885,91 @@ func (c *NodeConfig) RegisterFlags() {
 		api.EndpointsNetCtrl,
 		api.EndpointsInsecureWalletSeed,
 		api.EndpointsStorage,
-	}
-	flag.StringVar(&c.EnabledAPISets, "enable-api-sets", c.EnabledAPISets, fmt.Sprintf("enable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
-	flag.StringVar(&c.DisabledAPISets, "disable-api-sets", c.DisabledAPISets, fmt.Sprintf("disable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
-	flag.BoolVar(&c.EnableAllAPISets, "enable-all-api-sets", c.EnableAllAPISets, "enable all API sets, except for deprecated or insecure sets. This option is applied before -disable-api-sets.")
-
-	flag.StringVar(&c.WebInterfaceUsername, "web-interface-username", c.WebInterfaceUsername, "username for the web interface")
-	flag.StringVar(&c.WebInterfacePassword, "web-interface-password", c.WebInterfacePassword, "password for the web interface")
-	flag.BoolVar(&c.WebInterfacePlaintextAuth, "web-interface-plaintext-auth", c.WebInterfacePlaintextAuth, "allow web interface auth without https")
-
-	flag.BoolVar(&c.LaunchBrowser, "launch-browser", c.LaunchBrowser, "launch system default webbrowser at client startup")
-	flag.StringVar(&c.DataDirectory, "data-dir", c.DataDirectory, "directory to store app data (defaults to ~/.laqpay)")
-	flag.StringVar(&c.DBPath, "db-path", c.DBPath, "path of database file (defaults to ~/.laqpay/data.db)")
-	flag.BoolVar(&c.DBReadOnly, "db-read-only", c.DBReadOnly, "open bolt db read-only")
-	flag.BoolVar(&c.ProfileCPU, "profile-cpu", c.ProfileCPU, "enable cpu profiling")
-	flag.StringVar(&c.ProfileCPUFile, "profile-cpu-file", c.ProfileCPUFile, "where to write the cpu profile file")
-	flag.BoolVar(&c.HTTPProf, "http-prof", c.HTTPProf, "run the HTTP profiling interface")
-	flag.StringVar(&c.HTTPProfHost, "http-prof-host", c.HTTPProfHost, "hostname to bind the HTTP profiling interface to")
-	flag.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Choices are: debug, info, warn, error, fatal, panic")
-	flag.BoolVar(&c.ColorLog, "color-log", c.ColorLog, "Add terminal colors to log output")
-	flag.BoolVar(&c.DisablePingPong, "no-ping-log", c.DisablePingPong, `disable "reply to ping" and "received pong" debug log messages`)
-	flag.BoolVar(&c.LogToFile, "logtofile", c.LogToFile, "log to file")
-	flag.StringVar(&c.GUIDirectory, "gui-dir", c.GUIDirectory, "static content directory for the HTML interface")
-
-	flag.BoolVar(&c.VerifyDB, "verify-db", c.VerifyDB, "check the database for corruption")
-	flag.BoolVar(&c.ResetCorruptDB, "reset-corrupt-db", c.ResetCorruptDB, "reset the database if corrupted, and continue running instead of exiting")
-
-	flag.BoolVar(&c.DisableDefaultPeers, "disable-default-peers", c.DisableDefaultPeers, "disable the hardcoded default peers")
-	flag.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the peers.json file in the data directory")
-
-	flag.StringVar(&c.UserAgentRemark, "user-agent-remark", c.UserAgentRemark, "additional remark to include in the user agent sent over the wire protocol")
-
-	flag.Uint64Var(&c.maxUnconfirmedTransactionSize, "max-txn-size-unconfirmed", uint64(c.UnconfirmedVerifyTxn.MaxTransactionSize), "maximum size of an unconfirmed transaction")
-	flag.Uint64Var(&c.unconfirmedBurnFactor, "burn-factor-unconfirmed", uint64(c.UnconfirmedVerifyTxn.BurnFactor), "coinhour burn factor applied to unconfirmed transactions")
-	flag.Uint64Var(&c.unconfirmedMaxDropletPrecision, "max-decimals-unconfirmed", uint64(c.UnconfirmedVerifyTxn.MaxDropletPrecision), "max number of decimal places applied to unconfirmed transactions")
-	flag.Uint64Var(&c.createBlockBurnFactor, "burn-factor-create-block", uint64(c.CreateBlockVerifyTxn.BurnFactor), "coinhour burn factor applied when creating blocks")
-	flag.Uint64Var(&c.createBlockMaxTransactionSize, "max-txn-size-create-block", uint64(c.CreateBlockVerifyTxn.MaxTransactionSize), "maximum size of a transaction applied when creating blocks")
-	flag.Uint64Var(&c.createBlockMaxDropletPrecision, "max-decimals-create-block", uint64(c.CreateBlockVerifyTxn.MaxDropletPrecision), "max number of decimal places applied when creating blocks")
-	flag.Uint64Var(&c.maxBlockSize, "max-block-size", uint64(c.MaxBlockTransactionsSize), "maximum total size of transactions in a block")
-
-	flag.BoolVar(&c.RunBlockPublisher, "block-publisher", c.RunBlockPublisher, "run the daemon as a block publisher")
-	flag.StringVar(&c.BlockchainPubkeyStr, "blockchain-public-key", c.BlockchainPubkeyStr, "public key of the blockchain")
-	flag.StringVar(&c.BlockchainSeckeyStr, "blockchain-secret-key", c.BlockchainSeckeyStr, "secret key of the blockchain")
-
-	flag.StringVar(&c.GenesisAddressStr, "genesis-address", c.GenesisAddressStr, "genesis address")
-	flag.StringVar(&c.GenesisSignatureStr, "genesis-signature", c.GenesisSignatureStr, "genesis block signature")
-	flag.Uint64Var(&c.GenesisTimestamp, "genesis-timestamp", c.GenesisTimestamp, "genesis block timestamp")
-
-	flag.StringVar(&c.WalletDirectory, "wallet-dir", c.WalletDirectory, "location of the wallet files. Defaults to ~/.laqpay/wallet/")
-	flag.StringVar(&c.KVStorageDirectory, "storage-dir", c.KVStorageDirectory, "location of the storage data files. Defaults to ~/.laqpay/data/")
-	flag.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "Maximum number of total connections allowed")
-	flag.IntVar(&c.MaxOutgoingConnections, "max-outgoing-connections", c.MaxOutgoingConnections, "Maximum number of outgoing connections allowed")
-	flag.IntVar(&c.MaxDefaultPeerOutgoingConnections, "max-default-peer-outgoing-connections", c.MaxDefaultPeerOutgoingConnections, "The maximum default peer outgoing connections allowed")
-	flag.IntVar(&c.PeerlistSize, "peerlist-size", c.PeerlistSize, "Max number of peers to track in peerlist")
-	flag.DurationVar(&c.OutgoingConnectionsRate, "connection-rate", c.OutgoingConnectionsRate, "How often to make an outgoing connection")
-	flag.IntVar(&c.MaxOutgoingMessageLength, "max-out-msg-len", c.MaxOutgoingMessageLength, "Maximum length of outgoing wire messages")
-	flag.IntVar(&c.MaxIncomingMessageLength, "max-in-msg-len", c.MaxIncomingMessageLength, "Maximum length of incoming wire messages")
-	flag.BoolVar(&c.LocalhostOnly, "localhost-only", c.LocalhostOnly, "Run on localhost and only connect to localhost peers")
-	flag.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor or scrypt-chacha20poly1305")
-	flag.BoolVar(&c.Version, "version", false, "show node version")
+		api.EndpointsBlockPublisher,
+	}
+	fs.StringVar(&c.EnabledAPISets, "enable-api-sets", c.EnabledAPISets, fmt.Sprintf("enable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
+	fs.StringVar(&c.DisabledAPISets, "disable-api-sets", c.DisabledAPISets, fmt.Sprintf("disable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
+	fs.BoolVar(&c.EnableAllAPISets, "enable-all-api-sets", c.EnableAllAPISets, "enable all API sets, except for deprecated, insecure or block publisher sets. This option is applied before -disable-api-sets.")
+
+	fs.StringVar(&c.WebInterfaceUsername, "web-interface-username", c.WebInterfaceUsername, "username for the web interface")
+	fs.StringVar(&c.WebInterfacePassword, "web-interface-password", c.WebInterfacePassword, "password for the web interface")
+	fs.BoolVar(&c.WebInterfacePlaintextAuth, "web-interface-plaintext-auth", c.WebInterfacePlaintextAuth, "allow web interface auth without https")
+
+	fs.BoolVar(&c.LaunchBrowser, "launch-browser", c.LaunchBrowser, "launch system default webbrowser at client startup")
+	fs.StringVar(&c.DataDirectory, "data-dir", c.DataDirectory, "directory to store app data (defaults to ~/.laqpay)")
+	fs.StringVar(&c.DBPath, "db-path", c.DBPath, "path of database file (defaults to ~/.laqpay/data.db)")
+	fs.BoolVar(&c.DBReadOnly, "db-read-only", c.DBReadOnly, "open bolt db read-only")
+	fs.BoolVar(&c.ProfileCPU, "profile-cpu", c.ProfileCPU, "enable cpu profiling")
+	fs.StringVar(&c.ProfileCPUFile, "profile-cpu-file", c.ProfileCPUFile, "where to write the cpu profile file")
+	fs.BoolVar(&c.HTTPProf, "http-prof", c.HTTPProf, "run the HTTP profiling interface")
+	fs.StringVar(&c.HTTPProfHost, "http-prof-host", c.HTTPProfHost, "hostname to bind the HTTP profiling interface to")
+	fs.StringVar(&c.LogLevel, "log-level", c.LogLevel, "Choices are: debug, info, warn, error, fatal, panic")
+	fs.BoolVar(&c.ColorLog, "color-log", c.ColorLog, "Add terminal colors to log output")
+	fs.BoolVar(&c.DisablePingPong, "no-ping-log", c.DisablePingPong, `disable "reply to ping" and "received pong" debug log messages`)
+	fs.BoolVar(&c.LogToFile, "logtofile", c.LogToFile, "log to file")
+	fs.StringVar(&c.GUIDirectory, "gui-dir", c.GUIDirectory, "static content directory for the HTML interface")
+
+	fs.BoolVar(&c.VerifyDB, "verify-db", c.VerifyDB, "check the database for corruption")
+	fs.BoolVar(&c.ResetCorruptDB, "reset-corrupt-db", c.ResetCorruptDB, "reset the database if corrupted, and continue running instead of exiting")
+	fs.BoolVar(&c.AssumeValid, "assume-valid", c.AssumeValid, "skip the verification of transaction signatures in blocks at or below the latest checkpoint")
+
+	fs.BoolVar(&c.DisableDefaultPeers, "disable-default-peers", c.DisableDefaultPeers, "disable the hardcoded default peers")
+	fs.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the peers.json file in the data directory")
+
+	fs.StringVar(&c.UserAgentRemark, "user-agent-remark", c.UserAgentRemark, "additional remark to include in the user agent sent over the wire protocol")
+
+	fs.Uint64Var(&c.maxUnconfirmedTransactionSize, "max-txn-size-unconfirmed", uint64(c.UnconfirmedVerifyTxn.MaxTransactionSize), "maximum size of an unconfirmed transaction")
+	fs.Uint64Var(&c.unconfirmedBurnFactor, "burn-factor-unconfirmed", uint64(c.UnconfirmedVerifyTxn.BurnFactor), "coinhour burn factor applied to unconfirmed transactions")
+	fs.Uint64Var(&c.unconfirmedMaxDropletPrecision, "max-decimals-unconfirmed", uint64(c.UnconfirmedVerifyTxn.MaxDropletPrecision), "max number of decimal places applied to unconfirmed transactions")
+	fs.Uint64Var(&c.createBlockBurnFactor, "burn-factor-create-block", uint64(c.CreateBlockVerifyTxn.BurnFactor), "coinhour burn factor applied when creating blocks")
+	fs.Uint64Var(&c.createBlockMaxTransactionSize, "max-txn-size-create-block", uint64(c.CreateBlockVerifyTxn.MaxTransactionSize), "maximum size of a transaction applied when creating blocks")
+	fs.Uint64Var(&c.createBlockMaxDropletPrecision, "max-decimals-create-block", uint64(c.CreateBlockVerifyTxn.MaxDropletPrecision), "max number of decimal places applied when creating blocks")
+	fs.Uint64Var(&c.maxBlockSize, "max-block-size", uint64(c.MaxBlockTransactionsSize), "maximum total size of transactions in a block")
+	fs.IntVar(&c.MaxBlockTransactionsPerAddress, "max-block-txns-per-address", c.MaxBlockTransactionsPerAddress, "maximum number of transactions spending from the same address in a block when creating blocks. 0 is unlimited")
+	fs.BoolVar(&c.AllowEmptyBlocks, "allow-empty-blocks", c.AllowEmptyBlocks, "accept blocks with no transactions. All nodes of the network must set it")
+
+	fs.BoolVar(&c.RunBlockPublisher, "block-publisher", c.RunBlockPublisher, "run the daemon as a block publisher")
+	fs.StringVar(&c.BlockchainPubkeyStr, "blockchain-public-key", c.BlockchainPubkeyStr, "public key of the blockchain")
+	fs.StringVar(&c.BlockchainSeckeyStr, "blockchain-secret-key", c.BlockchainSeckeyStr, "secret key of the blockchain")
+	fs.StringVar(&c.BlockPublisherPubkeysStr, "block-publisher-public-keys", c.BlockPublisherPubkeysStr, "comma-separated public keys of other block publishers. Blocks signed by them are accepted, and the block publishers agree on each block")
+	fs.DurationVar(&c.ConsensusWait, "consensus-wait", c.ConsensusWait, "How long to wait for the block publishers to agree on the next block, before sending the candidates and signatures again and requesting the blocks from peers")
+	fs.BoolVar(&c.PublisherStandby, "block-publisher-standby", c.PublisherStandby, "run the block publisher as a hot standby of another block publisher with the same key. Blocks are created only after the lease of the active block publisher expires")
+	fs.StringVar(&c.BlockSignerCommand, "block-signer-command", c.BlockSignerCommand, "command that signs the blocks created by the block publisher, in place of -blockchain-secret-key, e.g. over ssh to a signing host. It reads a JSON sign request on stdin and prints the hex-encoded signature")
+	fs.StringVar(&c.BlockSignerPubkeyStr, "block-signer-public-key", c.BlockSignerPubkeyStr, "public key that -block-signer-command signs with. Defaults to -blockchain-public-key")
+	fs.DurationVar(&c.BlockSignerTimeout, "block-signer-timeout", c.BlockSignerTimeout, "how long -block-signer-command may take to sign a block")
+	fs.StringVar(&c.BlockchainKeystore, "blockchain-keystore", c.BlockchainKeystore, "keystore file with the encrypted blockchain secret key, in place of -blockchain-secret-key. The password is read from -blockchain-keystore-password-fd, the "+blockchainKeystorePasswordEnv+" environment variable or a terminal prompt")
+	fs.IntVar(&c.BlockchainKeystorePasswordFd, "blockchain-keystore-password-fd", c.BlockchainKeystorePasswordFd, "file descriptor to read the -blockchain-keystore password from")
+	fs.Uint64Var(&c.BlockCreationInterval, "block-creation-interval", c.BlockCreationInterval, "how often the block publisher decides whether to create a block, in seconds")
+	fs.IntVar(&c.BlockMinTransactions, "block-min-txns", c.BlockMinTransactions, "minimum number of valid unconfirmed transactions to create a block")
+	fs.Uint64Var(&c.BlockMinFee, "block-min-fee", c.BlockMinFee, "minimum total fee of the valid unconfirmed transactions to create a block, in coin hours")
+	fs.Uint64Var(&c.MaxBlockAge, "max-block-age", c.MaxBlockAge, "how old the head block may get before a block is created even if -block-min-txns or -block-min-fee are not reached, in seconds. 0 waits for them indefinitely")
+	fs.Uint64Var(&c.EmptyBlockInterval, "empty-block-interval", c.EmptyBlockInterval, "how old the head block may get before an empty block is created if there are no valid unconfirmed transactions, in seconds. 0 disables empty blocks. Requires -allow-empty-blocks")
+	fs.Uint64Var(&c.PublisherLeaseIntervals, "block-publisher-lease-intervals", c.PublisherLeaseIntervals, "how many block creation intervals unconfirmed transactions may wait without a new block before the lease of the active block publisher expires")
+
+	fs.StringVar(&c.GenesisAddressStr, "genesis-address", c.GenesisAddressStr, "genesis address")
+	fs.StringVar(&c.GenesisSignatureStr, "genesis-signature", c.GenesisSignatureStr, "genesis block signature")
+	fs.Uint64Var(&c.GenesisTimestamp, "genesis-timestamp", c.GenesisTimestamp, "genesis block timestamp")
+
+	fs.StringVar(&c.WalletDirectory, "wallet-dir", c.WalletDirectory, "location of the wallet files. Defaults to ~/.laqpay/wallet/")
+	fs.StringVar(&c.KVStorageDirectory, "storage-dir", c.KVStorageDirectory, "location of the storage data files. Defaults to ~/.laqpay/data/")
+	fs.IntVar(&c.MaxConnections, "max-connections", c.MaxConnections, "Maximum number of total connections allowed")
+	fs.IntVar(&c.MaxOutgoingConnections, "max-outgoing-connections", c.MaxOutgoingConnections, "Maximum number of outgoing connections allowed")
+	fs.IntVar(&c.MaxDefaultPeerOutgoingConnections, "max-default-peer-outgoing-connections", c.MaxDefaultPeerOutgoingConnections, "The maximum default peer outgoing connections allowed")
+	fs.IntVar(&c.PeerlistSize, "peerlist-size", c.PeerlistSize, "Max number of peers to track in peerlist")
+	fs.DurationVar(&c.OutgoingConnectionsRate, "connection-rate", c.OutgoingConnectionsRate, "How often to make an outgoing connection")
+	fs.IntVar(&c.MaxOutgoingMessageLength, "max-out-msg-len", c.MaxOutgoingMessageLength, "Maximum length of outgoing wire messages")
+	fs.IntVar(&c.MaxIncomingMessageLength, "max-in-msg-len", c.MaxIncomingMessageLength, "Maximum length of incoming wire messages")
+	fs.IntVar(&c.MaxUploadRate, "max-upload-rate", c.MaxUploadRate, "Maximum upload rate of all wire connections combined, in bytes per second. 0 is unlimited")
+	fs.IntVar(&c.MaxDownloadRate, "max-download-rate", c.MaxDownloadRate, "Maximum download rate of all wire connections combined, in bytes per second. 0 is unlimited")
+	fs.IntVar(&c.MaxConnectionUploadRate, "max-connection-upload-rate", c.MaxConnectionUploadRate, "Maximum upload rate of each wire connection, in bytes per second. 0 is unlimited")
+	fs.IntVar(&c.MaxConnectionDownloadRate, "max-connection-download-rate", c.MaxConnectionDownloadRate, "Maximum download rate of each wire connection, in bytes per second. 0 is unlimited")
+	fs.IntVar(&c.CompressionThreshold, "compression-threshold", c.CompressionThreshold, "Compress wire messages longer than this many bytes, for peers that support compression. 0 disables compression")
+	fs.BoolVar(&c.Dandelion, "dandelion", c.Dandelion, "Relay transactions created by this node through a random path of peers before broadcasting them, to hide their origin")
+	fs.DurationVar(&c.DandelionEmbargo, "dandelion-embargo", c.DandelionEmbargo, "How long to wait for a transaction relayed by -dandelion to be broadcast by another node, before broadcasting it")
+	fs.DurationVar(&c.SyncStallTimeout, "sync-stall-timeout", c.SyncStallTimeout, "Disconnect peers that leave a block request unanswered for this long, and report the sync as stalled if the head block does not advance for this long. 0 disables it")
+	fs.BoolVar(&c.LocalhostOnly, "localhost-only", c.LocalhostOnly, "Run on localhost and only connect to localhost peers")
+	fs.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor or scrypt-chacha20poly1305")
+	fs.BoolVar(&c.Version, "version", false, "show node version")
 }
 
 func (c *NodeConfig) applyConfigMode(configMode string) {
@@ -831,7 +1067,7 @@ func (c *Coin) Run() error {
 	if c.config.Node.WebInterfaceHTTPS {
 		scheme = "https"
 	}
-	host := fmt.Sprintf("%s:%d", c.config.Node.WebInterfaceAddr, c.config.Node.WebInterfacePort)
+	host := iputil.JoinAddr(c.config.Node.WebInterfaceAddr, uint16(c.config.Node.WebInterfacePort))
 
 	if c.config.Node.ProfileCPU {
 		f, err := os.Create(c.config.Node.ProfileCPUFile)
@@ -919,7 +1155,7 @@ func (c *Coin) Run() error {
 		if c.config.Node.ResetCorruptDB {
 			// Check the database integrity and recreate it if necessary
 			c.logger.Info("Checking database and resetting if corrupted")
-			if newDB, err := visor.ResetCorruptDB(db, c.config.Node.blockchainPubkey, quit); err != nil {
+			if newDB, err := visor.ResetCorruptDB(db, c.config.Node.blockchainPubkey, c.config.Node.blockPublisherPubkeys, params.MainNetPublisherKeyChanges, quit); err != nil {
 				if err != visor.ErrVerifyStopped {
 					c.logger.WithError(err).Error("visor.ResetCorruptDB failed")
 					retErr = err
@@ -930,7 +1166,7 @@ func (c *Coin) Run() error {
 			}
 		} else {
 			c.logger.Info("Checking database")
-			if err := visor.CheckDatabase(db, c.config.Node.blockchainPubkey, quit); err != nil {
+			if err := visor.CheckDatabase(db, c.config.Node.blockchainPubkey, c.config.Node.blockPublisherPubkeys, params.MainNetPublisherKeyChanges, quit); err != nil {
 				if err != visor.ErrVerifyStopped {
 					c.logger.WithError(err).Error("visor.CheckDatabase failed")
 					retErr = err
@@ -1007,6 +1243,15 @@ func (c *Coin) Run() error {
 		goto earlyShutdown
 	}
 
+	if rc := c.config.Node.regtest; rc != nil {
+		c.logger.Infof("Running a regtest chain, its parameters and the distribution wallet seed are in %s", rc.filename)
+		if err := rc.distributeGenesis(d, v); err != nil {
+			c.logger.WithError(err).Error("Regtest genesis distribution failed")
+			retErr = err
+			goto earlyShutdown
+		}
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -1124,16 +1369,27 @@ func (c *Coin) ConfigureVisor() visor.Config {
 	vc := visor.NewConfig()
 
 	vc.Distribution = params.MainNetDistribution
+	if c.config.Node.regtest != nil {
+		vc.Distribution = c.config.Node.regtest.distribution()
+	}
+
+	vc.Checkpoints = params.MainNetCheckpoints
+	vc.PublisherKeyChanges = params.MainNetPublisherKeyChanges
+	vc.AssumeValid = c.config.Node.AssumeValid
 
 	vc.IsBlockPublisher = c.config.Node.RunBlockPublisher
 	vc.Arbitrating = c.config.Node.RunBlockPublisher
 
 	vc.BlockchainPubkey = c.config.Node.blockchainPubkey
+	vc.BlockPublisherPubkeys = c.config.Node.blockPublisherPubkeys
 	vc.BlockchainSeckey = c.config.Node.blockchainSeckey
+	vc.BlockSigner = c.config.Node.blockSigner
 
 	vc.UnconfirmedVerifyTxn = c.config.Node.UnconfirmedVerifyTxn
 	vc.CreateBlockVerifyTxn = c.config.Node.CreateBlockVerifyTxn
 	vc.MaxBlockTransactionsSize = c.config.Node.MaxBlockTransactionsSize
+	vc.MaxBlockTransactionsPerAddress = c.config.Node.MaxBlockTransactionsPerAddress
+	vc.AllowEmptyBlocks = c.config.Node.AllowEmptyBlocks
 
 	vc.GenesisAddress = c.config.Node.genesisAddress
 	vc.GenesisSignature = c.config.Node.genesisSignature
@@ -1184,6 +1440,12 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Pool.MaxDefaultPeerOutgoingConnections = c.config.Node.MaxDefaultPeerOutgoingConnections
 	dc.Pool.MaxIncomingMessageLength = c.config.Node.MaxIncomingMessageLength
 	dc.Pool.MaxOutgoingMessageLength = c.config.Node.MaxOutgoingMessageLength
+	dc.Pool.MaxUploadRate = c.config.Node.MaxUploadRate
+	dc.Pool.MaxDownloadRate = c.config.Node.MaxDownloadRate
+	dc.Pool.MaxConnectionUploadRate = c.config.Node.MaxConnectionUploadRate
+	dc.Pool.MaxConnectionDownloadRate = c.config.Node.MaxConnectionDownloadRate
+	dc.Pool.CompressionThreshold = c.config.Node.CompressionThreshold
+	dc.Pool.Proxy = c.config.Node.Proxy
 
 	dc.Pex.DataDirectory = c.config.Node.DataDirectory
 	dc.Pex.Disabled = c.config.Node.DisablePEX
@@ -1191,6 +1453,9 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Pex.Max = c.config.Node.PeerlistSize
 	dc.Pex.DownloadPeerList = c.config.Node.DownloadPeerList
 	dc.Pex.PeerListURL = c.config.Node.PeerListURL
+	dc.Pex.Proxy = c.config.Node.Proxy
+	dc.Pex.DNSSeeds = c.config.Node.DNSSeeds
+	dc.Pex.DNSSeedPort = c.config.Node.dnsSeedPort
 	dc.Pex.DisableTrustedPeers = c.config.Node.DisableDefaultPeers
 	dc.Pex.CustomPeersFile = c.config.Node.CustomPeersFile
 	dc.Pex.DefaultConnections = c.config.Node.DefaultConnections
@@ -1202,6 +1467,7 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Daemon.DisableOutgoingConnections = c.config.Node.DisableOutgoingConnections
 	dc.Daemon.DisableIncomingConnections = c.config.Node.DisableIncomingConnections
 	dc.Daemon.DisableNetworking = c.config.Node.DisableNetworking
+	dc.Daemon.Regtest = c.config.Node.regtest != nil
 	dc.Daemon.Port = c.config.Node.Port
 	dc.Daemon.Address = c.config.Node.Address
 	dc.Daemon.LocalhostOnly = c.config.Node.LocalhostOnly
@@ -1213,6 +1479,17 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Daemon.GenesisHash = c.config.Node.genesisHash
 	dc.Daemon.UserAgent = c.config.Node.userAgent
 	dc.Daemon.UnconfirmedVerifyTxn = c.config.Node.UnconfirmedVerifyTxn
+	dc.Daemon.Dandelion = c.config.Node.Dandelion
+	dc.Daemon.DandelionEmbargo = c.config.Node.DandelionEmbargo
+	dc.Daemon.SyncStallTimeout = c.config.Node.SyncStallTimeout
+	dc.Daemon.ConsensusWait = c.config.Node.ConsensusWait
+	dc.Daemon.PublisherStandby = c.config.Node.PublisherStandby
+	dc.Daemon.PublisherLeaseIntervals = c.config.Node.PublisherLeaseIntervals
+	dc.Daemon.BlockCreationInterval = c.config.Node.BlockCreationInterval
+	dc.Daemon.BlockMinTransactions = c.config.Node.BlockMinTransactions
+	dc.Daemon.BlockMinFee = c.config.Node.BlockMinFee
+	dc.Daemon.MaxBlockAge = c.config.Node.MaxBlockAge
+	dc.Daemon.EmptyBlockInterval = c.config.Node.EmptyBlockInterval
 
 	if c.config.Node.OutgoingConnectionsRate == 0 {
 		c.config.Node.OutgoingConnectionsRate = time.Millisecond
@@ -1244,8 +1521,9 @@ func (c *Coin) createGUI(gw *api.Gateway, host string) (*api.Server, error) {
 			DaemonUserAgent: c.config.Node.userAgent,
 			BlockPublisher:  c.config.Node.RunBlockPublisher,
 		},
-		Username: c.config.Node.WebInterfaceUsername,
-		Password: c.config.Node.WebInterfacePassword,
+		Username:        c.config.Node.WebInterfaceUsername,
+		Password:        c.config.Node.WebInterfacePassword,
+		EffectiveConfig: c.config.Node.effectiveConfig,
 	}
 
 	var s *api.Server
@@ -0,0 +1,212 @@
+package laqpay
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"../../src/readable"
+	"../../src/util/file"
+)
+
+// An option is set, in order of precedence, by its command line flag, by its environment variable
+// or by the config file given with -config. Otherwise it keeps its default value.
+// The environment variable of an option is its name upper-cased, with "-" replaced by "_",
+// prefixed with the upper-cased coin name, e.g. LAQPAY_DATA_DIR for -data-dir.
+// The config file keys are the option names, e.g. data-dir = "/var/lib/laqpay" in TOML.
+
+const (
+	// ConfigSourceFlag is the source of an option set on the command line
+	ConfigSourceFlag = "flag"
+	// ConfigSourceEnv is the source of an option set by an environment variable
+	ConfigSourceEnv = "env"
+	// ConfigSourceFile is the source of an option set by the -config file
+	ConfigSourceFile = "file"
+	// ConfigSourceDefault is the source of an option that was not set
+	ConfigSourceDefault = "default"
+	// ConfigSourceDerived is the source of an option whose value the node changed because of other options,
+	// e.g. the data directory of -network=regtest, or -enable-gui when the WALLET API set is disabled
+	ConfigSourceDerived = "derived"
+
+	// redactedConfigValue replaces the values of secretOptions in the effective config
+	redactedConfigValue = "[redacted]"
+)
+
+// secretOptions are the options whose values are not shown in the effective config
+var secretOptions = map[string]struct{}{
+	"blockchain-secret-key":  {},
+	"web-interface-password": {},
+}
+
+// ApplyConfigSources sets the options that were not set on the command line from their
+// environment variables, then from the -config file. It must be called after flag.Parse.
+// Invalid values and unknown config file keys are reported by Coin.ParseConfig, with the other config errors.
+// Options that were not set by any source are reported as defaults in the effective config.
+// An error is returned if the config file can't be read.
+func (c *NodeConfig) ApplyConfigSources() error {
+	sources := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		sources[f.Name] = ConfigSourceFlag
+	})
+
+	var errs ConfigErrors
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := sources[f.Name]; ok {
+			return
+		}
+
+		name := c.envName(f.Name)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+
+		if err := setFlag(f, v); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value %q for environment variable %s: %v", v, name, err))
+			return
+		}
+
+		sources[f.Name] = ConfigSourceEnv
+	})
+
+	if c.ConfigFile != "" {
+		fn := replaceHome(c.ConfigFile, file.UserHome())
+		values, err := loadConfigFile(fn)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if k == "config" || flag.Lookup(k) == nil {
+				errs = append(errs, fmt.Errorf("%s: unknown option %q", fn, k))
+				continue
+			}
+
+			if _, ok := sources[k]; ok {
+				continue
+			}
+
+			if err := setFlag(flag.Lookup(k), values[k]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q for %s: %v", fn, values[k], k, err))
+				continue
+			}
+
+			sources[k] = ConfigSourceFile
+		}
+	}
+
+	c.configErrors = errs
+	c.configSources = sources
+
+	return nil
+}
+
+// flagValues returns the values of all options
+func flagValues() map[string]string {
+	values := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+// buildEffectiveConfig records the final option values, after postProcess, with where they were set.
+// prev are the option values of the command line flags before postProcess. A value changed by postProcess
+// is derived, unless the change only resolves $HOME. Secrets are cleared by postProcess once parsed,
+// so they are reported as set if they were set before it
+func (c *NodeConfig) buildEffectiveConfig(prev map[string]string) {
+	home := file.UserHome()
+
+	// The command line flags are bound to the NodeConfig that the flags were parsed into,
+	// which is copied before postProcess. Bind a new flag set to c to read its final values
+	final := flag.NewFlagSet(c.CoinName, flag.ContinueOnError)
+	c.registerFlags(final)
+
+	c.effectiveConfig = nil
+	final.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+
+		source, ok := c.configSources[f.Name]
+		if !ok {
+			source = ConfigSourceDefault
+		}
+
+		if _, ok := secretOptions[f.Name]; ok {
+			if value != "" || prev[f.Name] != "" {
+				value = redactedConfigValue
+			}
+		} else if p, ok := prev[f.Name]; ok && value != p && value != replaceHome(p, home) {
+			source = ConfigSourceDerived
+		}
+
+		c.effectiveConfig = append(c.effectiveConfig, readable.ConfigOption{
+			Name:   f.Name,
+			Value:  value,
+			Source: source,
+		})
+	})
+}
+
+// setFlag sets the value of a flag. The value is left unchanged if it is invalid,
+// as the flag package zeroes some values on parse errors
+func setFlag(f *flag.Flag, value string) error {
+	prev := f.Value.String()
+	if err := f.Value.Set(value); err != nil {
+		f.Value.Set(prev) // nolint: errcheck
+		return err
+	}
+
+	return nil
+}
+
+// envName returns the name of the environment variable of an option
+func (c *NodeConfig) envName(option string) string {
+	return strings.ToUpper(c.CoinName + "_" + strings.Replace(option, "-", "_", -1))
+}
+
+// loadConfigFile reads the option values of a TOML or YAML config file.
+// Lists are joined with commas, e.g. for -enable-api-sets
+func loadConfigFile(fn string) (map[string]string, error) {
+	v := viper.New()
+	switch ext := strings.ToLower(filepath.Ext(fn)); ext {
+	case ".toml":
+		v.SetConfigType("toml")
+	case ".yaml", ".yml":
+		v.SetConfigType("yaml")
+	default:
+		return nil, fmt.Errorf("-config %s must be a .toml, .yaml or .yml file", fn)
+	}
+
+	v.SetConfigFile(fn)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read -config %s failed: %v", fn, err)
+	}
+
+	values := make(map[string]string)
+	for _, k := range v.AllKeys() {
+		switch x := v.Get(k).(type) {
+		case []interface{}:
+			items := make([]string, len(x))
+			for i, item := range x {
+				items[i] = fmt.Sprint(item)
+			}
+			values[k] = strings.Join(items, ",")
+		default:
+			values[k] = fmt.Sprint(x)
+		}
+	}
+
+	return values, nil
+}
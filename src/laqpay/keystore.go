@@ -0,0 +1,87 @@
+package laqpay
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/crypto/ssh/terminal"
+
+	"../../src/cipher"
+	"../../src/wallet"
+)
+
+// blockchainKeystorePasswordEnv is the environment variable that the blockchain keystore password
+// is read from, if no password file descriptor is set
+const blockchainKeystorePasswordEnv = "BLOCKCHAIN_KEYSTORE_PASSWORD"
+
+// unlockBlockchainKeystore loads the keystore file and decrypts the blockchain secret key.
+// The password is read from the file descriptor passwordFd if it is >= 0,
+// otherwise from the BLOCKCHAIN_KEYSTORE_PASSWORD environment variable if it is set,
+// otherwise it is prompted for if stdin is a terminal
+func unlockBlockchainKeystore(filename string, passwordFd int) (cipher.SecKey, error) {
+	k, err := wallet.LoadKeystore(filename)
+	if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	password, err := readBlockchainKeystorePassword(filename, passwordFd)
+	if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	defer func() {
+		// Wipes the password
+		for i := range password {
+			password[i] = 0
+		}
+	}()
+
+	seckey, err := k.Unlock(password)
+	if err != nil {
+		return cipher.SecKey{}, fmt.Errorf("unlock -blockchain-keystore %s failed: %v", filename, err)
+	}
+
+	return seckey, nil
+}
+
+func readBlockchainKeystorePassword(filename string, passwordFd int) ([]byte, error) {
+	if passwordFd >= 0 {
+		f := os.NewFile(uintptr(passwordFd), "blockchain-keystore-password")
+		if f == nil {
+			return nil, fmt.Errorf("invalid -blockchain-keystore-password-fd %d", passwordFd)
+		}
+		defer f.Close()
+
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("read -blockchain-keystore-password-fd %d failed: %v", passwordFd, err)
+		}
+
+		return []byte(strings.TrimRight(line, "\r\n")), nil
+	}
+
+	if p, ok := os.LookupEnv(blockchainKeystorePasswordEnv); ok {
+		// Child processes, e.g. the block signer command, must not inherit the password
+		if err := os.Unsetenv(blockchainKeystorePasswordEnv); err != nil {
+			return nil, err
+		}
+		return []byte(p), nil
+	}
+
+	if !terminal.IsTerminal(int(syscall.Stdin)) { //nolint:unconvert
+		return nil, errors.New("-blockchain-keystore password must be given with -blockchain-keystore-password-fd or " + blockchainKeystorePasswordEnv + " if stdin is not a terminal")
+	}
+
+	fmt.Fprintf(os.Stderr, "enter password for %s:", filename)
+	password, err := terminal.ReadPassword(int(syscall.Stdin)) //nolint:unconvert
+	fmt.Fprintln(os.Stderr, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return password, nil
+}
@@ -0,0 +1,217 @@
+package laqpay
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"../../src/cipher"
+	"../../src/cipher/bip39"
+	"../../src/coin"
+	"../../src/daemon"
+	"../../src/params"
+	"../../src/util/droplet"
+	"../../src/util/file"
+	"../../src/visor"
+)
+
+const (
+	// NetworkMainnet runs the node on the main network
+	NetworkMainnet = "mainnet"
+	// NetworkRegtest runs the node on a local regtest chain, see applyRegtest
+	NetworkRegtest = "regtest"
+
+	// regtestChainFile is the file in the regtest data directory that holds the regtest chain parameters
+	regtestChainFile = "regtest.json"
+	// regtestDistributionAddresses is the number of distribution addresses of a regtest chain
+	regtestDistributionAddresses = 10
+)
+
+// regtestChain are the parameters of a regtest chain. They are generated the first time
+// the regtest data directory is used, and kept next to the blockchain database
+type regtestChain struct {
+	GenesisSeckey    string `json:"genesis_secret_key"`
+	BlockchainSeckey string `json:"blockchain_secret_key"`
+	GenesisTimestamp uint64 `json:"genesis_timestamp"`
+	// DistributionSeed is the seed of a deterministic wallet whose first addresses are the distribution addresses
+	DistributionSeed      string   `json:"distribution_seed"`
+	DistributionAddresses []string `json:"distribution_addresses"`
+
+	filename         string
+	genesisSeckey    cipher.SecKey
+	blockchainSeckey cipher.SecKey
+}
+
+// newRegtestChain generates the keys and distribution addresses of a new regtest chain
+func newRegtestChain() (*regtestChain, error) {
+	_, genesisSeckey := cipher.GenerateKeyPair()
+	_, blockchainSeckey := cipher.GenerateKeyPair()
+
+	seed, err := bip39.NewDefaultMnemonic()
+	if err != nil {
+		return nil, err
+	}
+
+	seckeys, err := cipher.GenerateDeterministicKeyPairs([]byte(seed), regtestDistributionAddresses)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, len(seckeys))
+	for i, sk := range seckeys {
+		addrs[i] = cipher.MustAddressFromSecKey(sk).String()
+	}
+
+	return &regtestChain{
+		GenesisSeckey:         genesisSeckey.Hex(),
+		BlockchainSeckey:      blockchainSeckey.Hex(),
+		GenesisTimestamp:      uint64(time.Now().UTC().Unix()),
+		DistributionSeed:      seed,
+		DistributionAddresses: addrs,
+		genesisSeckey:         genesisSeckey,
+		blockchainSeckey:      blockchainSeckey,
+	}, nil
+}
+
+// loadRegtestChain loads the regtest chain parameters from the data directory,
+// or generates and saves them if the directory has none
+func loadRegtestChain(dir string) (*regtestChain, error) {
+	fn := filepath.Join(dir, regtestChainFile)
+
+	exists, err := file.Exists(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		c, err := newRegtestChain()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := file.SaveJSON(fn, c, 0600); err != nil {
+			return nil, err
+		}
+		c.filename = fn
+
+		return c, nil
+	}
+
+	var c regtestChain
+	if err := file.LoadJSON(fn, &c); err != nil {
+		return nil, fmt.Errorf("load %s failed: %v", fn, err)
+	}
+
+	c.genesisSeckey, err = cipher.SecKeyFromHex(c.GenesisSeckey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid genesis_secret_key: %v", fn, err)
+	}
+
+	c.blockchainSeckey, err = cipher.SecKeyFromHex(c.BlockchainSeckey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid blockchain_secret_key: %v", fn, err)
+	}
+
+	c.filename = fn
+
+	return &c, nil
+}
+
+// distribution returns the distribution parameters of the regtest chain. All addresses are unlocked
+func (c *regtestChain) distribution() params.Distribution {
+	return params.Distribution{
+		MaxCoinSupply:        params.MainNetDistribution.MaxCoinSupply,
+		InitialUnlockedCount: uint64(len(c.DistributionAddresses)),
+		Addresses:            c.DistributionAddresses,
+	}
+}
+
+// applyRegtest replaces the blockchain parameters with those of the regtest chain in the
+// "regtest" subdirectory of the data directory, generating a new chain if there is none.
+// The node runs as the only block publisher with networking disabled, and blocks are
+// created on demand through the REGTEST API set.
+// Options that conflict with regtest are reported by validate, with the other config errors
+func (c *Config) applyRegtest() error {
+	if c.Node.PublisherStandby {
+		c.Node.configErrors = append(c.Node.configErrors, errors.New("-network=regtest can't be used with -block-publisher-standby"))
+	}
+	if c.Node.BlockSignerCommand != "" {
+		c.Node.configErrors = append(c.Node.configErrors, errors.New("-network=regtest can't be used with -block-signer-command"))
+	}
+	if c.Node.BlockchainKeystore != "" {
+		c.Node.configErrors = append(c.Node.configErrors, errors.New("-network=regtest can't be used with -blockchain-keystore"))
+	}
+
+	dir, err := file.InitDataDir(filepath.Join(replaceHome(c.Node.DataDirectory, file.UserHome()), NetworkRegtest))
+	if err != nil {
+		return err
+	}
+	c.Node.DataDirectory = dir
+
+	chain, err := loadRegtestChain(dir)
+	if err != nil {
+		return err
+	}
+
+	dist := chain.distribution()
+	if err := dist.Validate(); err != nil {
+		return fmt.Errorf("invalid regtest distribution: %v", err)
+	}
+
+	genesisAddress := cipher.MustAddressFromSecKey(chain.genesisSeckey)
+	coinVolume := dist.MaxCoinSupply * droplet.Multiplier
+
+	gb, err := coin.NewGenesisBlock(genesisAddress, coinVolume, chain.GenesisTimestamp)
+	if err != nil {
+		return err
+	}
+
+	c.Node.GenesisAddressStr = genesisAddress.String()
+	c.Node.GenesisSignatureStr = cipher.MustSignHash(gb.HashHeader(), chain.blockchainSeckey).Hex()
+	c.Node.GenesisTimestamp = chain.GenesisTimestamp
+	c.Node.GenesisCoinVolume = coinVolume
+	c.Node.BlockchainPubkeyStr = cipher.MustPubKeyFromSecKey(chain.blockchainSeckey).Hex()
+	c.Node.BlockchainSeckeyStr = chain.BlockchainSeckey
+	c.Node.BlockPublisherPubkeysStr = ""
+
+	c.Node.RunBlockPublisher = true
+	c.Node.DisableNetworking = true
+	c.Node.AllowEmptyBlocks = true
+	c.Node.DefaultConnections = nil
+	c.Node.DNSSeeds = nil
+
+	c.Node.regtest = chain
+
+	return nil
+}
+
+// distributeGenesis splits the genesis coins into the distribution addresses in block 1,
+// if the regtest chain only has the genesis block
+func (c *regtestChain) distributeGenesis(d *daemon.Daemon, v *visor.Visor) error {
+	headSeq, ok, err := v.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+	if !ok || headSeq != 0 {
+		return nil
+	}
+
+	gb, err := v.GetSignedBlockBySeq(0)
+	if err != nil {
+		return err
+	}
+	if gb == nil {
+		return errors.New("genesis block not found")
+	}
+
+	ux := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	txn := InitTransaction(ux[0].Hash().Hex(), c.genesisSeckey, c.distribution())
+
+	if err := d.InjectTransaction(txn); err != nil {
+		return err
+	}
+
+	_, err = d.MineBlocks(1, 0)
+	return err
+}
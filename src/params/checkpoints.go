@@ -0,0 +1,64 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+
+	"../../src/cipher"
+)
+
+// Checkpoint commits to the hash of the block at a given sequence.
+// A chain that has a different block at the checkpoint's sequence is rejected
+type Checkpoint struct {
+	// Seq is the sequence of the block
+	Seq uint64
+	// Hash is the hex-encoded header hash of the block
+	Hash string
+}
+
+// Checkpoints are block checkpoints, sorted by ascending sequence.
+// The signatures of the transactions in blocks at or below the latest checkpoint
+// are assumed to be valid when syncing the blockchain
+type Checkpoints []Checkpoint
+
+// MustValidate validates Checkpoints, panics on error
+func (c Checkpoints) MustValidate() {
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Validate validates Checkpoints
+func (c Checkpoints) Validate() error {
+	for i, cp := range c {
+		if _, err := cipher.SHA256FromHex(cp.Hash); err != nil {
+			return fmt.Errorf("invalid hash for checkpoint %d: %v", cp.Seq, err)
+		}
+
+		if i > 0 && cp.Seq <= c[i-1].Seq {
+			return errors.New("checkpoints must be sorted by ascending seq, without duplicates")
+		}
+	}
+
+	return nil
+}
+
+// Hash returns the block hash of the checkpoint at seq, if there is one
+func (c Checkpoints) Hash(seq uint64) (cipher.SHA256, bool) {
+	for _, cp := range c {
+		if cp.Seq == seq {
+			return cipher.MustSHA256FromHex(cp.Hash), true
+		}
+	}
+
+	return cipher.SHA256{}, false
+}
+
+// LatestSeq returns the sequence of the latest checkpoint, if there is one
+func (c Checkpoints) LatestSeq() (uint64, bool) {
+	if len(c) == 0 {
+		return 0, false
+	}
+
+	return c[len(c)-1].Seq, true
+}
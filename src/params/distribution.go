@@ -18,6 +18,10 @@ type Distribution struct {
 	// Once the InitialUnlockedCount is exhausted,
 	// UnlockAddressRate addresses will be unlocked per UnlockTimeInterval
 	UnlockTimeInterval uint64
+	// UnlockStartTime is the unix time at which the first UnlockAddressRate addresses after
+	// InitialUnlockedCount are unlocked. 0 disables time-based unlocking,
+	// and only the InitialUnlockedCount addresses are unlocked
+	UnlockStartTime uint64
 
 	// Addresses are the distribution addresses that received coins in the
 	// first block after the genesis block
@@ -42,6 +46,10 @@ func (d *Distribution) Validate() error {
 		return errors.New("MaxCoinSupply should be perfectly divisible by len(addresses)")
 	}
 
+	if d.timedUnlock() && d.UnlockTimeInterval == 0 {
+		return errors.New("UnlockTimeInterval must be > 0 if UnlockAddressRate and UnlockStartTime are set")
+	}
+
 	if err := d.decodeAddresses(); err != nil {
 		return err
 	}
@@ -54,31 +62,95 @@ func (d *Distribution) AddressInitialBalance() uint64 {
 	return d.MaxCoinSupply / uint64(len(d.Addresses))
 }
 
-// UnlockedAddresses returns distribution addresses that are unlocked, i.e. they have spendable outputs
-func (d *Distribution) UnlockedAddresses() []string {
-	// The first InitialUnlockedCount (25) addresses are unlocked by default.
-	// Subsequent addresses will be unlocked at a rate of UnlockAddressRate (5) per year,
-	// after the InitialUnlockedCount (25) addresses have no remaining balance.
-	// The unlock timer will be enabled manually once the
-	// InitialUnlockedCount (25) addresses are distributed.
+// UnlockedCount returns the number of distribution addresses that are unlocked at headTime.
+// The first InitialUnlockedCount addresses are always unlocked. If time-based unlocking is enabled,
+// the next UnlockAddressRate addresses are unlocked at UnlockStartTime, and every UnlockTimeInterval after it
+func (d *Distribution) UnlockedCount(headTime uint64) uint64 {
+	n := uint64(len(d.Addresses))
+	if d.InitialUnlockedCount > n {
+		panic("number of distribution addresses is less than InitialUnlockedCount")
+	}
 
-	// NOTE: To have automatic unlocking, transaction verification would have
-	// to be handled in visor rather than in coin.Transactions.Visor(), because
-	// the coin package is agnostic to the state of the blockchain and cannot reference it.
-	// Instead of automatic unlocking, we can hardcode the timestamp at which the first 30%
-	// is distributed, then compute the unlocked addresses easily here.
+	if !d.timedUnlock() || headTime < d.UnlockStartTime {
+		return d.InitialUnlockedCount
+	}
+
+	// Count the unlocks in batches so that the multiplication can't overflow
+	unlocked := d.InitialUnlockedCount
+	batches := (headTime-d.UnlockStartTime)/d.UnlockTimeInterval + 1
+	for i := uint64(0); i < batches && unlocked < n; i++ {
+		if n-unlocked <= d.UnlockAddressRate {
+			return n
+		}
+		unlocked += d.UnlockAddressRate
+	}
+
+	return unlocked
+}
+
+// NextUnlockTime returns the time at which more distribution addresses are unlocked after headTime.
+// Returns false if no more addresses will be unlocked
+func (d *Distribution) NextUnlockTime(headTime uint64) (uint64, bool) {
+	if !d.timedUnlock() || d.UnlockedCount(headTime) == uint64(len(d.Addresses)) {
+		return 0, false
+	}
+
+	if headTime < d.UnlockStartTime {
+		return d.UnlockStartTime, true
+	}
+
+	batches := (headTime-d.UnlockStartTime)/d.UnlockTimeInterval + 1
+	return d.UnlockStartTime + batches*d.UnlockTimeInterval, true
+}
 
-	addrs := make([]string, d.InitialUnlockedCount)
-	copy(addrs[:], d.Addresses[:d.InitialUnlockedCount])
+// UnlockEvent is a scheduled unlock of distribution addresses
+type UnlockEvent struct {
+	// Time is the unix time of the unlock
+	Time uint64
+	// Addresses are the addresses unlocked at Time
+	Addresses []string
+}
+
+// UnlockSchedule returns the time-based unlocks, in order. It is empty if time-based unlocking is disabled
+func (d *Distribution) UnlockSchedule() []UnlockEvent {
+	if !d.timedUnlock() {
+		return nil
+	}
+
+	var events []UnlockEvent
+	t := d.UnlockStartTime
+	for i := d.InitialUnlockedCount; i < uint64(len(d.Addresses)); i += d.UnlockAddressRate {
+		j := i + d.UnlockAddressRate
+		if j > uint64(len(d.Addresses)) || j < i {
+			j = uint64(len(d.Addresses))
+		}
+
+		addrs := make([]string, j-i)
+		copy(addrs, d.Addresses[i:j])
+		events = append(events, UnlockEvent{
+			Time:      t,
+			Addresses: addrs,
+		})
+
+		t += d.UnlockTimeInterval
+	}
+
+	return events
+}
+
+// UnlockedAddresses returns distribution addresses that are unlocked at headTime, i.e. they have spendable outputs
+func (d *Distribution) UnlockedAddresses(headTime uint64) []string {
+	n := d.UnlockedCount(headTime)
+	addrs := make([]string, n)
+	copy(addrs, d.Addresses[:n])
 	return addrs
 }
 
-// LockedAddresses returns distribution addresses that are locked, i.e. they have unspendable outputs
-func (d *Distribution) LockedAddresses() []string {
-	// TODO -- once we reach 30% distribution, we can hardcode the
-	// initial timestamp for releasing more coins
-	addrs := make([]string, d.numLocked())
-	copy(addrs, d.Addresses[d.InitialUnlockedCount:])
+// LockedAddresses returns distribution addresses that are locked at headTime, i.e. they have unspendable outputs
+func (d *Distribution) LockedAddresses(headTime uint64) []string {
+	n := d.UnlockedCount(headTime)
+	addrs := make([]string, uint64(len(d.Addresses))-n)
+	copy(addrs, d.Addresses[n:])
 	return addrs
 }
 
@@ -91,41 +163,27 @@ func (d *Distribution) AddressesDecoded() []cipher.Address {
 	return addrs
 }
 
-// UnlockedAddressesDecoded returns distribution addresses that are unlocked, i.e. they have spendable outputs
-func (d *Distribution) UnlockedAddressesDecoded() []cipher.Address {
-	// The first d.InitialUnlockedCount (25) addresses are unlocked by default.
-	// Subsequent addresses will be unlocked at a rate of UnlockAddressRate (5) per year,
-	// after the d.InitialUnlockedCount (25) addresses have no remaining balance.
-	// The unlock timer will be enabled manually once the
-	// d.InitialUnlockedCount (25) addresses are distributed.
-
-	// NOTE: To have automatic unlocking, transaction verification would have
-	// to be handled in visor rather than in coin.Transactions.Visor(), because
-	// the coin package is agnostic to the state of the blockchain and cannot reference it.
-	// Instead of automatic unlocking, we can hardcode the timestamp at which the first 30%
-	// is distributed, then compute the unlocked addresses easily here.
+// UnlockedAddressesDecoded returns distribution addresses that are unlocked at headTime, i.e. they have spendable outputs
+func (d *Distribution) UnlockedAddressesDecoded(headTime uint64) []cipher.Address {
 	d.mustDecodeAddresses()
-	addrs := make([]cipher.Address, d.InitialUnlockedCount)
-	copy(addrs[:], d.addressesDecoded[:d.InitialUnlockedCount])
+	n := d.UnlockedCount(headTime)
+	addrs := make([]cipher.Address, n)
+	copy(addrs, d.addressesDecoded[:n])
 	return addrs
 }
 
-// LockedAddressesDecoded returns distribution addresses that are locked, i.e. they have unspendable outputs
-func (d *Distribution) LockedAddressesDecoded() []cipher.Address {
-	// TODO -- once we reach 30% distribution, we can hardcode the
-	// initial timestamp for releasing more coins
+// LockedAddressesDecoded returns distribution addresses that are locked at headTime, i.e. they have unspendable outputs
+func (d *Distribution) LockedAddressesDecoded(headTime uint64) []cipher.Address {
 	d.mustDecodeAddresses()
-	addrs := make([]cipher.Address, d.numLocked())
-	copy(addrs, d.addressesDecoded[d.InitialUnlockedCount:])
+	n := d.UnlockedCount(headTime)
+	addrs := make([]cipher.Address, uint64(len(d.addressesDecoded))-n)
+	copy(addrs, d.addressesDecoded[n:])
 	return addrs
 }
 
-func (d *Distribution) numLocked() uint64 {
-	n := uint64(len(d.Addresses))
-	if n < d.InitialUnlockedCount {
-		panic("number of distribution addresses is less than InitialUnlockedCount")
-	}
-	return n - d.InitialUnlockedCount
+// timedUnlock returns true if time-based unlocking is enabled
+func (d *Distribution) timedUnlock() bool {
+	return d.UnlockAddressRate != 0 && d.UnlockStartTime != 0
 }
 
 func (d *Distribution) decodeAddresses() error {
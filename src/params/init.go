@@ -21,6 +21,8 @@ func sanityCheck() {
 	}
 
 	MainNetDistribution.MustValidate()
+	MainNetCheckpoints.MustValidate()
+	MainNetPublisherKeyChanges.MustValidate()
 }
 
 func loadUserBurnFactor() {
@@ -12,6 +12,7 @@ var (
 		InitialUnlockedCount: 100,
 		UnlockAddressRate:    0,
 		UnlockTimeInterval:   0,
+		UnlockStartTime:      0,
 		Addresses: []string{
 			"fnKcaxVgEZCjmW53Nv26pSeUWxGVfMZbZT",
 			"2MCyvB1GjtdUgFcdTkmzm7wCGLETvR7BDoB",
@@ -116,6 +117,12 @@ var (
 		},
 	}
 
+	// MainNetCheckpoints Laqpay mainnet block checkpoints
+	MainNetCheckpoints = Checkpoints{}
+
+	// MainNetPublisherKeyChanges Laqpay mainnet block publisher key changes
+	MainNetPublisherKeyChanges = PublisherKeyChanges{}
+
 	// UserVerifyTxn transaction verification parameters for user-created transactions
 	UserVerifyTxn = VerifyTxn{
 		// BurnFactor can be overriden with `USER_BURN_FACTOR` env var
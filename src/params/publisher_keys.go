@@ -0,0 +1,54 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+
+	"../../src/cipher"
+)
+
+// PublisherKeyChange hands off block publishing to a new public key.
+// Blocks at and above Seq must be signed by Pubkey.
+// The change is signed by the key that signs the block at Seq-1
+type PublisherKeyChange struct {
+	// Seq is the sequence of the first block signed by Pubkey
+	Seq uint64
+	// Pubkey is the hex-encoded public key of the new block publisher
+	Pubkey string
+	// Sig is the hex-encoded signature of the change by the previous block publisher
+	Sig string
+}
+
+// PublisherKeyChanges are block publisher key changes, sorted by ascending sequence
+type PublisherKeyChanges []PublisherKeyChange
+
+// MustValidate validates PublisherKeyChanges, panics on error
+func (c PublisherKeyChanges) MustValidate() {
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// Validate validates PublisherKeyChanges.
+// The signatures are verified by the visor, which knows the key in effect at each sequence
+func (c PublisherKeyChanges) Validate() error {
+	for i, kc := range c {
+		if kc.Seq == 0 {
+			return errors.New("publisher key change seq must be > 0")
+		}
+
+		if _, err := cipher.PubKeyFromHex(kc.Pubkey); err != nil {
+			return fmt.Errorf("invalid pubkey for publisher key change %d: %v", kc.Seq, err)
+		}
+
+		if _, err := cipher.SigFromHex(kc.Sig); err != nil {
+			return fmt.Errorf("invalid sig for publisher key change %d: %v", kc.Seq, err)
+		}
+
+		if i > 0 && kc.Seq <= c[i-1].Seq {
+			return errors.New("publisher key changes must be sorted by ascending seq, without duplicates")
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package readable
 
 import (
+	"fmt"
+
+	"../../src/cipher"
 	"../../src/daemon"
 	"../../src/visor"
 )
@@ -56,3 +59,58 @@ func NewBlockchainProgress(bp *daemon.BlockchainProgress) BlockchainProgress {
 		Peers:   peers,
 	}
 }
+
+// PublisherKeyChange is a hand-off of block publishing to a new public key
+type PublisherKeyChange struct {
+	Seq    uint64 `json:"seq"`
+	Pubkey string `json:"pubkey"`
+	Sig    string `json:"sig"`
+}
+
+// NewPublisherKeyChange copies visor.PublisherKeyChange to a struct with json tags
+func NewPublisherKeyChange(kc visor.PublisherKeyChange) PublisherKeyChange {
+	return PublisherKeyChange{
+		Seq:    kc.Seq,
+		Pubkey: kc.Pubkey.Hex(),
+		Sig:    kc.Sig.Hex(),
+	}
+}
+
+// ToVisorPublisherKeyChange converts to visor.PublisherKeyChange
+func (kc PublisherKeyChange) ToVisorPublisherKeyChange() (visor.PublisherKeyChange, error) {
+	pubkey, err := cipher.PubKeyFromHex(kc.Pubkey)
+	if err != nil {
+		return visor.PublisherKeyChange{}, fmt.Errorf("Invalid pubkey: %v", err)
+	}
+
+	sig, err := cipher.SigFromHex(kc.Sig)
+	if err != nil {
+		return visor.PublisherKeyChange{}, fmt.Errorf("Invalid sig: %v", err)
+	}
+
+	return visor.PublisherKeyChange{
+		Seq:    kc.Seq,
+		Pubkey: pubkey,
+		Sig:    sig,
+	}, nil
+}
+
+// PublisherKeys are the keys that sign blocks: the blockchain pubkey,
+// and the changes that hand off block publishing to new keys
+type PublisherKeys struct {
+	BlockchainPubkey string               `json:"blockchain_pubkey"`
+	Changes          []PublisherKeyChange `json:"changes"`
+}
+
+// NewPublisherKeys creates PublisherKeys
+func NewPublisherKeys(pubkey cipher.PubKey, kcs []visor.PublisherKeyChange) PublisherKeys {
+	changes := make([]PublisherKeyChange, len(kcs))
+	for i, kc := range kcs {
+		changes[i] = NewPublisherKeyChange(kc)
+	}
+
+	return PublisherKeys{
+		BlockchainPubkey: pubkey.Hex(),
+		Changes:          changes,
+	}
+}
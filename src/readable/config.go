@@ -0,0 +1,8 @@
+package readable
+
+// ConfigOption is a daemon option value and where it was set: "flag", "env", "file" or "default"
+type ConfigOption struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
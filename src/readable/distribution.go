@@ -0,0 +1,76 @@
+package readable
+
+import (
+	"../../src/params"
+	"../../src/util/droplet"
+)
+
+// UnlockEvent is a scheduled time-based unlock of distribution addresses
+type UnlockEvent struct {
+	Time      uint64   `json:"time"`
+	Addresses []string `json:"addresses"`
+	// Unlocked is true if the head block time is at or after Time
+	Unlocked bool `json:"unlocked"`
+}
+
+// Distribution is the distribution unlock schedule and the unlock state as of the head block
+type Distribution struct {
+	MaxSupply             string `json:"max_supply"`
+	AddressInitialBalance string `json:"address_initial_balance"`
+	InitialUnlockedCount  uint64 `json:"initial_unlocked_count"`
+	UnlockAddressRate     uint64 `json:"unlock_address_rate"`
+	UnlockTimeInterval    uint64 `json:"unlock_time_interval"`
+	UnlockStartTime       uint64 `json:"unlock_start_time"`
+
+	HeadTime      uint64 `json:"head_time"`
+	UnlockedCount uint64 `json:"unlocked_count"`
+	LockedCount   uint64 `json:"locked_count"`
+	// NextUnlockTime is the time of the next unlock, 0 if no more addresses will be unlocked
+	NextUnlockTime    uint64        `json:"next_unlock_time"`
+	UnlockedAddresses []string      `json:"unlocked_addresses"`
+	LockedAddresses   []string      `json:"locked_addresses"`
+	Schedule          []UnlockEvent `json:"schedule"`
+}
+
+// NewDistribution creates a Distribution from params.Distribution, as of the head block time
+func NewDistribution(d params.Distribution, headTime uint64) (*Distribution, error) {
+	maxSupply, err := droplet.ToString(d.MaxCoinSupply * droplet.Multiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	initialBalance, err := droplet.ToString(d.AddressInitialBalance() * droplet.Multiplier)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := d.UnlockSchedule()
+	events := make([]UnlockEvent, len(schedule))
+	for i, e := range schedule {
+		events[i] = UnlockEvent{
+			Time:      e.Time,
+			Addresses: e.Addresses,
+			Unlocked:  headTime >= e.Time,
+		}
+	}
+
+	unlocked := d.UnlockedAddresses(headTime)
+	locked := d.LockedAddresses(headTime)
+	nextUnlockTime, _ := d.NextUnlockTime(headTime)
+
+	return &Distribution{
+		MaxSupply:             maxSupply,
+		AddressInitialBalance: initialBalance,
+		InitialUnlockedCount:  d.InitialUnlockedCount,
+		UnlockAddressRate:     d.UnlockAddressRate,
+		UnlockTimeInterval:    d.UnlockTimeInterval,
+		UnlockStartTime:       d.UnlockStartTime,
+		HeadTime:              headTime,
+		UnlockedCount:         uint64(len(unlocked)),
+		LockedCount:           uint64(len(locked)),
+		NextUnlockTime:        nextUnlockTime,
+		UnlockedAddresses:     unlocked,
+		LockedAddresses:       locked,
+		Schedule:              events,
+	}, nil
+}
@@ -2,7 +2,10 @@ package readable
 
 import (
 	"../../src/daemon"
+	"../../src/daemon/gnet"
+	"../../src/daemon/pex"
 	"../../src/params"
+	wh "../../src/util/http"
 	"../../src/util/useragent"
 )
 
@@ -21,6 +24,10 @@ type Connection struct {
 	UserAgent            useragent.Data         `json:"user_agent"`
 	IsTrustedPeer        bool                   `json:"is_trusted_peer"`
 	UnconfirmedVerifyTxn VerifyTxn              `json:"unconfirmed_verify_transaction"`
+	Services             []string               `json:"services"`
+	Stats                ConnectionStats        `json:"stats"`
+	RTT                  wh.Duration            `json:"rtt"`
+	PeerStats            PeerStats              `json:"peer_stats"`
 }
 
 // NewConnection copies daemon.Connection to a struct with json tags
@@ -53,6 +60,109 @@ func NewConnection(c *daemon.Connection) Connection {
 		UserAgent:            c.UserAgent,
 		IsTrustedPeer:        c.Pex.Trusted,
 		UnconfirmedVerifyTxn: NewVerifyTxn(c.UnconfirmedVerifyTxn),
+		Services:             c.Services.Names(),
+		Stats:                NewConnectionStats(c.Gnet.Stats),
+		RTT:                  wh.FromDuration(c.RTT),
+		PeerStats:            NewPeerStats(c.Pex.Stats),
+	}
+}
+
+// PeerStats latency and reliability of the connections made to a peer
+type PeerStats struct {
+	RTT             wh.Duration       `json:"rtt"`
+	Uptime          wh.Duration       `json:"uptime"`
+	Connections     uint64            `json:"connections"`
+	BlocksRequested uint64            `json:"blocks_requested"`
+	BlocksDelivered uint64            `json:"blocks_delivered"`
+	Disconnects     map[string]uint64 `json:"disconnects"`
+	Score           float64           `json:"score"`
+}
+
+// NewPeerStats copies pex.PeerStats to a struct with json tags
+func NewPeerStats(s pex.PeerStats) PeerStats {
+	disconnects := make(map[string]uint64, len(s.Disconnects))
+	for k, v := range s.Disconnects {
+		disconnects[k] = v
+	}
+
+	return PeerStats{
+		RTT:             wh.FromDuration(s.RTT),
+		Uptime:          wh.FromDuration(s.Uptime),
+		Connections:     s.Connections,
+		BlocksRequested: s.BlocksRequested,
+		BlocksDelivered: s.BlocksDelivered,
+		Disconnects:     disconnects,
+		Score:           s.Score(),
+	}
+}
+
+// Peer a peer in the daemon's peer list
+type Peer struct {
+	Addr            string         `json:"address"`
+	LastSeen        int64          `json:"last_seen"`
+	Private         bool           `json:"private"`
+	Trusted         bool           `json:"trusted"`
+	HasIncomingPort bool           `json:"has_incoming_port"`
+	UserAgent       useragent.Data `json:"user_agent"`
+	Tried           bool           `json:"tried"`
+	Stats           PeerStats      `json:"stats"`
+}
+
+// NewPeer copies pex.Peer to a struct with json tags
+func NewPeer(p pex.Peer) Peer {
+	return Peer{
+		Addr:            p.Addr,
+		LastSeen:        p.LastSeen,
+		Private:         p.Private,
+		Trusted:         p.Trusted,
+		HasIncomingPort: p.HasIncomingPort,
+		UserAgent:       p.UserAgent,
+		Tried:           p.Tried,
+		Stats:           NewPeerStats(p.Stats),
+	}
+}
+
+// MessageStats number of messages and bytes of a message type
+type MessageStats struct {
+	Count uint64 `json:"count"`
+	Bytes uint64 `json:"bytes"`
+}
+
+// ConnectionStats bytes and messages sent and received, in total and per message type
+type ConnectionStats struct {
+	BytesSent        uint64                  `json:"bytes_sent"`
+	BytesReceived    uint64                  `json:"bytes_received"`
+	MessagesSent     uint64                  `json:"messages_sent"`
+	MessagesReceived uint64                  `json:"messages_received"`
+	SentByType       map[string]MessageStats `json:"sent_by_type"`
+	ReceivedByType   map[string]MessageStats `json:"received_by_type"`
+}
+
+// NewConnectionStats copies gnet.ConnectionStats to a struct with json tags
+func NewConnectionStats(s gnet.ConnectionStats) ConnectionStats {
+	sent := make(map[string]MessageStats, len(s.SentByType))
+	for k, v := range s.SentByType {
+		sent[k] = MessageStats{
+			Count: v.Count,
+			Bytes: v.Bytes,
+		}
+	}
+
+	received := make(map[string]MessageStats, len(s.ReceivedByType))
+	for k, v := range s.ReceivedByType {
+		received[k] = MessageStats{
+			Count: v.Count,
+			Bytes: v.Bytes,
+		}
+	}
+
+	return ConnectionStats{
+		BytesSent:        s.BytesSent,
+		BytesReceived:    s.BytesReceived,
+		MessagesSent:     s.MessagesSent,
+		MessagesReceived: s.MessagesReceived,
+		SentByType:       sent,
+		ReceivedByType:   received,
 	}
 }
 
@@ -5,8 +5,22 @@ package iputil
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"strconv"
+	"strings"
+)
+
+const (
+	// onionSuffix is the pseudo top level domain of Tor onion services
+	onionSuffix = ".onion"
+	// onionV2Len is the length of a v2 onion service name, without the suffix
+	onionV2Len = 16
+	// onionV3Len is the length of a v3 onion service name, without the suffix
+	onionV3Len = 56
+	// ipv6BaseBits is the prefix length of an IPv6 address treated as a single host.
+	// Hosts are normally assigned a whole /64.
+	ipv6BaseBits = 64
 )
 
 var (
@@ -64,3 +78,61 @@ func SplitAddr(addr string) (string, uint16, error) {
 
 	return ip, uint16(port64), nil
 }
+
+// IsIPv6 returns true if host is an IPv6 address.
+// IPv4-mapped IPv6 addresses are treated as IPv4.
+func IsIPv6(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() == nil
+}
+
+// BaseIP returns the base IP of ip, used to group connections from the same host.
+// IPv4 addresses are returned unchanged, IPv6 addresses are reduced to their /64 network.
+// If ip is not an IP address (e.g. a hostname), it is returned unchanged.
+func BaseIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() != nil {
+		return ip
+	}
+
+	base := parsed.Mask(net.CIDRMask(ipv6BaseBits, 8*net.IPv6len))
+	return fmt.Sprintf("%s/%d", base.String(), ipv6BaseBits)
+}
+
+// JoinAddr joins an ip and port to an ip:port string.
+// IPv6 addresses are enclosed in brackets.
+func JoinAddr(ip string, port uint16) string {
+	return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+}
+
+// IsOnion returns true if host is a Tor onion service hostname (v2 or v3).
+// The host must not include a port.
+func IsOnion(host string) bool {
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, onionSuffix) {
+		return false
+	}
+
+	name := strings.TrimSuffix(host, onionSuffix)
+	if len(name) != onionV2Len && len(name) != onionV3Len {
+		return false
+	}
+
+	// Onion service names are base32 encoded
+	for _, c := range name {
+		if !((c >= 'a' && c <= 'z') || (c >= '2' && c <= '7')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsOnionAddr returns true if addr is an onion:port string
+func IsOnionAddr(addr string) bool {
+	host, _, err := SplitAddr(addr)
+	if err != nil {
+		return false
+	}
+	return IsOnion(host)
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/sirupsen/logrus"
+
 	"../../src/cipher"
 	"../../src/coin"
 	"../../src/params"
@@ -25,6 +27,14 @@ const (
 var (
 	// ErrVerifyStopped is returned when database verification is interrupted
 	ErrVerifyStopped = errors.New("database verification stopped")
+	// ErrCheckpointMismatch is returned when a block does not match the checkpoint at its seq
+	ErrCheckpointMismatch = errors.New("Block hash does not match checkpoint")
+	// ErrUnknownBlockSigner is returned when a block is not signed by a block publisher
+	ErrUnknownBlockSigner = errors.New("Block is not signed by a block publisher")
+	// ErrEmptyBlocksNotAllowed is returned when creating a block with no transactions, if empty blocks are not allowed
+	ErrEmptyBlocksNotAllowed = errors.New("Empty blocks are not allowed")
+	// ErrHeadChanged is returned when the head block changed while a created block was being signed
+	ErrHeadChanged = errors.New("Head block changed while the new block was being signed")
 )
 
 // ErrBlockNotExist may be returned if a block is not found
@@ -98,6 +108,9 @@ func CreateBuckets(db *dbutil.DB) error {
 		return dbutil.CreateBuckets(tx, [][]byte{
 			UnconfirmedTxnsBkt,
 			UnconfirmedUnspentsBkt,
+			PublisherKeyChangesBkt,
+			PublisherKeyProposalsBkt,
+			ConsensusVoteBkt,
 		})
 	})
 }
@@ -109,6 +122,18 @@ type BlockchainConfig struct {
 	// node will throw the error and return.
 	Arbitrating bool
 	Pubkey      cipher.PubKey
+	// PublisherPubkeys are the public keys of the other block publishers
+	PublisherPubkeys []cipher.PubKey
+	// PublisherKeyChanges hand off block publishing from Pubkey to new keys.
+	// They are committed along with the changes saved in the database
+	PublisherKeyChanges params.PublisherKeyChanges
+	// Checkpoints are the blocks that the blockchain must contain
+	Checkpoints params.Checkpoints
+	// AssumeValid skips the verification of transaction signatures
+	// in blocks at or below the latest checkpoint
+	AssumeValid bool
+	// AllowEmptyBlocks accepts blocks with no transactions, e.g. the heartbeat blocks of a private network
+	AllowEmptyBlocks bool
 }
 
 // Blockchain maintains blockchain and provides apis for accessing the chain.
@@ -116,6 +141,7 @@ type Blockchain struct {
 	db    *dbutil.DB
 	cfg   BlockchainConfig
 	store chainStore
+	keys  *keySchedule
 }
 
 // NewBlockchain creates a Blockchain
@@ -125,10 +151,40 @@ func NewBlockchain(db *dbutil.DB, cfg BlockchainConfig) (*Blockchain, error) {
 		return nil, err
 	}
 
+	cfgKcs, err := NewPublisherKeyChangesFromParams(cfg.PublisherKeyChanges)
+	if err != nil {
+		return nil, err
+	}
+
+	var kcs, proposed []PublisherKeyChange
+	if err := db.View("NewBlockchain", func(tx *dbutil.Tx) error {
+		var err error
+		kcs, err = getPublisherKeyChanges(tx, PublisherKeyChangesBkt)
+		if err != nil {
+			return err
+		}
+
+		proposed, err = getPublisherKeyChanges(tx, PublisherKeyProposalsBkt)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	keys := newKeySchedule(cfg.Pubkey)
+	if err := keys.addAll(append(cfgKcs, kcs...)); err != nil {
+		return nil, err
+	}
+
+	// The proposals were checked when they were added, and are verified again by the block at their seq
+	for _, kc := range proposed {
+		keys.propose(kc)
+	}
+
 	return &Blockchain{
 		cfg:   cfg,
 		db:    db,
 		store: chainstore,
+		keys:  keys,
 	}, nil
 }
 
@@ -187,7 +243,7 @@ func (bc *Blockchain) Time(tx *dbutil.Tx) (uint64, error) {
 // The caller of this function should apply any additional soft constraints,
 // and choose which transactions to place into the block.
 func (bc Blockchain) NewBlock(tx *dbutil.Tx, txns coin.Transactions, currentTime uint64) (*coin.Block, error) {
-	if len(txns) == 0 {
+	if len(txns) == 0 && !bc.cfg.AllowEmptyBlocks {
 		return nil, errors.New("No transactions")
 	}
 
@@ -200,7 +256,7 @@ func (bc Blockchain) NewBlock(tx *dbutil.Tx, txns coin.Transactions, currentTime
 		return nil, errors.New("Time can only move forward")
 	}
 
-	txns, err = bc.processTransactions(tx, txns)
+	txns, err = bc.processTransactions(tx, txns, TxnSigned)
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +278,7 @@ func (bc Blockchain) NewBlock(tx *dbutil.Tx, txns coin.Transactions, currentTime
 		if err := bc.verifyBlockHeader(tx, *b); err != nil {
 			return nil, err
 		}
-		txns, err := bc.processTransactions(tx, b.Body.Transactions)
+		txns, err := bc.processTransactions(tx, b.Body.Transactions, TxnSigned)
 		if err != nil {
 			logger.Panicf("bc.processTransactions second verification call failed: %v", err)
 		}
@@ -232,6 +288,10 @@ func (bc Blockchain) NewBlock(tx *dbutil.Tx, txns coin.Transactions, currentTime
 }
 
 func (bc *Blockchain) processBlock(tx *dbutil.Tx, b coin.SignedBlock) (coin.SignedBlock, error) {
+	if err := bc.verifyCheckpoint(b.Block); err != nil {
+		return coin.SignedBlock{}, err
+	}
+
 	length, err := bc.Len(tx)
 	if err != nil {
 		return coin.SignedBlock{}, err
@@ -249,7 +309,7 @@ func (bc *Blockchain) processBlock(tx *dbutil.Tx, b coin.SignedBlock) (coin.Sign
 				return coin.SignedBlock{}, err
 			}
 
-			txns, err := bc.processTransactions(tx, b.Body.Transactions)
+			txns, err := bc.processTransactions(tx, b.Body.Transactions, bc.blockTxnSignedFlag(b.Seq()))
 			if err != nil {
 				return coin.SignedBlock{}, err
 			}
@@ -291,6 +351,39 @@ func (bc *Blockchain) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 		return err
 	}
 
+	return bc.commitPublisherKeyChange(tx, nb)
+}
+
+// commitPublisherKeyChange commits the proposed publisher key change that the block is signed with, if any,
+// and drops the other proposed changes for the block's seq and below. The key schedule is updated
+// once the database transaction is committed
+func (bc *Blockchain) commitPublisherKeyChange(tx *dbutil.Tx, b coin.SignedBlock) error {
+	dropped := bc.keys.proposalsUpTo(b.Seq())
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	if err := deletePublisherKeyProposals(tx, dropped); err != nil {
+		return err
+	}
+
+	kc, ok := bc.keys.proposalFor(b)
+	if !ok {
+		tx.OnCommit(func() {
+			bc.keys.commit(b.Seq(), nil)
+		})
+		return nil
+	}
+
+	if err := putPublisherKeyChange(tx, kc); err != nil {
+		return err
+	}
+
+	logger.Infof("Block %d commits the block publisher key change to %s", kc.Seq, kc.Pubkey.Hex())
+
+	tx.OnCommit(func() {
+		bc.keys.commit(b.Seq(), &kc)
+	})
 	return nil
 }
 
@@ -329,9 +422,78 @@ func (bc Blockchain) verifyUxHash(tx *dbutil.Tx, b coin.Block) error {
 	return nil
 }
 
+// verifyCheckpoint checks that the block matches the checkpoint at its seq, if there is one
+func (bc Blockchain) verifyCheckpoint(b coin.Block) error {
+	hash, ok := bc.cfg.Checkpoints.Hash(b.Seq())
+	if !ok {
+		return nil
+	}
+
+	if b.HashHeader() != hash {
+		logger.WithFields(logrus.Fields{
+			"seq":        b.Seq(),
+			"hash":       b.HashHeader().Hex(),
+			"checkpoint": hash.Hex(),
+		}).Warning("Block does not match checkpoint")
+		return ErrCheckpointMismatch
+	}
+
+	return nil
+}
+
+// VerifyCheckpoints checks that the blocks in the blockchain match the checkpoints
+func (bc Blockchain) VerifyCheckpoints(tx *dbutil.Tx) error {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	for _, cp := range bc.cfg.Checkpoints {
+		if cp.Seq > headSeq {
+			break
+		}
+
+		b, err := bc.GetSignedBlockBySeq(tx, cp.Seq)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("block %d of checkpoint not found", cp.Seq)
+		}
+
+		if err := bc.verifyCheckpoint(b.Block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// blockTxnSignedFlag returns the TxnSignedFlag used to verify the transactions of the block at seq.
+// The signatures of transactions in blocks at or below the latest checkpoint are assumed to be valid,
+// since the checkpoint commits to the blocks before it
+func (bc Blockchain) blockTxnSignedFlag(seq uint64) TxnSignedFlag {
+	if !bc.cfg.AssumeValid {
+		return TxnSigned
+	}
+
+	if latest, ok := bc.cfg.Checkpoints.LatestSeq(); ok && seq <= latest {
+		return TxnAssumeSigned
+	}
+
+	return TxnSigned
+}
+
 // VerifyBlockTxnConstraints checks that the transaction does not violate hard constraints,
 // for transactions that are already included in a block.
 func (bc Blockchain) VerifyBlockTxnConstraints(tx *dbutil.Tx, txn coin.Transaction) error {
+	return bc.verifyBlockTxnConstraints(tx, txn, TxnSigned)
+}
+
+func (bc Blockchain) verifyBlockTxnConstraints(tx *dbutil.Tx, txn coin.Transaction, signed TxnSignedFlag) error {
 	// NOTE: Unspent().GetArray() returns an error if not all txn.In can be found
 	// This prevents double spends
 	uxIn, err := bc.Unspent().GetArray(tx, txn.In)
@@ -349,11 +511,11 @@ func (bc Blockchain) VerifyBlockTxnConstraints(tx *dbutil.Tx, txn coin.Transacti
 		return err
 	}
 
-	return bc.verifyBlockTxnHardConstraints(tx, txn, head, uxIn)
+	return bc.verifyBlockTxnHardConstraints(tx, txn, head, uxIn, signed)
 }
 
-func (bc Blockchain) verifyBlockTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, head *coin.SignedBlock, uxIn coin.UxArray) error {
-	if err := VerifyBlockTxnConstraints(txn, head.Head, uxIn); err != nil {
+func (bc Blockchain) verifyBlockTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, head *coin.SignedBlock, uxIn coin.UxArray, signed TxnSignedFlag) error {
+	if err := verifyBlockTxnConstraints(txn, head.Head, uxIn, signed); err != nil {
 		return err
 	}
 
@@ -523,6 +685,36 @@ func (bc Blockchain) GetLastBlocks(tx *dbutil.Tx, num uint64) ([]coin.SignedBloc
 
 /* Private */
 
+// verifyInputSignatures verifies the input signatures of the transactions concurrently,
+// and returns whether the signatures of each transaction are valid.
+// Transactions with invalid signatures, or that spend outputs not in the unspent pool,
+// are verified again with the other hard constraints, so that the first error
+// found for the block does not depend on the order in which the signatures are verified
+func (bc Blockchain) verifyInputSignatures(tx *dbutil.Tx, txns coin.Transactions) ([]bool, error) {
+	uxIns := make([]coin.UxArray, len(txns))
+	for i, txn := range txns {
+		uxIn, err := bc.Unspent().GetArray(tx, txn.In)
+		if err != nil {
+			switch err.(type) {
+			case blockdb.ErrUnspentNotExist:
+				continue
+			default:
+				return nil, err
+			}
+		}
+		uxIns[i] = uxIn
+	}
+
+	errs := txns.VerifyInputSignatures(uxIns)
+
+	verified := make([]bool, len(txns))
+	for i, err := range errs {
+		verified[i] = err == nil
+	}
+
+	return verified, nil
+}
+
 // Validates a set of Transactions, individually, against each other and
 // against the Blockchain.  If firstFail is true, it will return an error
 // as soon as it encounters one.  Else, it will return an array of
@@ -533,7 +725,7 @@ func (bc Blockchain) GetLastBlocks(tx *dbutil.Tx, num uint64) ([]coin.SignedBloc
 // TODO:
 //  - move arbitration to visor
 //  - blockchain should have strict checking
-func (bc Blockchain) processTransactions(tx *dbutil.Tx, txs coin.Transactions) (coin.Transactions, error) {
+func (bc Blockchain) processTransactions(tx *dbutil.Tx, txs coin.Transactions, signed TxnSignedFlag) (coin.Transactions, error) {
 	// copy txs so that the following code won't modify the original txns
 	txns := make(coin.Transactions, len(txs))
 	copy(txns, txs)
@@ -554,7 +746,7 @@ func (bc Blockchain) processTransactions(tx *dbutil.Tx, txs coin.Transactions) (
 
 	//TODO: audit
 	if len(txns) == 0 {
-		if bc.cfg.Arbitrating {
+		if bc.cfg.Arbitrating || bc.cfg.AllowEmptyBlocks {
 			return txns, nil
 		}
 
@@ -562,12 +754,27 @@ func (bc Blockchain) processTransactions(tx *dbutil.Tx, txs coin.Transactions) (
 		return nil, errors.New("No transactions")
 	}
 
+	// Verify the input signatures of all transactions concurrently
+	var sigsVerified []bool
+	if signed == TxnSigned {
+		sigsVerified, err = bc.verifyInputSignatures(tx, txns)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	skip := make(map[int]struct{})
 	uxHashes := make(coin.UxHashSet, len(txns))
 	for i, txn := range txns {
+		// The signatures were verified already, don't verify them again
+		txnSigned := signed
+		if sigsVerified != nil && sigsVerified[i] {
+			txnSigned = TxnAssumeSigned
+		}
+
 		// Check the transaction against itself.  This covers the hash,
 		// signature indices and duplicate spends within itself
-		if err := bc.VerifyBlockTxnConstraints(tx, txn); err != nil {
+		if err := bc.verifyBlockTxnConstraints(tx, txn, txnSigned); err != nil {
 			switch err.(type) {
 			case ErrTxnViolatesSoftConstraint:
 				logger.Critical().WithError(err).Panic("bc.VerifyBlockTxnConstraints should not return a ErrTxnViolatesSoftConstraint error")
@@ -697,10 +904,96 @@ func (bc Blockchain) TransactionFee(tx *dbutil.Tx, headTime uint64) coin.FeeCalc
 	}
 }
 
+// VerifyBlockSignature verifies that the block is signed by one of pubkeys
+func VerifyBlockSignature(b coin.SignedBlock, pubkeys []cipher.PubKey) error {
+	if len(pubkeys) == 1 {
+		return b.VerifySignature(pubkeys[0])
+	}
+
+	signer, err := cipher.PubKeyFromSig(b.Sig, b.HashHeader())
+	if err != nil {
+		return err
+	}
+
+	if !containsPubKey(pubkeys, signer) {
+		return ErrUnknownBlockSigner
+	}
+
+	return b.VerifySignature(signer)
+}
+
+func containsPubKey(pubkeys []cipher.PubKey, pk cipher.PubKey) bool {
+	for _, x := range pubkeys {
+		if x == pk {
+			return true
+		}
+	}
+	return false
+}
+
+// PublisherPubkeysAt returns the public keys that may sign the block at seq: the key in effect
+// for the blockchain pubkey at seq, the new keys of the proposed changes for seq, and the other block publishers' keys
+func (bc *Blockchain) PublisherPubkeysAt(seq uint64) []cipher.PubKey {
+	return append(bc.keys.signersAt(seq), bc.cfg.PublisherPubkeys...)
+}
+
+// ConsensusPubkeysAt returns the public keys of the block publishers that take part in the block consensus at seq:
+// the key in effect for the blockchain pubkey at seq, after the committed changes, and the other block publishers' keys.
+// The new keys of the proposed changes are not included until their change is committed
+func (bc *Blockchain) ConsensusPubkeysAt(seq uint64) []cipher.PubKey {
+	return append([]cipher.PubKey{bc.keys.pubkeyAt(seq)}, bc.cfg.PublisherPubkeys...)
+}
+
+// HandsOffPublisherKey returns true if pubkey is the key in effect for the blockchain pubkey at seq
+// and it signed a proposed change for seq, so that the block at seq is left to the new key
+func (bc *Blockchain) HandsOffPublisherKey(seq uint64, pubkey cipher.PubKey) bool {
+	return bc.keys.handsOff(seq, pubkey)
+}
+
+// PublisherKeyChanges returns the committed publisher key changes followed by the proposed changes, sorted by ascending seq
+func (bc *Blockchain) PublisherKeyChanges() []PublisherKeyChange {
+	return bc.keys.all()
+}
+
+// AddPublisherKeyChange proposes a publisher key change and saves it. The change is committed
+// by the block at its seq if that block is signed by its new key, see PublisherKeyChange.
+// The change must take effect above the head block and after the committed changes,
+// and be signed by the key that signs the block before it takes effect.
+// The key schedule is updated once the database transaction is committed
+func (bc *Blockchain) AddPublisherKeyChange(tx *dbutil.Tx, kc PublisherKeyChange) error {
+	bc.keys.RLock()
+	defer bc.keys.RUnlock()
+
+	if bc.keys.known(kc) {
+		return ErrPublisherKeyChangeKnown
+	}
+
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+	if ok && kc.Seq <= headSeq {
+		return ErrPublisherKeyChangeTooLate
+	}
+
+	if err := bc.keys.checkProposal(kc); err != nil {
+		return err
+	}
+
+	if err := putPublisherKeyProposal(tx, kc); err != nil {
+		return err
+	}
+
+	tx.OnCommit(func() {
+		bc.keys.propose(kc)
+	})
+	return nil
+}
+
 // VerifySignature checks that BlockSigs state correspond with coin.Blockchain state
 // and that all signatures are valid.
 func (bc *Blockchain) VerifySignature(block *coin.SignedBlock) error {
-	err := block.VerifySignature(bc.cfg.Pubkey)
+	err := VerifyBlockSignature(*block, bc.PublisherPubkeysAt(block.Seq()))
 	if err != nil {
 		logger.Errorf("Blockchain signature verification failed for block %d: %v", block.Head.BkSeq, err)
 	}
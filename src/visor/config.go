@@ -15,9 +15,15 @@ type Config struct {
 
 	// Public key of the blockchain
 	BlockchainPubkey cipher.PubKey
+	// Public keys of the other block publishers. Blocks signed by any of them are accepted
+	BlockPublisherPubkeys []cipher.PubKey
 
-	// Secret key of the blockchain (required if block publisher)
+	// Secret key of the blockchain (required if block publisher, unless BlockSigner is set)
 	BlockchainSeckey cipher.SecKey
+	// Signs the blocks created by a block publisher. If nil, the blocks are signed with BlockchainSeckey
+	BlockSigner BlockSigner
+	// Hand-offs of block publishing from BlockchainPubkey to new keys
+	PublisherKeyChanges params.PublisherKeyChanges
 
 	// Transaction verification parameters used for unconfirmed transactions
 	UnconfirmedVerifyTxn params.VerifyTxn
@@ -25,10 +31,20 @@ type Config struct {
 	CreateBlockVerifyTxn params.VerifyTxn
 	// Maximum size of a block, in bytes for creating blocks
 	MaxBlockTransactionsSize uint32
+	// Maximum number of transactions spending from the same address in a block, for creating blocks.
+	// 0 is unlimited
+	MaxBlockTransactionsPerAddress int
+	// Accept blocks with no transactions
+	AllowEmptyBlocks bool
 
 	// Coin distribution parameters (necessary for txn verification)
 	Distribution params.Distribution
 
+	// Blocks that the blockchain must contain
+	Checkpoints params.Checkpoints
+	// Skip the verification of transaction signatures in blocks at or below the latest checkpoint
+	AssumeValid bool
+
 	// Where the blockchain is saved
 	BlockchainFile string
 	// Where the block signatures are saved
@@ -67,11 +83,25 @@ func NewConfig() Config {
 	return c
 }
 
+// PublisherPubkeys returns the public keys that blocks may be signed by,
+// BlockchainPubkey followed by BlockPublisherPubkeys
+func (c Config) PublisherPubkeys() []cipher.PubKey {
+	return append([]cipher.PubKey{c.BlockchainPubkey}, c.BlockPublisherPubkeys...)
+}
+
 // Verify verifies the configuration
 func (c Config) Verify() error {
-	if c.IsBlockPublisher {
-		if c.BlockchainPubkey != cipher.MustPubKeyFromSecKey(c.BlockchainSeckey) {
-			return errors.New("Cannot run as block publisher: invalid seckey for pubkey")
+	if c.IsBlockPublisher && c.BlockSigner == nil {
+		// The seckey is checked against the publisher keys once the publisher key changes
+		// saved in the database are loaded, see New
+		if err := c.BlockchainSeckey.Verify(); err != nil {
+			return fmt.Errorf("Cannot run as block publisher: invalid seckey: %v", err)
+		}
+	}
+
+	for _, pk := range c.BlockPublisherPubkeys {
+		if err := pk.Verify(); err != nil {
+			return fmt.Errorf("Invalid block publisher pubkey %s: %v", pk.Hex(), err)
 		}
 	}
 
@@ -111,9 +141,21 @@ func (c Config) Verify() error {
 		return errors.New("MaxBlockTransactionsSize must be >= CreateBlockVerifyTxn.MaxTransactionSize")
 	}
 
+	if c.MaxBlockTransactionsPerAddress < 0 {
+		return errors.New("MaxBlockTransactionsPerAddress must be >= 0")
+	}
+
 	if err := c.Distribution.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.Checkpoints.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.PublisherKeyChanges.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,64 @@
+package visor
+
+import (
+	"../../src/cipher"
+	"../../src/visor/dbutil"
+)
+
+//go:generate laqencoder -unexported -struct ConsensusVote
+
+var (
+	// ConsensusVoteBkt holds the last block consensus vote of this node
+	ConsensusVoteBkt = []byte("consensus_vote")
+
+	consensusVoteKey = []byte("vote")
+)
+
+// ConsensusVote is the last candidate that this node signed as a block publisher taking part in the block consensus.
+// It is saved before the signature is sent, so that a restarted node does not sign another candidate for the same
+// seq and round
+type ConsensusVote struct {
+	Seq   uint64
+	Round uint32
+	Hash  cipher.SHA256
+}
+
+// GetConsensusVote returns the last saved block consensus vote, or nil if there is none
+func (vs *Visor) GetConsensusVote() (*ConsensusVote, error) {
+	var v *ConsensusVote
+	if err := vs.db.View("GetConsensusVote", func(tx *dbutil.Tx) error {
+		// Read-only databases created by older versions do not have the bucket
+		if !dbutil.Exists(tx, ConsensusVoteBkt) {
+			return nil
+		}
+
+		buf, err := dbutil.GetBucketValue(tx, ConsensusVoteBkt, consensusVoteKey)
+		if err != nil || buf == nil {
+			return err
+		}
+
+		var x ConsensusVote
+		if err := decodeConsensusVoteExact(buf, &x); err != nil {
+			return err
+		}
+
+		v = &x
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// SetConsensusVote saves a block consensus vote, in place of the last one
+func (vs *Visor) SetConsensusVote(v ConsensusVote) error {
+	buf, err := encodeConsensusVote(&v)
+	if err != nil {
+		return err
+	}
+
+	return vs.db.Update("SetConsensusVote", func(tx *dbutil.Tx) error {
+		return dbutil.PutBucketValue(tx, ConsensusVoteBkt, consensusVoteKey, buf)
+	})
+}
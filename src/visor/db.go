@@ -17,6 +17,7 @@ import (
 	"../../src/cipher"
 	"../../src/cipher/encoder"
 	"../../src/coin"
+	"../../src/params"
 	"../../src/util/elapse"
 	"../../src/visor/blockdb"
 	"../../src/visor/dbutil"
@@ -34,8 +35,10 @@ type ErrCorruptDB struct {
 	error
 }
 
-// CheckDatabase checks the database for corruption, rebuild history if corrupted
-func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) error {
+// CheckDatabase checks the database for corruption, rebuild history if corrupted.
+// Blocks must be signed by pubkey or by one of publisherPubkeys, where pubkey is replaced by
+// the key changes of keyChanges and of the database from the seq that they take effect
+func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey, keyChanges params.PublisherKeyChanges, quit chan struct{}) error {
 	elapser := elapse.NewElapser(time.Second*30, logger)
 	elapser.Register("CheckDatabase")
 	defer elapser.CheckForDone()
@@ -53,7 +56,11 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		return nil
 	}
 
-	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey:              pubkey,
+		PublisherPubkeys:    publisherPubkeys,
+		PublisherKeyChanges: keyChanges,
+	})
 	if err != nil {
 		return err
 	}
@@ -165,8 +172,8 @@ func backupDB(db *dbutil.DB) (*dbutil.DB, error) { //nolint:unused,megacheck
 // is ErrMissingSignature, then then it erases the db and starts over.
 // If it's ErrHistoryDBCorrupted, then rebuild historydb from scratch.
 // A copy of the corrupted database is saved.
-func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*dbutil.DB, error) {
-	err := CheckDatabase(db, pubkey, quit)
+func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey, keyChanges params.PublisherKeyChanges, quit chan struct{}) (*dbutil.DB, error) {
+	err := CheckDatabase(db, pubkey, publisherPubkeys, keyChanges, quit)
 	switch err.(type) {
 	case nil:
 		return db, nil
@@ -5,9 +5,10 @@ import (
 	"../../src/params"
 )
 
-// TransactionIsLocked returns true if the transaction spends locked outputs
-func TransactionIsLocked(d params.Distribution, inUxs coin.UxArray) bool {
-	lockedAddrs := d.LockedAddresses()
+// TransactionIsLocked returns true if the transaction spends outputs of distribution addresses
+// that are locked at headTime
+func TransactionIsLocked(d params.Distribution, headTime uint64, inUxs coin.UxArray) bool {
+	lockedAddrs := d.LockedAddresses(headTime)
 	lockedAddrsMap := make(map[string]struct{})
 	for _, a := range lockedAddrs {
 		lockedAddrsMap[a] = struct{}{}
@@ -47,6 +47,11 @@ type Blockchainer interface {
 	VerifySingleTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, signed TxnSignedFlag) error
 	VerifySingleTxnSoftHardConstraints(tx *dbutil.Tx, txn coin.Transaction, distParams params.Distribution, verifyParams params.VerifyTxn, signed TxnSignedFlag) (*coin.SignedBlock, coin.UxArray, error)
 	TransactionFee(tx *dbutil.Tx, hours uint64) coin.FeeCalculator
+	PublisherPubkeysAt(seq uint64) []cipher.PubKey
+	ConsensusPubkeysAt(seq uint64) []cipher.PubKey
+	HandsOffPublisherKey(seq uint64, pubkey cipher.PubKey) bool
+	PublisherKeyChanges() []PublisherKeyChange
+	AddPublisherKeyChange(tx *dbutil.Tx, kc PublisherKeyChange) error
 }
 
 // UnconfirmedTransactionPooler is the interface that provides methods for
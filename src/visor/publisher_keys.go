@@ -0,0 +1,406 @@
+package visor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"../../src/cipher"
+	"../../src/coin"
+	"../../src/params"
+	"../../src/visor/dbutil"
+)
+
+//go:generate laqencoder -unexported -struct PublisherKeyChange
+
+var (
+	// PublisherKeyChangesBkt holds the committed block publisher key changes, keyed by seq
+	PublisherKeyChangesBkt = []byte("publisher_key_changes")
+	// PublisherKeyProposalsBkt holds the proposed block publisher key changes, keyed by seq, pubkey and sig
+	PublisherKeyProposalsBkt = []byte("publisher_key_proposals")
+
+	// ErrPublisherKeyChangeKnown is returned when adding a publisher key change that is already known
+	ErrPublisherKeyChangeKnown = errors.New("Publisher key change is already known")
+	// ErrPublisherKeyChangeConflict is returned when a publisher key change does not take effect after the committed changes
+	ErrPublisherKeyChangeConflict = errors.New("Publisher key change conflicts with a committed publisher key change")
+	// ErrPublisherKeyChangeTooLate is returned when a publisher key change takes effect at or below the head block
+	ErrPublisherKeyChangeTooLate = errors.New("Publisher key change must take effect above the head block")
+	// ErrPublisherKeyNotActive is returned when creating a block with a key that does not sign the next block
+	ErrPublisherKeyNotActive = errors.New("Block publisher key does not sign the next block")
+	// ErrPublisherKeyProposalsFull is returned when proposing a publisher key change while maxPublisherKeyProposals are pending
+	ErrPublisherKeyProposalsFull = errors.New("Too many proposed publisher key changes")
+)
+
+// maxPublisherKeyProposals is the number of proposed publisher key changes that are kept until a block commits or drops them
+const maxPublisherKeyProposals = 256
+
+// PublisherKeyChange hands off block publishing to a new public key.
+// Blocks at and above Seq must be signed by Pubkey, in place of the blockchain pubkey.
+// The change is signed by the key that signs the block at Seq-1, so that only
+// the current block publisher can hand off to a new key.
+//
+// A change is proposed first: it is relayed to the peers and kept aside, without changing the key
+// that signs the blocks. The change is committed by the block at Seq, if that block is signed by Pubkey,
+// in the database transaction that adds the block. If the block at Seq is signed by the previous key,
+// the change is dropped. The chain decides between conflicting changes for the same seq,
+// and a node that syncs the chain commits the same changes as the nodes that created it.
+// The changes of params.PublisherKeyChanges are committed when the node starts
+type PublisherKeyChange struct {
+	Seq    uint64
+	Pubkey cipher.PubKey
+	Sig    cipher.Sig
+}
+
+// NewPublisherKeyChange creates a PublisherKeyChange signed by seckey
+func NewPublisherKeyChange(seq uint64, pubkey cipher.PubKey, seckey cipher.SecKey) (PublisherKeyChange, error) {
+	kc := PublisherKeyChange{
+		Seq:    seq,
+		Pubkey: pubkey,
+	}
+
+	sig, err := cipher.SignHash(kc.Hash(), seckey)
+	if err != nil {
+		return PublisherKeyChange{}, err
+	}
+
+	kc.Sig = sig
+	return kc, nil
+}
+
+// NewPublisherKeyChangesFromParams parses the hex-encoded publisher key changes of params
+func NewPublisherKeyChangesFromParams(c params.PublisherKeyChanges) ([]PublisherKeyChange, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	kcs := make([]PublisherKeyChange, len(c))
+	for i, x := range c {
+		kcs[i] = PublisherKeyChange{
+			Seq:    x.Seq,
+			Pubkey: cipher.MustPubKeyFromHex(x.Pubkey),
+			Sig:    cipher.MustSigFromHex(x.Sig),
+		}
+	}
+
+	return kcs, nil
+}
+
+// Hash returns the hash of the seq and the pubkey, which is signed by the previous block publisher
+func (kc PublisherKeyChange) Hash() cipher.SHA256 {
+	b := make([]byte, 8+len(kc.Pubkey))
+	binary.LittleEndian.PutUint64(b, kc.Seq)
+	copy(b[8:], kc.Pubkey[:])
+	return cipher.SumSHA256(b)
+}
+
+// Verify checks that the change is signed by pubkey, the key that signs the block at Seq-1
+func (kc PublisherKeyChange) Verify(pubkey cipher.PubKey) error {
+	if kc.Seq == 0 {
+		return errors.New("Publisher key change seq must be > 0")
+	}
+
+	if err := kc.Pubkey.Verify(); err != nil {
+		return fmt.Errorf("Invalid publisher key change pubkey: %v", err)
+	}
+
+	return cipher.VerifyPubKeySignedHash(pubkey, kc.Sig, kc.Hash())
+}
+
+// keySchedule is the public key that signs the blocks at each seq
+type keySchedule struct {
+	sync.RWMutex
+	initial cipher.PubKey
+	// changes are the committed changes, sorted by ascending seq
+	changes []PublisherKeyChange
+	// proposed are the changes that no block has committed or dropped yet, sorted by ascending seq.
+	// Their seqs are above the seqs of changes
+	proposed []PublisherKeyChange
+}
+
+func newKeySchedule(initial cipher.PubKey) *keySchedule {
+	return &keySchedule{
+		initial: initial,
+	}
+}
+
+// pubkeyAt returns the public key that signs the block at seq, after the committed changes
+func (s *keySchedule) pubkeyAt(seq uint64) cipher.PubKey {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.pubkeyAtLocked(seq)
+}
+
+func (s *keySchedule) pubkeyAtLocked(seq uint64) cipher.PubKey {
+	pubkey := s.initial
+	for _, kc := range s.changes {
+		if kc.Seq > seq {
+			break
+		}
+		pubkey = kc.Pubkey
+	}
+	return pubkey
+}
+
+// signersAt returns the public key that signs the block at seq, after the committed changes,
+// followed by the new keys of the proposed changes for seq that are signed by it
+func (s *keySchedule) signersAt(seq uint64) []cipher.PubKey {
+	s.RLock()
+	defer s.RUnlock()
+
+	pubkeys := []cipher.PubKey{s.pubkeyAtLocked(seq)}
+	for _, kc := range s.proposalsAtLocked(seq) {
+		pubkeys = append(pubkeys, kc.Pubkey)
+	}
+	return pubkeys
+}
+
+// proposalsAtLocked returns the proposed changes for seq that are signed by the key
+// that signs the block at seq-1. The caller must hold the lock
+func (s *keySchedule) proposalsAtLocked(seq uint64) []PublisherKeyChange {
+	var kcs []PublisherKeyChange
+	for _, kc := range s.proposed {
+		if kc.Seq != seq {
+			continue
+		}
+
+		if err := kc.Verify(s.pubkeyAtLocked(seq - 1)); err != nil {
+			continue
+		}
+
+		kcs = append(kcs, kc)
+	}
+	return kcs
+}
+
+// handsOff returns true if pubkey signs the block at seq after the committed changes,
+// and signed a proposed change for seq
+func (s *keySchedule) handsOff(seq uint64, pubkey cipher.PubKey) bool {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.pubkeyAtLocked(seq) == pubkey && len(s.proposalsAtLocked(seq)) != 0
+}
+
+// all returns a copy of the committed changes followed by the proposed changes
+func (s *keySchedule) all() []PublisherKeyChange {
+	s.RLock()
+	defer s.RUnlock()
+
+	kcs := append([]PublisherKeyChange{}, s.changes...)
+	return append(kcs, s.proposed...)
+}
+
+// known returns true if kc was committed or proposed. The caller must hold the lock
+func (s *keySchedule) known(kc PublisherKeyChange) bool {
+	for _, x := range s.changes {
+		if x == kc {
+			return true
+		}
+	}
+	for _, x := range s.proposed {
+		if x == kc {
+			return true
+		}
+	}
+	return false
+}
+
+// lastSeqLocked returns the seq of the last committed change, or 0. The caller must hold the lock
+func (s *keySchedule) lastSeqLocked() uint64 {
+	if n := len(s.changes); n > 0 {
+		return s.changes[n-1].Seq
+	}
+	return 0
+}
+
+// add verifies kc and commits it after the committed changes
+func (s *keySchedule) add(kc PublisherKeyChange) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.known(kc) {
+		return ErrPublisherKeyChangeKnown
+	}
+
+	if len(s.changes) != 0 && kc.Seq <= s.lastSeqLocked() {
+		return ErrPublisherKeyChangeConflict
+	}
+
+	if err := kc.Verify(s.pubkeyAtLocked(kc.Seq - 1)); err != nil {
+		return err
+	}
+
+	s.changes = append(s.changes, kc)
+	return nil
+}
+
+// addAll commits the changes in seq order, skipping duplicates
+func (s *keySchedule) addAll(kcs []PublisherKeyChange) error {
+	kcs = append([]PublisherKeyChange{}, kcs...)
+	sort.SliceStable(kcs, func(i, j int) bool {
+		return kcs[i].Seq < kcs[j].Seq
+	})
+
+	for _, kc := range kcs {
+		if err := s.add(kc); err != nil && err != ErrPublisherKeyChangeKnown {
+			return fmt.Errorf("Invalid publisher key change for seq %d: %v", kc.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// checkProposal returns an error if kc can't be proposed. kc must take effect after the committed changes,
+// and be signed by the key that signs the block at kc.Seq-1 after the committed changes,
+// or by the new key of a proposed change below kc.Seq, which may be committed before kc.Seq.
+// The caller must hold the lock
+func (s *keySchedule) checkProposal(kc PublisherKeyChange) error {
+	if s.known(kc) {
+		return ErrPublisherKeyChangeKnown
+	}
+
+	if len(s.changes) != 0 && kc.Seq <= s.lastSeqLocked() {
+		return ErrPublisherKeyChangeConflict
+	}
+
+	if len(s.proposed) >= maxPublisherKeyProposals {
+		return ErrPublisherKeyProposalsFull
+	}
+
+	err := kc.Verify(s.pubkeyAtLocked(kc.Seq - 1))
+	if err == nil {
+		return nil
+	}
+
+	for _, x := range s.proposed {
+		if x.Seq < kc.Seq && kc.Verify(x.Pubkey) == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// propose adds kc to the proposed changes, keeping them sorted by seq. kc must have been checked by checkProposal
+func (s *keySchedule) propose(kc PublisherKeyChange) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.known(kc) {
+		return
+	}
+
+	i := sort.Search(len(s.proposed), func(i int) bool {
+		return s.proposed[i].Seq > kc.Seq
+	})
+
+	s.proposed = append(s.proposed, PublisherKeyChange{})
+	copy(s.proposed[i+1:], s.proposed[i:])
+	s.proposed[i] = kc
+}
+
+// proposalsUpTo returns the proposed changes for seq and below
+func (s *keySchedule) proposalsUpTo(seq uint64) []PublisherKeyChange {
+	s.RLock()
+	defer s.RUnlock()
+
+	var kcs []PublisherKeyChange
+	for _, kc := range s.proposed {
+		if kc.Seq > seq {
+			break
+		}
+		kcs = append(kcs, kc)
+	}
+	return kcs
+}
+
+// proposalFor returns the proposed change that the block b is signed with, if any
+func (s *keySchedule) proposalFor(b coin.SignedBlock) (PublisherKeyChange, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, kc := range s.proposalsAtLocked(b.Seq()) {
+		if b.VerifySignature(kc.Pubkey) == nil {
+			return kc, true
+		}
+	}
+	return PublisherKeyChange{}, false
+}
+
+// commit drops the proposed changes for seq and below, and commits kc if it is not nil
+func (s *keySchedule) commit(seq uint64, kc *PublisherKeyChange) {
+	s.Lock()
+	defer s.Unlock()
+
+	i := sort.Search(len(s.proposed), func(i int) bool {
+		return s.proposed[i].Seq > seq
+	})
+	s.proposed = append([]PublisherKeyChange{}, s.proposed[i:]...)
+
+	if kc != nil {
+		s.changes = append(s.changes, *kc)
+	}
+}
+
+// getPublisherKeyChanges returns the publisher key changes saved in the bucket bkt
+func getPublisherKeyChanges(tx *dbutil.Tx, bkt []byte) ([]PublisherKeyChange, error) {
+	// Databases created by older versions do not have the buckets
+	if !dbutil.Exists(tx, bkt) {
+		return nil, nil
+	}
+
+	var kcs []PublisherKeyChange
+	if err := dbutil.ForEach(tx, bkt, func(_, v []byte) error {
+		var kc PublisherKeyChange
+		if err := decodePublisherKeyChangeExact(v, &kc); err != nil {
+			return err
+		}
+
+		kcs = append(kcs, kc)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return kcs, nil
+}
+
+// putPublisherKeyChange saves a committed publisher key change in the database
+func putPublisherKeyChange(tx *dbutil.Tx, kc PublisherKeyChange) error {
+	buf, err := encodePublisherKeyChange(&kc)
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, PublisherKeyChangesBkt, dbutil.Itob(kc.Seq), buf)
+}
+
+// putPublisherKeyProposal saves a proposed publisher key change in the database
+func putPublisherKeyProposal(tx *dbutil.Tx, kc PublisherKeyChange) error {
+	buf, err := encodePublisherKeyChange(&kc)
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, PublisherKeyProposalsBkt, publisherKeyProposalKey(kc), buf)
+}
+
+// publisherKeyProposalKey returns the database key of a proposed publisher key change.
+// Proposals for the same seq and pubkey may be signed by different keys
+func publisherKeyProposalKey(kc PublisherKeyChange) []byte {
+	k := append(dbutil.Itob(kc.Seq), kc.Pubkey[:]...)
+	return append(k, kc.Sig[:]...)
+}
+
+// deletePublisherKeyProposals deletes proposed publisher key changes from the database
+func deletePublisherKeyProposals(tx *dbutil.Tx, kcs []PublisherKeyChange) error {
+	for _, kc := range kcs {
+		if err := dbutil.Delete(tx, PublisherKeyProposalsBkt, publisherKeyProposalKey(kc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package visor
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"../../src/cipher"
+	"../../src/coin"
+)
+
+// BlockSigner signs the blocks created by a block publisher.
+// The secret key can be held by this process, see SecKeySigner, or by another process
+// that may run on an isolated signing host, see CommandSigner
+type BlockSigner interface {
+	// PubKey returns the public key that the blocks are signed by
+	PubKey() cipher.PubKey
+	// SignBlock returns the signature of the block header hash
+	SignBlock(b coin.Block) (cipher.Sig, error)
+}
+
+// SecKeySigner signs blocks with a secret key held by this process
+type SecKeySigner struct {
+	seckey cipher.SecKey
+	pubkey cipher.PubKey
+}
+
+// NewSecKeySigner creates a SecKeySigner
+func NewSecKeySigner(seckey cipher.SecKey) (*SecKeySigner, error) {
+	pubkey, err := cipher.PubKeyFromSecKey(seckey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecKeySigner{
+		seckey: seckey,
+		pubkey: pubkey,
+	}, nil
+}
+
+// PubKey returns the public key of the secret key
+func (s *SecKeySigner) PubKey() cipher.PubKey {
+	return s.pubkey
+}
+
+// SignBlock signs the block header hash with the secret key
+func (s *SecKeySigner) SignBlock(b coin.Block) (cipher.Sig, error) {
+	return cipher.SignHash(b.HashHeader(), s.seckey)
+}
+
+// BlockSignRequest is written as JSON to the stdin of the command run by CommandSigner.
+// Header is the hex-encoded serialized block header. The signer should hash Header itself,
+// so that it only signs block headers, and check that the other fields match it, see Verify
+type BlockSignRequest struct {
+	Seq      uint64 `json:"seq"`
+	Hash     string `json:"hash"`
+	PrevHash string `json:"prev_hash"`
+	Time     uint64 `json:"time"`
+	Header   string `json:"header"`
+}
+
+// NewBlockSignRequest creates a BlockSignRequest for a block
+func NewBlockSignRequest(b coin.Block) BlockSignRequest {
+	return BlockSignRequest{
+		Seq:      b.Head.BkSeq,
+		Hash:     b.HashHeader().Hex(),
+		PrevHash: b.Head.PrevHash.Hex(),
+		Time:     b.Head.Time,
+		Header:   hex.EncodeToString(b.Head.Bytes()),
+	}
+}
+
+// Verify decodes the block header of the request and returns its hash.
+// Returns an error if Header is not a block header, or if the other fields do not match it
+func (r BlockSignRequest) Verify() (cipher.SHA256, error) {
+	if r.Header == "" {
+		return cipher.SHA256{}, errors.New("sign request has no block header")
+	}
+
+	b, err := hex.DecodeString(r.Header)
+	if err != nil {
+		return cipher.SHA256{}, fmt.Errorf("invalid block header hex: %v", err)
+	}
+
+	bh, err := coin.DeserializeBlockHeader(b)
+	if err != nil {
+		return cipher.SHA256{}, err
+	}
+
+	hash := bh.Hash()
+	switch {
+	case r.Hash != hash.Hex():
+		return cipher.SHA256{}, errors.New("sign request hash is not the hash of its block header")
+	case r.Seq != bh.BkSeq:
+		return cipher.SHA256{}, errors.New("sign request seq does not match its block header")
+	case r.PrevHash != bh.PrevHash.Hex():
+		return cipher.SHA256{}, errors.New("sign request prev_hash does not match its block header")
+	case r.Time != bh.Time:
+		return cipher.SHA256{}, errors.New("sign request time does not match its block header")
+	}
+
+	return hash, nil
+}
+
+// CommandSigner signs blocks by running a command, which holds the secret key.
+// The command can run a signer on another host, e.g. over ssh.
+// For each block, the command is started and given a BlockSignRequest as JSON on stdin.
+// It must print the hex-encoded signature of the hash of the request's header on stdout, and exit with status 0
+type CommandSigner struct {
+	pubkey  cipher.PubKey
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+// NewCommandSigner creates a CommandSigner for blocks signed by pubkey.
+// command is split into the program and its arguments at spaces.
+// The command is killed if it does not exit within timeout
+func NewCommandSigner(pubkey cipher.PubKey, command string, timeout time.Duration) (*CommandSigner, error) {
+	if err := pubkey.Verify(); err != nil {
+		return nil, fmt.Errorf("Invalid block signer pubkey: %v", err)
+	}
+
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("Block signer command is empty")
+	}
+
+	if timeout <= 0 {
+		return nil, errors.New("Block signer timeout must be > 0")
+	}
+
+	return &CommandSigner{
+		pubkey:  pubkey,
+		name:    fields[0],
+		args:    fields[1:],
+		timeout: timeout,
+	}, nil
+}
+
+// PubKey returns the public key that the command signs with
+func (s *CommandSigner) PubKey() cipher.PubKey {
+	return s.pubkey
+}
+
+// SignBlock runs the command to sign the block header hash, and verifies the signature
+func (s *CommandSigner) SignBlock(b coin.Block) (cipher.Sig, error) {
+	req, err := json.Marshal(NewBlockSignRequest(b))
+	if err != nil {
+		return cipher.Sig{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.name, s.args...)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return cipher.Sig{}, fmt.Errorf("Block signer command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	sig, err := cipher.SigFromHex(strings.TrimSpace(string(out)))
+	if err != nil {
+		return cipher.Sig{}, fmt.Errorf("Block signer command returned an invalid signature: %v", err)
+	}
+
+	if err := cipher.VerifyPubKeySignedHash(s.pubkey, sig, b.HashHeader()); err != nil {
+		return cipher.Sig{}, fmt.Errorf("Block signer command returned a signature that does not match the block signer pubkey: %v", err)
+	}
+
+	return sig, nil
+}
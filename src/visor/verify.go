@@ -82,6 +82,9 @@ const (
 	TxnSigned TxnSignedFlag = 1
 	// TxnUnsigned is used for unsigned transactions
 	TxnUnsigned TxnSignedFlag = 2
+	// TxnAssumeSigned is used for signed transactions in blocks at or below the latest checkpoint.
+	// Their signatures are assumed to be valid and are not verified
+	TxnAssumeSigned TxnSignedFlag = 3
 )
 
 // ErrTxnViolatesHardConstraint is returned when a transaction violates hard constraints
@@ -149,7 +152,7 @@ func (e ErrTxnViolatesUserConstraint) Error() string {
 // Checks:
 //      * That the transaction size is not greater than the max block total transaction size
 //      * That the transaction burn enough coin hours (the fee)
-//      * That if that transaction does not spend from a locked distribution address
+//      * That if that transaction does not spend from a distribution address that is locked at headTime
 //      * That the transaction does not create outputs with a higher decimal precision than is allowed
 func VerifySingleTxnSoftConstraints(txn coin.Transaction, headTime uint64, uxIn coin.UxArray, distParams params.Distribution, verifyParams params.VerifyTxn) error {
 	if err := verifyTxnSoftConstraints(txn, headTime, uxIn, distParams, verifyParams); err != nil {
@@ -178,7 +181,7 @@ func verifyTxnSoftConstraints(txn coin.Transaction, headTime uint64, uxIn coin.U
 		return err
 	}
 
-	if TransactionIsLocked(distParams, uxIn) {
+	if TransactionIsLocked(distParams, headTime, uxIn) {
 		return ErrTxnIsLocked
 	}
 
@@ -246,7 +249,11 @@ func VerifySingleTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader,
 // NOTE: output hours overflow is treated as a soft constraint for transactions inside of a block, due to a bug
 //       which allowed some blocks to be published with overflowing output hours.
 func VerifyBlockTxnConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray) error {
-	if err := verifyTxnHardConstraints(txn, head, uxIn, TxnSigned); err != nil {
+	return verifyBlockTxnConstraints(txn, head, uxIn, TxnSigned)
+}
+
+func verifyBlockTxnConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn coin.UxArray, signed TxnSignedFlag) error {
+	if err := verifyTxnHardConstraints(txn, head, uxIn, signed); err != nil {
 		return NewErrTxnViolatesHardConstraint(err)
 	}
 
@@ -294,6 +301,11 @@ func verifyTxnHardConstraints(txn coin.Transaction, head coin.BlockHeader, uxIn
 		if err := txn.VerifyPartialInputSignatures(uxIn); err != nil {
 			return err
 		}
+	case TxnAssumeSigned:
+		// The block is committed to by a checkpoint, so the signatures are not verified
+		if err := txn.VerifyAssumeSigned(); err != nil {
+			return err
+		}
 	default:
 		logger.Panic("Invalid TxnSignedFlag")
 	}
@@ -14,6 +14,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 
 	"time"
 
@@ -33,6 +34,9 @@ import (
 
 var logger = logging.MustGetLogger("visor")
 
+// ErrUnconfirmedTxnNotFound is returned by CreateUnsignedBlockFromTxns if a transaction is not in the unconfirmed pool
+var ErrUnconfirmedTxnNotFound = errors.New("Unconfirmed transaction not found")
+
 // Visor manages the blockchain
 type Visor struct {
 	Config Config
@@ -43,6 +47,13 @@ type Visor struct {
 	blockchain  Blockchainer
 	history     Historyer
 	wallets     *wallet.Service
+	blockTime   *blockTime
+}
+
+// blockTime is added to the current time to get the time of created blocks, see AdvanceBlockTime
+type blockTime struct {
+	sync.Mutex
+	offset time.Duration
 }
 
 // New creates a Visor for managing the blockchain database
@@ -56,6 +67,14 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		return nil, err
 	}
 
+	if c.IsBlockPublisher && c.BlockSigner == nil {
+		signer, err := NewSecKeySigner(c.BlockchainSeckey)
+		if err != nil {
+			return nil, err
+		}
+		c.BlockSigner = signer
+	}
+
 	logger.Infof("Coinhour burn factor for unconfirmed transactions is %d", c.UnconfirmedVerifyTxn.BurnFactor)
 	logger.Infof("Max transaction size for unconfirmed transactions is %d", c.UnconfirmedVerifyTxn.MaxTransactionSize)
 	logger.Infof("Max decimals for unconfirmed transactions is %d", c.UnconfirmedVerifyTxn.MaxDropletPrecision)
@@ -63,6 +82,12 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 	logger.Infof("Max transaction size for transactions when creating blocks is %d", c.CreateBlockVerifyTxn.MaxTransactionSize)
 	logger.Infof("Max decimals for transactions when creating blocks is %d", c.CreateBlockVerifyTxn.MaxDropletPrecision)
 	logger.Infof("Max block size is %d", c.MaxBlockTransactionsSize)
+	if c.MaxBlockTransactionsPerAddress > 0 {
+		logger.Infof("Max transactions per address in a block is %d", c.MaxBlockTransactionsPerAddress)
+	}
+	if c.AllowEmptyBlocks {
+		logger.Info("Empty blocks are allowed")
+	}
 
 	if !db.IsReadOnly() {
 		if err := CreateBuckets(db); err != nil {
@@ -72,13 +97,31 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 	}
 
 	bc, err := NewBlockchain(db, BlockchainConfig{
-		Pubkey:      c.BlockchainPubkey,
-		Arbitrating: c.Arbitrating,
+		Pubkey:              c.BlockchainPubkey,
+		PublisherPubkeys:    c.BlockPublisherPubkeys,
+		PublisherKeyChanges: c.PublisherKeyChanges,
+		Arbitrating:         c.Arbitrating,
+		Checkpoints:         c.Checkpoints,
+		AssumeValid:         c.AssumeValid,
+		AllowEmptyBlocks:    c.AllowEmptyBlocks,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	if err := checkPublisherKey(c, bc); err != nil {
+		return nil, err
+	}
+
+	if latest, ok := c.Checkpoints.LatestSeq(); ok {
+		logger.Infof("Latest checkpoint is block %d, assume valid is %v", latest, c.AssumeValid)
+	}
+
+	if err := db.View("VerifyCheckpoints", bc.VerifyCheckpoints); err != nil {
+		logger.WithError(err).Error("The blockchain database does not match the checkpoints, it must be removed and the blockchain synced again")
+		return nil, err
+	}
+
 	history := historydb.New()
 
 	if !db.IsReadOnly() {
@@ -111,11 +154,55 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		unconfirmed: utp,
 		history:     history,
 		wallets:     wltServ,
+		blockTime:   &blockTime{},
 	}
 
 	return v, nil
 }
 
+// checkPublisherKey checks that a block publisher's seckey belongs to the blockchain pubkey,
+// one of the block publisher pubkeys or a key that block publishing was handed off to.
+// A key that does not sign the next block yet is allowed, so that a node can be ready for a hand-off
+func checkPublisherKey(c Config, bc *Blockchain) error {
+	if !c.IsBlockPublisher {
+		return nil
+	}
+
+	pubkey := c.BlockSigner.PubKey()
+
+	var nextSeq uint64
+	if err := bc.db.View("checkPublisherKey", func(tx *dbutil.Tx) error {
+		headSeq, ok, err := bc.HeadSeq(tx)
+		if err != nil {
+			return err
+		}
+		if ok {
+			nextSeq = headSeq + 1
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if containsPubKey(bc.PublisherPubkeysAt(nextSeq), pubkey) {
+		return nil
+	}
+
+	for _, kc := range bc.PublisherKeyChanges() {
+		if kc.Pubkey == pubkey {
+			logger.Warningf("Block publisher key does not sign blocks until block %d", kc.Seq)
+			return nil
+		}
+	}
+
+	if containsPubKey(c.PublisherPubkeys(), pubkey) {
+		logger.Warning("Block publisher key was replaced by a publisher key change and no longer signs blocks")
+		return nil
+	}
+
+	return errors.New("Cannot run as block publisher: invalid seckey for pubkey")
+}
+
 // VisorConfig returns Config
 func (vs *Visor) VisorConfig() Config {
 	return vs.Config
@@ -221,9 +308,13 @@ func (vs *Visor) maybeCreateGenesisBlock(tx *dbutil.Tx) error {
 	}
 
 	var sb coin.SignedBlock
-	// record the signature of genesis block
-	if vs.Config.IsBlockPublisher {
-		sb = vs.signBlock(*b)
+	// record the signature of genesis block.
+	// A block publisher that block publishing was handed off to does not sign it
+	if vs.signsBlock(0) {
+		sb, err = vs.signBlock(*b)
+		if err != nil {
+			return err
+		}
 		logger.Infof("Genesis block signature=%s", sb.Sig.Hex())
 	} else {
 		sb = coin.SignedBlock{
@@ -237,18 +328,54 @@ func (vs *Visor) maybeCreateGenesisBlock(tx *dbutil.Tx) error {
 
 // GenesisPreconditions panics if conditions for genesis block are not met
 func (vs *Visor) GenesisPreconditions() {
-	if vs.Config.BlockchainSeckey != (cipher.SecKey{}) {
-		if vs.Config.BlockchainPubkey != cipher.MustPubKeyFromSecKey(vs.Config.BlockchainSeckey) {
-			logger.Panic("Cannot create genesis block. Invalid secret key for pubkey")
+	if vs.Config.BlockSigner != nil {
+		pubkey := vs.Config.BlockSigner.PubKey()
+		if containsPubKey(vs.Config.PublisherPubkeys(), pubkey) {
+			return
+		}
+
+		for _, kc := range vs.blockchain.PublisherKeyChanges() {
+			if kc.Pubkey == pubkey {
+				return
+			}
 		}
+
+		logger.Panic("Cannot create genesis block. Invalid secret key for pubkey")
 	}
 }
 
+// signsBlock returns true if this node is a block publisher whose key may sign the block at seq.
+// A key that signed a proposed publisher key change for seq does not sign it, so that the new key commits the change
+func (vs *Visor) signsBlock(seq uint64) bool {
+	if !vs.Config.IsBlockPublisher {
+		return false
+	}
+
+	pubkey := vs.Config.BlockSigner.PubKey()
+	return containsPubKey(vs.blockchain.PublisherPubkeysAt(seq), pubkey) && !vs.blockchain.HandsOffPublisherKey(seq, pubkey)
+}
+
 // StartedAt returns the time that the visor was created
 func (vs *Visor) StartedAt() time.Time {
 	return vs.startedAt
 }
 
+// AdvanceBlockTime moves the time of the blocks created by CreateAndExecuteBlock and
+// CreateAndExecuteEmptyBlock forward by d, so that coin hours accrue without waiting.
+// The offset is not persisted, it is reset when the node restarts
+func (vs *Visor) AdvanceBlockTime(d time.Duration) {
+	vs.blockTime.Lock()
+	defer vs.blockTime.Unlock()
+	vs.blockTime.offset += d
+}
+
+// BlockTime returns the time of a block created now, in seconds
+func (vs *Visor) BlockTime() uint64 {
+	vs.blockTime.Lock()
+	defer vs.blockTime.Unlock()
+	return uint64(time.Now().UTC().Add(vs.blockTime.offset).Unix())
+}
+
 // RefreshUnconfirmed checks unconfirmed txns against the blockchain and returns
 // all transaction that turn to valid.
 func (vs *Visor) RefreshUnconfirmed() ([]cipher.SHA256, error) {
@@ -280,8 +407,8 @@ func (vs *Visor) RemoveInvalidUnconfirmed() ([]cipher.SHA256, error) {
 	return hashes, nil
 }
 
-// createBlock creates a SignedBlock from pending transactions
-func (vs *Visor) createBlock(tx *dbutil.Tx, when uint64) (coin.SignedBlock, error) {
+// createBlock creates a Block from pending transactions, to be signed by the block publisher
+func (vs *Visor) createBlock(tx *dbutil.Tx, when uint64) (coin.Block, error) {
 	if !vs.Config.IsBlockPublisher {
 		logger.Panic("Only a block publisher node can create blocks")
 	}
@@ -290,19 +417,23 @@ func (vs *Visor) createBlock(tx *dbutil.Tx, when uint64) (coin.SignedBlock, erro
 	txns, err := vs.unconfirmed.AllRawTransactions(tx)
 
 	if len(txns) == 0 {
-		return coin.SignedBlock{}, errors.New("No transactions")
+		return coin.Block{}, errors.New("No transactions")
 	}
 
 	if err != nil {
-		return coin.SignedBlock{}, err
+		return coin.Block{}, err
 	}
 
 	b, err := vs.createBlockFromTxns(tx, txns, when)
 	if err != nil {
-		return coin.SignedBlock{}, err
+		return coin.Block{}, err
 	}
 
-	return vs.signBlock(b), nil
+	if !vs.signsBlock(b.Seq()) {
+		return coin.Block{}, ErrPublisherKeyNotActive
+	}
+
+	return b, nil
 }
 
 // createBlockFromTxns creates a Block from specified set of transactions according to set of determinstic rules.
@@ -315,8 +446,9 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 
 	// Filter transactions that violate all constraints
 	var filteredTxns coin.Transactions
+	inputAddrs := make(map[cipher.SHA256][]cipher.Address, len(txns))
 	for _, txn := range txns {
-		if _, _, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, txn, vs.Config.Distribution, vs.Config.CreateBlockVerifyTxn, TxnSigned); err != nil {
+		if _, uxIn, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, txn, vs.Config.Distribution, vs.Config.CreateBlockVerifyTxn, TxnSigned); err != nil {
 			switch err.(type) {
 			case ErrTxnViolatesHardConstraint, ErrTxnViolatesSoftConstraint:
 				logger.Warningf("Transaction %s violates constraints: %v", txn.Hash().Hex(), err)
@@ -325,6 +457,7 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 			}
 		} else {
 			filteredTxns = append(filteredTxns, txn)
+			inputAddrs[txn.Hash()] = uxOutOwners(uxIn)
 		}
 	}
 
@@ -352,6 +485,11 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 		return coin.Block{}, err
 	}
 
+	// Apply the per-address transaction limit
+	if vs.Config.MaxBlockTransactionsPerAddress > 0 {
+		txns = limitTransactionsPerAddress(txns, inputAddrs, vs.Config.MaxBlockTransactionsPerAddress)
+	}
+
 	// Apply block size transaction limit
 	txns, err = txns.TruncateBytesTo(vs.Config.MaxBlockTransactionsSize)
 	if err != nil {
@@ -378,21 +516,187 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 	return *b, nil
 }
 
-// CreateAndExecuteBlock creates a SignedBlock from pending transactions and executes it
-func (vs *Visor) CreateAndExecuteBlock() (coin.SignedBlock, error) {
-	var sb coin.SignedBlock
+// limitTransactionsPerAddress drops the transactions that spend from an address which is spent from
+// by limit earlier transactions. inputAddrs are the addresses that each transaction spends from.
+// The dropped transactions stay in the unconfirmed pool for later blocks
+func limitTransactionsPerAddress(txns coin.Transactions, inputAddrs map[cipher.SHA256][]cipher.Address, limit int) coin.Transactions {
+	counts := make(map[cipher.Address]int)
+	limited := make(coin.Transactions, 0, len(txns))
+
+	for _, txn := range txns {
+		addrs := inputAddrs[txn.Hash()]
+
+		overLimit := false
+		for _, a := range addrs {
+			if counts[a] >= limit {
+				overLimit = true
+				break
+			}
+		}
+		if overLimit {
+			continue
+		}
+
+		for _, a := range addrs {
+			counts[a]++
+		}
+		limited = append(limited, txn)
+	}
+
+	if n := len(txns) - len(limited); n > 0 {
+		logger.Infof("CreateBlock deferred %d transactions over the limit of %d transactions per address", n, limit)
+	}
+
+	return limited
+}
+
+// uxOutOwners returns the distinct owner addresses of the outputs
+func uxOutOwners(uxa coin.UxArray) []cipher.Address {
+	addrs := make([]cipher.Address, 0, len(uxa))
+	seen := make(map[cipher.Address]struct{}, len(uxa))
+	for _, ux := range uxa {
+		if _, ok := seen[ux.Body.Address]; ok {
+			continue
+		}
+		seen[ux.Body.Address] = struct{}{}
+		addrs = append(addrs, ux.Body.Address)
+	}
+	return addrs
+}
+
+// createEmptyBlock creates a SignedBlock with no transactions
+func (vs *Visor) createEmptyBlock(tx *dbutil.Tx, when uint64) (coin.Block, error) {
+	if !vs.Config.IsBlockPublisher {
+		logger.Panic("Only a block publisher node can create blocks")
+	}
+
+	if !vs.Config.AllowEmptyBlocks {
+		return coin.Block{}, ErrEmptyBlocksNotAllowed
+	}
+
+	b, err := vs.blockchain.NewBlock(tx, nil, when)
+	if err != nil {
+		return coin.Block{}, err
+	}
+
+	if !vs.signsBlock(b.Seq()) {
+		return coin.Block{}, ErrPublisherKeyNotActive
+	}
+
+	return *b, nil
+}
+
+// createAndSignBlock creates a block with create in a read transaction, then signs it outside of
+// any transaction, as the BlockSigner may be slow, e.g. an external command
+func (vs *Visor) createAndSignBlock(name string, when uint64, create func(*dbutil.Tx, uint64) (coin.Block, error)) (coin.SignedBlock, error) {
+	var b coin.Block
 
-	err := vs.db.Update("CreateAndExecuteBlock", func(tx *dbutil.Tx) error {
+	if err := vs.db.View(name, func(tx *dbutil.Tx) error {
 		var err error
-		sb, err = vs.createBlock(tx, uint64(time.Now().UTC().Unix()))
+		b, err = create(tx, when)
+		return err
+	}); err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	return vs.signBlock(b)
+}
+
+// createAndExecuteBlock creates and signs a block with createAndSignBlock, then executes it.
+// Returns ErrHeadChanged if another block was executed while it was signed
+func (vs *Visor) createAndExecuteBlock(name string, create func(*dbutil.Tx, uint64) (coin.Block, error)) (coin.SignedBlock, error) {
+	sb, err := vs.createAndSignBlock(name, vs.BlockTime(), create)
+	if err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	if err := vs.db.Update(name, func(tx *dbutil.Tx) error {
+		head, err := vs.blockchain.Head(tx)
 		if err != nil {
 			return err
 		}
+		if head.Seq()+1 != sb.Seq() || head.HashHeader() != sb.Head.PrevHash {
+			return ErrHeadChanged
+		}
 
 		return vs.executeSignedBlock(tx, sb)
+	}); err != nil {
+		return coin.SignedBlock{}, err
+	}
+
+	return sb, nil
+}
+
+// CreateBlock creates a SignedBlock from pending transactions, without executing it
+func (vs *Visor) CreateBlock(when uint64) (coin.SignedBlock, error) {
+	return vs.createAndSignBlock("CreateBlock", when, vs.createBlock)
+}
+
+// CreateUnsignedBlock creates a Block from pending transactions, to be signed outside of the node.
+// Unlike CreateBlock, it does not require the node to be a block publisher
+func (vs *Visor) CreateUnsignedBlock(when uint64) (coin.Block, error) {
+	var b coin.Block
+
+	err := vs.db.View("CreateUnsignedBlock", func(tx *dbutil.Tx) error {
+		txns, err := vs.unconfirmed.AllRawTransactions(tx)
+		if err != nil {
+			return err
+		}
+
+		b, err = vs.createBlockFromTxns(tx, txns, when)
+		return err
 	})
 
-	return sb, err
+	return b, err
+}
+
+// CreateUnsignedBlockFromTxns creates a Block at time when from the unconfirmed transactions with the hashes,
+// to be signed outside of the node. It recreates a Block created by CreateUnsignedBlock from its time and transactions,
+// if the head block has not changed.
+// Returns ErrUnconfirmedTxnNotFound if a transaction is not in the unconfirmed pool
+func (vs *Visor) CreateUnsignedBlockFromTxns(hashes []cipher.SHA256, when uint64) (coin.Block, error) {
+	var b coin.Block
+
+	err := vs.db.View("CreateUnsignedBlockFromTxns", func(tx *dbutil.Tx) error {
+		txns, err := vs.unconfirmed.GetKnown(tx, hashes)
+		if err != nil {
+			return err
+		}
+
+		if len(txns) != len(hashes) {
+			return ErrUnconfirmedTxnNotFound
+		}
+
+		nb, err := vs.blockchain.NewBlock(tx, txns, when)
+		if err != nil {
+			return err
+		}
+
+		b = *nb
+		return nil
+	})
+
+	return b, err
+}
+
+// CreateAndExecuteBlock creates a SignedBlock from pending transactions and executes it.
+// The block is signed outside of the database transactions.
+// Returns ErrHeadChanged if another block was executed while it was signed
+func (vs *Visor) CreateAndExecuteBlock() (coin.SignedBlock, error) {
+	return vs.createAndExecuteBlock("CreateAndExecuteBlock", vs.createBlock)
+}
+
+// CreateEmptyBlock creates a SignedBlock with no transactions, without executing it.
+// Returns ErrEmptyBlocksNotAllowed unless Config.AllowEmptyBlocks is set
+func (vs *Visor) CreateEmptyBlock(when uint64) (coin.SignedBlock, error) {
+	return vs.createAndSignBlock("CreateEmptyBlock", when, vs.createEmptyBlock)
+}
+
+// CreateAndExecuteEmptyBlock creates a SignedBlock with no transactions and executes it.
+// Returns ErrEmptyBlocksNotAllowed unless Config.AllowEmptyBlocks is set,
+// and ErrHeadChanged if another block was executed while it was signed
+func (vs *Visor) CreateAndExecuteEmptyBlock() (coin.SignedBlock, error) {
+	return vs.createAndExecuteBlock("CreateAndExecuteEmptyBlock", vs.createEmptyBlock)
 }
 
 // CreateBlockFromTxns creates a Block from specified set of transactions according to set of determinstic rules.
@@ -438,7 +742,7 @@ func (vs *Visor) ExecuteSignedBlockUnsafe(b coin.SignedBlock) error {
 // executeSignedBlock adds a block to the blockchain, or returns error.
 // Blocks must be executed in sequence, and be signed by a block publisher node.
 func (vs *Visor) executeSignedBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
-	if err := b.VerifySignature(vs.Config.BlockchainPubkey); err != nil {
+	if err := VerifyBlockSignature(b, vs.blockchain.PublisherPubkeysAt(b.Seq())); err != nil {
 		return err
 	}
 
@@ -466,18 +770,47 @@ func (vs *Visor) executeSignedBlockUnsafe(tx *dbutil.Tx, b coin.SignedBlock) err
 	return vs.history.ParseBlock(tx, b.Block)
 }
 
-// signBlock signs a block for a block publisher node. Will panic if anything is invalid
-func (vs *Visor) signBlock(b coin.Block) coin.SignedBlock {
+// PublisherPubkeysAt returns the public keys that may sign the block at seq
+func (vs *Visor) PublisherPubkeysAt(seq uint64) []cipher.PubKey {
+	return vs.blockchain.PublisherPubkeysAt(seq)
+}
+
+// ConsensusPubkeysAt returns the public keys of the block publishers that take part in the block consensus at seq
+func (vs *Visor) ConsensusPubkeysAt(seq uint64) []cipher.PubKey {
+	return vs.blockchain.ConsensusPubkeysAt(seq)
+}
+
+// GetPublisherKeyChanges returns the committed block publisher key changes followed by the proposed changes,
+// sorted by ascending seq
+func (vs *Visor) GetPublisherKeyChanges() []PublisherKeyChange {
+	return vs.blockchain.PublisherKeyChanges()
+}
+
+// AddPublisherKeyChange verifies a block publisher key change and saves it as proposed,
+// until the block at its seq commits or drops it.
+// Returns ErrPublisherKeyChangeKnown if the change is already known
+func (vs *Visor) AddPublisherKeyChange(kc PublisherKeyChange) error {
+	return vs.db.Update("AddPublisherKeyChange", func(tx *dbutil.Tx) error {
+		return vs.blockchain.AddPublisherKeyChange(tx, kc)
+	})
+}
+
+// signBlock signs a block for a block publisher node with its BlockSigner.
+// Will panic if not a block publisher
+func (vs *Visor) signBlock(b coin.Block) (coin.SignedBlock, error) {
 	if !vs.Config.IsBlockPublisher {
 		logger.Panic("Only a block publisher node can sign blocks")
 	}
 
-	sig := cipher.MustSignHash(b.HashHeader(), vs.Config.BlockchainSeckey)
+	sig, err := vs.Config.BlockSigner.SignBlock(b)
+	if err != nil {
+		return coin.SignedBlock{}, err
+	}
 
 	return coin.SignedBlock{
 		Block: b,
 		Sig:   sig,
-	}
+	}, nil
 }
 
 /*
@@ -1528,6 +1861,49 @@ func (vs *Visor) GetAllValidUnconfirmedTxHashes() ([]cipher.SHA256, error) {
 	return hashes, nil
 }
 
+// GetValidUnconfirmedFee returns the number of valid unconfirmed transactions and their total fee,
+// in coin hours at the time of the head block
+func (vs *Visor) GetValidUnconfirmedFee() (int, uint64, error) {
+	var n int
+	var totalFee uint64
+
+	if err := vs.db.View("GetValidUnconfirmedFee", func(tx *dbutil.Tx) error {
+		txns, err := vs.unconfirmed.GetFiltered(tx, IsValid)
+		if err != nil {
+			return err
+		}
+
+		head, err := vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		feeCalc := vs.blockchain.TransactionFee(tx, head.Time())
+
+		n = len(txns)
+		totalFee = 0
+		for _, txn := range txns {
+			fee, err := feeCalc(&txn.Transaction)
+			if err != nil {
+				// The inputs may have been spent since the transaction was last checked
+				logger.WithError(err).WithField("txid", txn.Transaction.Hash().Hex()).Debug("GetValidUnconfirmedFee: fee calculation failed")
+				continue
+			}
+
+			totalFee, err = mathutil.AddUint64(totalFee, fee)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	return n, totalFee, nil
+}
+
 // GetConfirmedTransaction returns transaction, which has been already included in some block.
 func (vs *Visor) GetConfirmedTransaction(txnHash cipher.SHA256) (*coin.Transaction, error) {
 	var histTxn *historydb.Transaction
@@ -2376,7 +2752,7 @@ func (vs *Visor) GetUnspentOutputsSummary(filters []OutputsFilter) (*UnspentOutp
 	}, nil
 }
 
-// GetRichlist returns a Richlist
+// GetRichlist returns a Richlist. Distribution addresses are locked or unlocked as of the head block
 func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
 	rbOuts, err := vs.GetUnspentOutputsSummary(nil)
 	if err != nil {
@@ -2397,7 +2773,8 @@ func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
 		}
 	}
 
-	lockedAddrs := vs.Config.Distribution.LockedAddressesDecoded()
+	headTime := rbOuts.HeadBlock.Head.Time
+	lockedAddrs := vs.Config.Distribution.LockedAddressesDecoded(headTime)
 	addrsMap := make(map[cipher.Address]struct{}, len(lockedAddrs))
 	for _, a := range lockedAddrs {
 		addrsMap[a] = struct{}{}
@@ -2409,7 +2786,7 @@ func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
 	}
 
 	if !includeDistribution {
-		unlockedAddrs := vs.Config.Distribution.UnlockedAddressesDecoded()
+		unlockedAddrs := vs.Config.Distribution.UnlockedAddressesDecoded(headTime)
 		for _, a := range unlockedAddrs {
 			addrsMap[a] = struct{}{}
 		}
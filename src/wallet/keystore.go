@@ -0,0 +1,167 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+
+	"../../src/cipher"
+	"../../src/util/file"
+)
+
+// KeystoreVersion is the version of the keystore file format
+const KeystoreVersion = "0.1"
+
+var (
+	// ErrKeystorePubkeyMismatch is returned if the decrypted secret key of a keystore does not match its pubkey
+	ErrKeystorePubkeyMismatch = NewError(errors.New("keystore secret key does not match its pubkey"))
+)
+
+// Keystore is a secret key encrypted with a password, with one of the wallet crypto types.
+// It keeps the blockchain secret key of a block publisher out of flags and config files
+type Keystore struct {
+	Version    string     `json:"version"`
+	CryptoType CryptoType `json:"crypto_type"`
+	// Pubkey is the hex-encoded public key of the secret key, readable without the password
+	Pubkey string `json:"pubkey"`
+	// Secret is the encrypted hex-encoded secret key
+	Secret string `json:"secret"`
+}
+
+// NewKeystore encrypts a secret key with password and cryptoType
+func NewKeystore(seckey cipher.SecKey, password []byte, cryptoType CryptoType) (*Keystore, error) {
+	if len(password) == 0 {
+		return nil, ErrMissingPassword
+	}
+
+	pubkey, err := cipher.PubKeyFromSecKey(seckey)
+	if err != nil {
+		return nil, err
+	}
+
+	crypto, err := getCrypto(cryptoType)
+	if err != nil {
+		return nil, err
+	}
+
+	sb := []byte(seckey.Hex())
+	defer func() {
+		// Wipes the unencrypted secret key
+		for i := range sb {
+			sb[i] = 0
+		}
+	}()
+
+	encSecret, err := crypto.Encrypt(sb, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Keystore{
+		Version:    KeystoreVersion,
+		CryptoType: cryptoType,
+		Pubkey:     pubkey.Hex(),
+		Secret:     string(encSecret),
+	}, nil
+}
+
+// LoadKeystore loads a keystore file
+func LoadKeystore(filename string) (*Keystore, error) {
+	var k Keystore
+	if err := file.LoadJSON(filename, &k); err != nil {
+		return nil, err
+	}
+
+	if err := k.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid keystore %s: %v", filename, err)
+	}
+
+	return &k, nil
+}
+
+// Validate checks the fields of the keystore, without decrypting it
+func (k *Keystore) Validate() error {
+	if k.Version != KeystoreVersion {
+		return fmt.Errorf("unsupported keystore version %q", k.Version)
+	}
+
+	if _, err := getCrypto(k.CryptoType); err != nil {
+		return err
+	}
+
+	if _, err := cipher.PubKeyFromHex(k.Pubkey); err != nil {
+		return fmt.Errorf("invalid pubkey: %v", err)
+	}
+
+	if k.Secret == "" {
+		return errors.New("secret missing from keystore")
+	}
+
+	return nil
+}
+
+// PubKey returns the public key of the secret key
+func (k *Keystore) PubKey() (cipher.PubKey, error) {
+	return cipher.PubKeyFromHex(k.Pubkey)
+}
+
+// Save saves the keystore to a file, readable only by the owner
+func (k *Keystore) Save(filename string) error {
+	return file.SaveJSON(filename, k, 0600)
+}
+
+// Unlock decrypts the secret key with password.
+// Returns ErrInvalidPassword if the decryption fails
+func (k *Keystore) Unlock(password []byte) (cipher.SecKey, error) {
+	if len(password) == 0 {
+		return cipher.SecKey{}, ErrMissingPassword
+	}
+
+	crypto, err := getCrypto(k.CryptoType)
+	if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	sb, err := crypto.Decrypt([]byte(k.Secret), password)
+	if err != nil {
+		return cipher.SecKey{}, ErrInvalidPassword
+	}
+
+	defer func() {
+		// Wipes the decrypted secret key
+		for i := range sb {
+			sb[i] = 0
+		}
+	}()
+
+	seckey, err := cipher.SecKeyFromHex(string(sb))
+	if err != nil {
+		return cipher.SecKey{}, fmt.Errorf("invalid keystore secret key: %v", err)
+	}
+
+	pubkey, err := cipher.PubKeyFromSecKey(seckey)
+	if err != nil {
+		return cipher.SecKey{}, err
+	}
+
+	if pubkey.Hex() != k.Pubkey {
+		return cipher.SecKey{}, ErrKeystorePubkeyMismatch
+	}
+
+	return seckey, nil
+}
+
+// Reencrypt decrypts the keystore with password and encrypts it again with newPassword and cryptoType
+func (k *Keystore) Reencrypt(password, newPassword []byte, cryptoType CryptoType) error {
+	seckey, err := k.Unlock(password)
+	if err != nil {
+		return err
+	}
+
+	nk, err := NewKeystore(seckey, newPassword, cryptoType)
+	if err != nil {
+		return err
+	}
+
+	*k = *nk
+	return nil
+}